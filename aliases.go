@@ -0,0 +1,55 @@
+package clientconfig
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+type alias struct {
+	name   string
+	target string
+}
+
+var (
+	aliasesMtx sync.Mutex
+	// aliases are deprecated names that are still accepted, in the order they are checked.
+	aliases = []alias{
+		{"ETCD_CA_CERT", "ETCD_SERVER_CA"},
+	}
+)
+
+// RegisterAlias makes us accept name (and name_FILE) as a deprecated alias of target, like ETCD_SERVER_CA. A warning is logged when the alias is used.
+// This makes it easy to migrate from your own naming convention. If both are set, they must have the same value.
+// RegisterAlias is meant to be called from init functions and panics if the name is already taken.
+func RegisterAlias(name, target string) {
+	aliasesMtx.Lock()
+	defer aliasesMtx.Unlock()
+	if name == "" || name == target || strings.HasSuffix(name, "_FILE") || strings.ContainsAny(name, "= ") {
+		panic(fmt.Sprintf("clientconfig: invalid alias name %q", name))
+	}
+	for _, k := range variables {
+		if k == name {
+			panic(fmt.Sprintf("clientconfig: alias %q is a built-in variable", name))
+		}
+	}
+	for _, a := range aliases {
+		if a.name == name {
+			panic(fmt.Sprintf("clientconfig: alias %q registered twice", name))
+		}
+	}
+	aliases = append(aliases, alias{name, target})
+}
+
+// aliasesFor returns the aliases of target.
+func aliasesFor(target string) []string {
+	aliasesMtx.Lock()
+	defer aliasesMtx.Unlock()
+	var ret []string
+	for _, a := range aliases {
+		if a.target == target {
+			ret = append(ret, a.name)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,58 @@
+package clientconfig
+
+import (
+	"crypto/x509"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// checkAuthMode validates c against ETCD_AUTH_MODE and ETCD_EXPECTED_IDENTITY.
+func checkAuthMode(c clientv3.Config, mode, identity string) error {
+	hasCert := c.TLS != nil && len(c.TLS.Certificates) > 0
+	switch mode {
+	case "":
+	case "cert":
+		// etcd uses the CN of the client certificate as the user, unless a username is passed.
+		if !hasCert {
+			return errorf(CodeConflictingVariables, "ETCD_AUTH_MODE", "ETCD_AUTH_MODE=cert requires ETCD_CLIENT_CERT and ETCD_CLIENT_KEY")
+		}
+		if c.Username != "" || c.Password != "" {
+			return errorf(CodeConflictingVariables, "ETCD_AUTH_MODE", "ETCD_AUTH_MODE=cert doesn't allow a username or password, because they would override the certificate's identity")
+		}
+	case "password":
+		if c.Username == "" || c.Password == "" {
+			return errorf(CodeConflictingVariables, "ETCD_AUTH_MODE", "ETCD_AUTH_MODE=password requires a username and password")
+		}
+	default:
+		return errorf(CodeInvalidValue, "ETCD_AUTH_MODE", "invalid ETCD_AUTH_MODE %q (should be cert or password)", mode)
+	}
+	if identity == "" {
+		return nil
+	}
+	if !hasCert {
+		return errorf(CodeConflictingVariables, "ETCD_EXPECTED_IDENTITY", "ETCD_EXPECTED_IDENTITY requires ETCD_CLIENT_CERT and ETCD_CLIENT_KEY")
+	}
+	crt, err := x509.ParseCertificate(c.TLS.Certificates[0].Certificate[0])
+	if err != nil {
+		return errorf(CodeInvalidCertificate, "ETCD_CLIENT_CERT", "failed to parse ETCD_CLIENT_CERT: %v", err)
+	}
+	if crt.Subject.CommonName == identity {
+		return nil
+	}
+	for _, n := range crt.DNSNames {
+		if n == identity {
+			return nil
+		}
+	}
+	for _, n := range crt.EmailAddresses {
+		if n == identity {
+			return nil
+		}
+	}
+	for _, u := range crt.URIs {
+		if u.String() == identity {
+			return nil
+		}
+	}
+	return errorf(CodeIdentityMismatch, "ETCD_EXPECTED_IDENTITY", "client certificate is for %q, not ETCD_EXPECTED_IDENTITY %q", crt.Subject.CommonName, identity)
+}
@@ -0,0 +1,150 @@
+// Package awssecrets resolves variables from AWS Secrets Manager and SSM Parameter Store, for environments like ECS where secret files can't be mounted.
+//
+// Importing it registers two resolvers (see clientconfig.RegisterResolver), which are used by setting the _SOURCE variant of a variable:
+//
+//	ETCD_PASSWORD_SOURCE=aws-sm://prod/etcd/password
+//	ETCD_CLIENT_KEY_SOURCE=aws-ssm:///prod/etcd/client-key
+//
+// aws-sm takes the name or ARN of a secret. For secrets with JSON key/value pairs, ?key=password picks one of them. ?version_stage=AWSPREVIOUS or ?version_id= select another version than the current one.
+// aws-ssm takes the name or ARN of a parameter (the leading slash can be omitted). SecureString parameters are decrypted.
+//
+// Credentials and the region come from the default AWS configuration (AWS_REGION, the shared config files, the ECS task role, etc). The region of an ARN takes precedence.
+package awssecrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	clientconfig "github.com/Jille/etcd-client-from-env"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+func init() {
+	clientconfig.RegisterResolver("aws-sm", resolver{})
+	clientconfig.RegisterResolver("aws-ssm", resolver{})
+}
+
+// resolver implements clientconfig.Resolver and clientconfig.ContextResolver.
+type resolver struct{}
+
+func (r resolver) Resolve(scheme, ref string) ([]byte, error) {
+	return r.ResolveContext(context.Background(), scheme, ref)
+}
+
+func (resolver) ResolveContext(ctx context.Context, scheme, ref string) ([]byte, error) {
+	var v string
+	var err error
+	if scheme == "aws-sm" {
+		v, err = resolveSecret(ctx, ref)
+	} else {
+		v, err = resolveParameter(ctx, ref)
+	}
+	return []byte(v), err
+}
+
+func resolveSecret(ctx context.Context, ref string) (string, error) {
+	name, q, err := parseRef(ref, "key", "version_stage", "version_id")
+	if err != nil {
+		return "", err
+	}
+	cfg, err := loadConfig(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	in := &secretsmanager.GetSecretValueInput{SecretId: aws.String(name)}
+	if v := q.Get("version_stage"); v != "" {
+		in.VersionStage = aws.String(v)
+	}
+	if v := q.Get("version_id"); v != "" {
+		in.VersionId = aws.String(v)
+	}
+	out, err := secretsmanager.NewFromConfig(cfg).GetSecretValue(ctx, in)
+	if err != nil {
+		return "", err
+	}
+	var value string
+	if out.SecretString != nil {
+		value = *out.SecretString
+	} else {
+		value = string(out.SecretBinary)
+	}
+	key := q.Get("key")
+	if key == "" {
+		return value, nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("secret %s isn't JSON, so ?key= can't be used: %v", name, err)
+	}
+	v, ok := fields[key].(string)
+	if !ok {
+		return "", fmt.Errorf("secret %s doesn't have a string field %q", name, key)
+	}
+	return v, nil
+}
+
+func resolveParameter(ctx context.Context, ref string) (string, error) {
+	name, _, err := parseRef(ref)
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(name, "/") && !strings.HasPrefix(name, "/") && !arn.IsARN(name) {
+		name = "/" + name
+	}
+	cfg, err := loadConfig(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	out, err := ssm.NewFromConfig(cfg).GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(out.Parameter.Value), nil
+}
+
+// parseRef splits ref into the name and the query parameters, which must be in allowed.
+func parseRef(ref string, allowed ...string) (string, url.Values, error) {
+	name, query, _ := strings.Cut(ref, "?")
+	if name == "" {
+		return "", nil, fmt.Errorf("%q doesn't contain a name", ref)
+	}
+	q, err := url.ParseQuery(query)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse the options in %q: %v", ref, err)
+	}
+	for k := range q {
+		found := false
+		for _, a := range allowed {
+			if k == a {
+				found = true
+			}
+		}
+		if !found {
+			return "", nil, fmt.Errorf("unknown option %q in %q", k, ref)
+		}
+	}
+	return name, q, nil
+}
+
+// loadConfig loads the default AWS configuration, with the region of name if it's an ARN.
+func loadConfig(ctx context.Context, name string) (aws.Config, error) {
+	var opts []func(*config.LoadOptions) error
+	if a, err := arn.Parse(name); err == nil && a.Region != "" {
+		opts = append(opts, config.WithRegion(a.Region))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load the AWS configuration: %v", err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,97 @@
+package clientconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Bind reads the regular configuration like Get, and also populates the fields of dst (a pointer to a struct) that have an `etcd` tag.
+// A field tagged `etcd:"LEASE_TTL"` is read from ETCD_LEASE_TTL (or ETCD_LEASE_TTL_FILE), so applications can keep their etcd-adjacent settings next to ours.
+// Supported field types are strings, bools, integers, floats, time.Duration and []string (comma separated). Fields whose variable is unset are left alone, so fill in your defaults before calling Bind.
+func Bind(dst interface{}, opts ...Option) (clientv3.Config, error) {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return clientv3.Config{}, errors.New("clientconfig.Bind: dst must be a pointer to a struct")
+	}
+	o := newOptions(opts)
+	rv = rv.Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		name, ok := f.Tag.Lookup("etcd")
+		if !ok || name == "" || name == "-" {
+			continue
+		}
+		k := "ETCD_" + name
+		v, err := readVariable(context.Background(), o, k)
+		if err != nil {
+			return clientv3.Config{}, err
+		}
+		if v == "" {
+			continue
+		}
+		if err := setField(rv.Field(i), v); err != nil {
+			return clientv3.Config{}, errorf(CodeInvalidValue, k, "failed to parse %s (%q) into field %s: %v", k, v, f.Name, err)
+		}
+	}
+	return ApplyWithOptions(Defaults(), opts...)
+}
+
+func setField(fv reflect.Value, v string) error {
+	if !fv.CanSet() {
+		return errors.New("field is not exported")
+	}
+	if fv.Type() == durationType {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(v)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(v, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(v, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(v, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(n)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", fv.Type())
+		}
+		fv.Set(reflect.ValueOf(strings.Split(v, ",")).Convert(fv.Type()))
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
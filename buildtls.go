@@ -0,0 +1,187 @@
+package clientconfig
+
+import (
+	"crypto/tls"
+	"errors"
+	"maps"
+	"strconv"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// BuildTLS returns the TLS config described by the TLS variables in s (ETCD_SERVER_CA, ETCD_CLIENT_CERT, ETCD_TLS_MIN_VERSION, etc), or nil if none are set, for callers that compose their own clientv3.Config.
+// Client certificates given as files are reloaded like with Apply, but a reloaded ETCD_SERVER_CA_FILE is only picked up through Apply.
+func BuildTLS(s Settings, opts ...Option) (*tls.Config, error) {
+	var c clientv3.Config
+	if _, _, err := applyTLS(newOptions(opts), &c, maps.Clone(s)); err != nil {
+		return nil, err
+	}
+	return c.TLS, nil
+}
+
+// applyTLS applies the TLS variables to c.TLS. A c.TLS supplied by the caller is cloned and the variables only override the fields they set, so fields like VerifyConnection and ClientSessionCache are kept.
+// It returns ETCD_TLS_RELOAD_INTERVAL and ETCD_SERVER_CA_APPEND_SYSTEM, which Apply needs for reloading the other files.
+func applyTLS(o *options, c *clientv3.Config, settings Settings) (reloadInterval time.Duration, appendSystem bool, err error) {
+	if c.TLS != nil {
+		c.TLS = c.TLS.Clone()
+	}
+	if v := settings["ETCD_DEV_TLS"]; v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return 0, false, errorf(CodeInvalidValue, "ETCD_DEV_TLS", "failed to parse ETCD_DEV_TLS as bool (%q)", v)
+		}
+		if b {
+			if err := applyDevTLS(o, settings); err != nil {
+				return 0, false, err
+			}
+			if c.TLS == nil {
+				c.TLS = new(tls.Config)
+			}
+		}
+	}
+	if v := settings["ETCD_INSECURE_SKIP_VERIFY"]; v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return 0, false, errorf(CodeInvalidValue, "ETCD_INSECURE_SKIP_VERIFY", "failed to parse ETCD_INSECURE_SKIP_VERIFY as bool (%q)", v)
+		}
+		if c.TLS == nil {
+			c.TLS = new(tls.Config)
+		}
+		c.TLS.InsecureSkipVerify = b
+	}
+	if v := settings["ETCD_SERVER_CA_APPEND_SYSTEM"]; v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return 0, false, errorf(CodeInvalidValue, "ETCD_SERVER_CA_APPEND_SYSTEM", "failed to parse ETCD_SERVER_CA_APPEND_SYSTEM as bool (%q)", v)
+		}
+		if b && settings["ETCD_SERVER_CA"] == "" {
+			return 0, false, errorf(CodeConflictingVariables, "ETCD_SERVER_CA_APPEND_SYSTEM", "ETCD_SERVER_CA_APPEND_SYSTEM can only be used together with ETCD_SERVER_CA")
+		}
+		appendSystem = b
+	}
+	if v := settings["ETCD_SERVER_CA"]; v != "" {
+		v, err := serverCAPEM(v)
+		if err != nil {
+			return 0, false, err
+		}
+		pool, err := newRootPool(appendSystem)
+		if err != nil {
+			return 0, false, errorf(CodeInvalidCertificate, "ETCD_SERVER_CA_APPEND_SYSTEM", "failed to load the system certificate pool for ETCD_SERVER_CA_APPEND_SYSTEM: %v", err)
+		}
+		if !pool.AppendCertsFromPEM([]byte(v)) {
+			return 0, false, errorf(CodeInvalidCertificate, "ETCD_SERVER_CA", "certificate(s) in ETCD_SERVER_CA(_FILE) were invalid PEM certificates")
+		}
+		if c.TLS == nil {
+			c.TLS = new(tls.Config)
+		}
+		c.TLS.RootCAs = pool
+	}
+	if v := settings["ETCD_TLS_RELOAD_INTERVAL"]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return 0, false, errorf(CodeInvalidValue, "ETCD_TLS_RELOAD_INTERVAL", "failed to parse ETCD_TLS_RELOAD_INTERVAL as a positive duration (%q)", v)
+		}
+		reloadInterval = d
+	}
+	vc, vk, kp := settings["ETCD_CLIENT_CERT"], settings["ETCD_CLIENT_KEY"], []byte(settings["ETCD_CLIENT_KEY_PASSWORD"])
+	cf, kf, certVars := o.env.Getenv("ETCD_CLIENT_CERT_FILE"), o.env.Getenv("ETCD_CLIENT_KEY_FILE"), "ETCD_CLIENT_CERT+ETCD_CLIENT_KEY"
+	if v := settings["ETCD_CLIENT_CERT_AND_KEY"]; v != "" {
+		if vc != "" || vk != "" {
+			return 0, false, errorf(CodeConflictingVariables, "ETCD_CLIENT_CERT_AND_KEY", "ETCD_CLIENT_CERT_AND_KEY can't be combined with ETCD_CLIENT_CERT or ETCD_CLIENT_KEY")
+		}
+		// tls.X509KeyPair only looks at the CERTIFICATE blocks of its first argument and the PRIVATE KEY block of its second, so the bundle can be passed as both.
+		vc, vk, certVars = v, v, "ETCD_CLIENT_CERT_AND_KEY"
+		cf = o.env.Getenv("ETCD_CLIENT_CERT_AND_KEY_FILE")
+		kf = cf
+	}
+	if len(kp) > 0 && vk == "" {
+		return 0, false, errorf(CodeConflictingVariables, "ETCD_CLIENT_KEY_PASSWORD", "ETCD_CLIENT_KEY_PASSWORD can only be used together with ETCD_CLIENT_KEY or ETCD_CLIENT_CERT_AND_KEY")
+	}
+	if vc != "" && vk != "" {
+		crt, err := clientKeyPair([]byte(vc), []byte(vk), kp)
+		if err != nil {
+			return 0, false, errorf(CodeInvalidCertificate, "ETCD_CLIENT_CERT", "failed to parse %s: %v", certVars, err)
+		}
+		if c.TLS == nil {
+			c.TLS = new(tls.Config)
+		}
+		c.TLS.Certificates = []tls.Certificate{crt}
+		// Go prefers GetClientCertificate over Certificates, so one set by the caller would hide ours.
+		c.TLS.GetClientCertificate = nil
+		if cf != "" && kf != "" {
+			// Certificates stays set for inspection, but Go prefers GetClientCertificate.
+			r := &clientCertReloader{o: o, files: newFileReloader(o.env, []string{cf, kf}, [][]byte{[]byte(vc), []byte(vk)}, reloadInterval), password: kp, cert: crt}
+			c.TLS.GetClientCertificate = r.GetClientCertificate
+		}
+	} else if vc != "" || vk != "" {
+		return 0, false, errorf(CodeConflictingVariables, "ETCD_CLIENT_CERT", "either both of ETCD_CLIENT_CERT(_FILE) and ETCD_CLIENT_KEY(_FILE) must be given or neither")
+	}
+	if v := settings["ETCD_GATEWAY_SERVER_NAME"]; v != "" {
+		if settings["ETCD_GATEWAY_ENDPOINT"] == "" {
+			return 0, false, errorf(CodeConflictingVariables, "ETCD_GATEWAY_SERVER_NAME", "ETCD_GATEWAY_SERVER_NAME can only be used together with ETCD_GATEWAY_ENDPOINT")
+		}
+		if c.TLS == nil {
+			c.TLS = new(tls.Config)
+		}
+		c.TLS.ServerName = v
+	}
+	if v := settings["ETCD_TLS_SERVER_NAME"]; v != "" {
+		if settings["ETCD_GATEWAY_SERVER_NAME"] != "" {
+			return 0, false, errorf(CodeConflictingVariables, "ETCD_TLS_SERVER_NAME", "you can't set both ETCD_TLS_SERVER_NAME and ETCD_GATEWAY_SERVER_NAME")
+		}
+		if c.TLS == nil {
+			c.TLS = new(tls.Config)
+		}
+		c.TLS.ServerName = v
+	}
+	for _, k := range []string{"ETCD_TLS_MIN_VERSION", "ETCD_TLS_MAX_VERSION"} {
+		v := settings[k]
+		if v == "" {
+			continue
+		}
+		ver, err := parseTLSVersion(k, v)
+		if err != nil {
+			return 0, false, err
+		}
+		if c.TLS == nil {
+			c.TLS = new(tls.Config)
+		}
+		if k == "ETCD_TLS_MIN_VERSION" {
+			c.TLS.MinVersion = ver
+		} else {
+			c.TLS.MaxVersion = ver
+		}
+	}
+	if c.TLS != nil && c.TLS.MinVersion != 0 && c.TLS.MaxVersion != 0 && c.TLS.MinVersion > c.TLS.MaxVersion {
+		return 0, false, errorf(CodeConflictingVariables, "ETCD_TLS_MIN_VERSION", "ETCD_TLS_MIN_VERSION is higher than ETCD_TLS_MAX_VERSION")
+	}
+	if v := settings["ETCD_TLS_CIPHER_SUITES"]; v != "" {
+		suites, err := parseCipherSuites(o, v)
+		if err != nil {
+			return 0, false, err
+		}
+		if c.TLS == nil {
+			c.TLS = new(tls.Config)
+		}
+		c.TLS.CipherSuites = suites
+	}
+	return reloadInterval, appendSystem, nil
+}
+
+// serverCAPEM returns the PEM encoded CAs of ETCD_SERVER_CA, reading them from the system certificate store for system-store:<name>.
+func serverCAPEM(v string) (string, error) {
+	name, ok := strings.CutPrefix(v, "system-store:")
+	if !ok {
+		return v, nil
+	}
+	b, err := systemStorePEM(name)
+	if err == nil && len(b) == 0 {
+		err = errors.New("it has no certificates")
+	}
+	if err != nil {
+		return "", errorf(CodeInvalidCertificate, "ETCD_SERVER_CA", "failed to read the system certificate store %q for ETCD_SERVER_CA: %v", name, err)
+	}
+	return string(b), nil
+}
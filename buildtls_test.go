@@ -0,0 +1,198 @@
+package clientconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/fs"
+	"math/big"
+	"testing"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// testCertificate returns a PEM encoded certificate and key for cn, signed by parent (or self-signed if parent is nil).
+func testCertificate(t *testing.T, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (certPEM, keyPEM string, crt *x509.Certificate, key *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crt, err = x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kb, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kb})), crt, key
+}
+
+func TestApplyTLSMergesCallerConfig(t *testing.T) {
+	caPEM, _, ca, caKey := testCertificate(t, "test-ca", nil, nil)
+	certPEM, keyPEM, clientCrt, _ := testCertificate(t, "alice", ca, caKey)
+	wantPool := x509.NewCertPool()
+	wantPool.AppendCertsFromPEM([]byte(caPEM))
+
+	callerPool := x509.NewCertPool()
+	callerCache := tls.NewLRUClientSessionCache(1)
+	callerCert := tls.Certificate{Certificate: [][]byte{[]byte("caller")}}
+	callerGetCert := func(*tls.CertificateRequestInfo) (*tls.Certificate, error) { return &callerCert, nil }
+	newCaller := func() *tls.Config {
+		return &tls.Config{
+			RootCAs:              callerPool,
+			Certificates:         []tls.Certificate{callerCert},
+			GetClientCertificate: callerGetCert,
+			ServerName:           "caller.example",
+			MinVersion:           tls.VersionTLS10,
+			MaxVersion:           tls.VersionTLS12,
+			CipherSuites:         []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+			ClientSessionCache:   callerCache,
+			VerifyConnection:     func(tls.ConnectionState) error { return nil },
+		}
+	}
+
+	tests := []struct {
+		name     string
+		settings Settings
+		check    func(t *testing.T, got *tls.Config)
+	}{
+		{
+			name:     "caller hooks are kept",
+			settings: Settings{"ETCD_SERVER_CA": caPEM},
+			check: func(t *testing.T, got *tls.Config) {
+				if got.VerifyConnection == nil {
+					t.Error("VerifyConnection was dropped")
+				}
+				if got.ClientSessionCache != callerCache {
+					t.Error("ClientSessionCache was dropped")
+				}
+			},
+		},
+		{
+			name:     "unset variables keep the caller's fields",
+			settings: Settings{},
+			check: func(t *testing.T, got *tls.Config) {
+				if got.RootCAs != callerPool {
+					t.Error("RootCAs was replaced")
+				}
+				if got.ServerName != "caller.example" {
+					t.Errorf("ServerName = %q, want caller.example", got.ServerName)
+				}
+				if got.MinVersion != tls.VersionTLS10 || got.MaxVersion != tls.VersionTLS12 {
+					t.Errorf("versions = %x-%x, want the caller's", got.MinVersion, got.MaxVersion)
+				}
+				if got.GetClientCertificate == nil || len(got.Certificates) != 1 || string(got.Certificates[0].Certificate[0]) != "caller" {
+					t.Error("the caller's client certificate was dropped")
+				}
+			},
+		},
+		{
+			name:     "ETCD_SERVER_CA overrides RootCAs",
+			settings: Settings{"ETCD_SERVER_CA": caPEM},
+			check: func(t *testing.T, got *tls.Config) {
+				if got.RootCAs == callerPool || !got.RootCAs.Equal(wantPool) {
+					t.Error("RootCAs isn't the pool of ETCD_SERVER_CA")
+				}
+			},
+		},
+		{
+			name:     "ETCD_CLIENT_CERT overrides Certificates and clears GetClientCertificate",
+			settings: Settings{"ETCD_CLIENT_CERT": certPEM, "ETCD_CLIENT_KEY": keyPEM},
+			check: func(t *testing.T, got *tls.Config) {
+				if len(got.Certificates) != 1 || string(got.Certificates[0].Certificate[0]) != string(clientCrt.Raw) {
+					t.Error("Certificates isn't the certificate of ETCD_CLIENT_CERT")
+				}
+				if got.GetClientCertificate != nil {
+					t.Error("GetClientCertificate of the caller would hide ETCD_CLIENT_CERT")
+				}
+			},
+		},
+		{
+			name:     "ETCD_TLS_SERVER_NAME overrides ServerName",
+			settings: Settings{"ETCD_TLS_SERVER_NAME": "etcd.example"},
+			check: func(t *testing.T, got *tls.Config) {
+				if got.ServerName != "etcd.example" {
+					t.Errorf("ServerName = %q, want etcd.example", got.ServerName)
+				}
+			},
+		},
+		{
+			name:     "ETCD_TLS_MIN_VERSION and ETCD_TLS_MAX_VERSION override the versions",
+			settings: Settings{"ETCD_TLS_MIN_VERSION": "1.2", "ETCD_TLS_MAX_VERSION": "1.3"},
+			check: func(t *testing.T, got *tls.Config) {
+				if got.MinVersion != tls.VersionTLS12 || got.MaxVersion != tls.VersionTLS13 {
+					t.Errorf("versions = %x-%x, want %x-%x", got.MinVersion, got.MaxVersion, tls.VersionTLS12, tls.VersionTLS13)
+				}
+			},
+		},
+		{
+			name:     "ETCD_TLS_CIPHER_SUITES overrides CipherSuites",
+			settings: Settings{"ETCD_TLS_CIPHER_SUITES": "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"},
+			check: func(t *testing.T, got *tls.Config) {
+				if len(got.CipherSuites) != 1 || got.CipherSuites[0] != tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384 {
+					t.Errorf("CipherSuites = %v, want only TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384", got.CipherSuites)
+				}
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			caller := newCaller()
+			c, err := ApplySettings(clientv3.Config{TLS: caller}, tc.settings, WithEnvironment(testEnvironment{}))
+			if err != nil {
+				t.Fatalf("ApplySettings: %v", err)
+			}
+			if c.TLS == nil {
+				t.Fatal("ApplySettings dropped the TLS config")
+			}
+			if c.TLS == caller {
+				t.Fatal("ApplySettings didn't clone the TLS config")
+			}
+			tc.check(t, c.TLS)
+
+			// Whatever the variables set, the caller's config must be unchanged.
+			if caller.RootCAs != callerPool || caller.ServerName != "caller.example" || caller.MinVersion != tls.VersionTLS10 || caller.MaxVersion != tls.VersionTLS12 {
+				t.Error("the caller's TLS config was modified")
+			}
+			if len(caller.Certificates) != 1 || string(caller.Certificates[0].Certificate[0]) != "caller" || caller.GetClientCertificate == nil {
+				t.Error("the client certificate of the caller's TLS config was modified")
+			}
+			if len(caller.CipherSuites) != 1 || caller.CipherSuites[0] != tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 {
+				t.Error("the cipher suites of the caller's TLS config were modified")
+			}
+		})
+	}
+}
+
+// testEnvironment has no variables or files, so the tests don't depend on the environment they run in.
+type testEnvironment struct{}
+
+func (testEnvironment) Getenv(string) string { return "" }
+
+func (testEnvironment) ReadFile(name string) ([]byte, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
@@ -0,0 +1,37 @@
+package clientconfig
+
+import (
+	"strconv"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// These variables can be set at build time to bake fleet defaults into a binary, e.g.:
+//
+//	go build -ldflags "-X github.com/Jille/etcd-client-from-env.defaultEndpoints=https://etcd.internal:2379 -X github.com/Jille/etcd-client-from-env.requireTLS=true"
+//
+// The environment variables still take precedence at runtime.
+var (
+	// defaultEndpoints is a comma separated list of endpoints returned by Defaults.
+	defaultEndpoints string
+	// requireTLS makes Apply fail if the resulting config doesn't use TLS.
+	requireTLS string
+	// defaultNamespace is the key prefix Connect, Dial and Wrap use if ETCD_NAMESPACE isn't set.
+	defaultNamespace string
+)
+
+// checkRequireTLS returns an error if requireTLS is set and c doesn't use TLS.
+func checkRequireTLS(c clientv3.Config) error {
+	if requireTLS == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(requireTLS)
+	if err != nil {
+		return errorf(CodeInvalidValue, "", "invalid requireTLS set at build time (%q)", requireTLS)
+	}
+	if !b {
+		return nil
+	}
+	// Even with c.TLS set, http:// endpoints are dialed without it.
+	return checkTLSUsed(c, "this binary")
+}
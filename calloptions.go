@@ -0,0 +1,145 @@
+package clientconfig
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// callOptions are the values of ETCD_CALL_OPTIONS.
+var callOptions = map[string]func() grpc.CallOption{
+	// wait-for-ready makes calls wait for a connection instead of failing while all endpoints are down. Combine it with ETCD_REQUEST_TIMEOUT.
+	"wait-for-ready": func() grpc.CallOption { return grpc.WaitForReady(true) },
+	"gzip":           func() grpc.CallOption { return grpc.UseCompressor(gzip.Name) },
+}
+
+func callOptionNames() []string {
+	var ret []string
+	for n := range callOptions {
+		ret = append(ret, n)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// applyCallOptions makes the comma separated call options in v the default for every call.
+func applyCallOptions(c *clientv3.Config, v string) error {
+	var opts []grpc.CallOption
+	for _, n := range strings.Split(v, ",") {
+		n = strings.ToLower(strings.TrimSpace(n))
+		if n == "" {
+			continue
+		}
+		f, ok := callOptions[n]
+		if !ok {
+			return errorf(CodeInvalidValue, "ETCD_CALL_OPTIONS", "unknown call option %q in ETCD_CALL_OPTIONS (should be %s)", n, strings.Join(callOptionNames(), ", "))
+		}
+		opts = append(opts, f())
+	}
+	if len(opts) > 0 {
+		appendDialOptions(c, grpc.WithDefaultCallOptions(opts...))
+	}
+	return nil
+}
+
+// requestTimeout parses ETCD_REQUEST_TIMEOUT. Zero means calls don't get a default deadline.
+func requestTimeout(s Settings) (time.Duration, error) {
+	v := s["ETCD_REQUEST_TIMEOUT"]
+	if v == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, errorf(CodeInvalidValue, "ETCD_REQUEST_TIMEOUT", "failed to parse ETCD_REQUEST_TIMEOUT as a positive duration (%q)", v)
+	}
+	return d, nil
+}
+
+// wrapRequestTimeout gives the KV calls of cli that don't have a deadline a timeout of d.
+// It wraps the KV rather than using an interceptor, because clientv3's retry interceptor comes first and would retry every timed out attempt.
+func wrapRequestTimeout(cli *clientv3.Client, d time.Duration) {
+	if d == 0 {
+		return
+	}
+	cli.KV = timeoutKV{cli.KV, d}
+}
+
+type timeoutKV struct {
+	clientv3.KV
+	timeout time.Duration
+}
+
+// withTimeout returns ctx with the timeout, unless it already has a deadline.
+func (kv timeoutKV) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, kv.timeout)
+}
+
+func (kv timeoutKV) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	ctx, cancel := kv.withTimeout(ctx)
+	defer cancel()
+	return kv.KV.Put(ctx, key, val, opts...)
+}
+
+func (kv timeoutKV) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	ctx, cancel := kv.withTimeout(ctx)
+	defer cancel()
+	return kv.KV.Get(ctx, key, opts...)
+}
+
+func (kv timeoutKV) Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	ctx, cancel := kv.withTimeout(ctx)
+	defer cancel()
+	return kv.KV.Delete(ctx, key, opts...)
+}
+
+func (kv timeoutKV) Compact(ctx context.Context, rev int64, opts ...clientv3.CompactOption) (*clientv3.CompactResponse, error) {
+	ctx, cancel := kv.withTimeout(ctx)
+	defer cancel()
+	return kv.KV.Compact(ctx, rev, opts...)
+}
+
+func (kv timeoutKV) Do(ctx context.Context, op clientv3.Op) (clientv3.OpResponse, error) {
+	ctx, cancel := kv.withTimeout(ctx)
+	defer cancel()
+	return kv.KV.Do(ctx, op)
+}
+
+// Txn starts the timeout right away, because the context is passed here rather than to Commit.
+func (kv timeoutKV) Txn(ctx context.Context) clientv3.Txn {
+	ctx, cancel := kv.withTimeout(ctx)
+	return &timeoutTxn{kv.KV.Txn(ctx), cancel}
+}
+
+// timeoutTxn releases the timer of its context once it's committed.
+type timeoutTxn struct {
+	clientv3.Txn
+	cancel context.CancelFunc
+}
+
+func (t *timeoutTxn) If(cs ...clientv3.Cmp) clientv3.Txn {
+	t.Txn = t.Txn.If(cs...)
+	return t
+}
+
+func (t *timeoutTxn) Then(ops ...clientv3.Op) clientv3.Txn {
+	t.Txn = t.Txn.Then(ops...)
+	return t
+}
+
+func (t *timeoutTxn) Else(ops ...clientv3.Op) clientv3.Txn {
+	t.Txn = t.Txn.Else(ops...)
+	return t
+}
+
+func (t *timeoutTxn) Commit() (*clientv3.TxnResponse, error) {
+	defer t.cancel()
+	return t.Txn.Commit()
+}
@@ -0,0 +1,146 @@
+package clientconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Capabilities are facts about the cluster found by DetectCapabilities.
+type Capabilities struct {
+	// ServerVersions are the versions reported by each reachable endpoint.
+	ServerVersions map[string]string
+	// MinVersion is the lowest version in ServerVersions. Only features supported by all members can be used safely.
+	MinVersion string
+	// MixedVersions is true if members run different versions, like during an upgrade or downgrade.
+	MixedVersions bool
+	// AuthEnabled is whether authentication is enabled on the cluster. It's only valid if AuthErr is nil.
+	AuthEnabled bool
+	AuthErr     error
+	// Learners are the endpoints that are learners. They only serve serializable reads.
+	Learners []string
+	// Warnings about client options that won't work as expected with this cluster. They have also been logged.
+	Warnings []string
+}
+
+// AtLeast returns whether all members run at least version major.minor.
+func (c *Capabilities) AtLeast(major, minor int) bool {
+	return compareVersions(c.MinVersion, fmt.Sprintf("%d.%d.0", major, minor)) >= 0
+}
+
+// DetectCapabilities asks every endpoint of cli for its status and the cluster for its auth status, and warns about client options that won't work.
+// Learner endpoints are removed from cli if other endpoints are available, because clientv3 would send requests to them that they can't serve.
+func DetectCapabilities(ctx context.Context, cli *clientv3.Client) (*Capabilities, error) {
+	eps := cli.Endpoints()
+	if len(eps) == 0 {
+		return nil, errors.New("no endpoints configured")
+	}
+	resps := make([]*clientv3.StatusResponse, len(eps))
+	errs := make([]error, len(eps))
+	var wg sync.WaitGroup
+	for i, ep := range eps {
+		wg.Add(1)
+		go func(i int, ep string) {
+			defer wg.Done()
+			resps[i], errs[i] = cli.Status(ctx, ep)
+		}(i, ep)
+	}
+	caps := &Capabilities{ServerVersions: map[string]string{}}
+	if resp, err := cli.AuthStatus(ctx); err != nil {
+		caps.AuthErr = err
+	} else {
+		caps.AuthEnabled = resp.Enabled
+	}
+	wg.Wait()
+	var lastErr error
+	var voters []string
+	for i, ep := range eps {
+		if errs[i] != nil {
+			lastErr = fmt.Errorf("%s: %v", ep, errs[i])
+			continue
+		}
+		v := resps[i].Version
+		caps.ServerVersions[ep] = v
+		if caps.MinVersion == "" || compareVersions(v, caps.MinVersion) < 0 {
+			if caps.MinVersion != "" {
+				caps.MixedVersions = true
+			}
+			caps.MinVersion = v
+		} else if v != caps.MinVersion {
+			caps.MixedVersions = true
+		}
+		if resps[i].IsLearner {
+			caps.Learners = append(caps.Learners, ep)
+		} else {
+			voters = append(voters, ep)
+		}
+	}
+	if len(caps.ServerVersions) == 0 {
+		return nil, lastErr
+	}
+	sort.Strings(caps.Learners)
+
+	warn := func(format string, args ...interface{}) {
+		w := fmt.Sprintf(format, args...)
+		caps.Warnings = append(caps.Warnings, w)
+		warnf("%s", w)
+	}
+	if caps.MixedVersions {
+		warn("members run different versions (lowest is %s); features of newer versions may not work", caps.MinVersion)
+	}
+	if !caps.AtLeast(3, 4) {
+		warn("cluster runs etcd %s, which is older than what this client supports (3.4)", caps.MinVersion)
+	}
+	if caps.AuthErr == nil {
+		if caps.AuthEnabled && cli.Username == "" {
+			warn("auth is enabled on the cluster, but no credentials are configured; requests only succeed if the client certificate maps to a user")
+		} else if !caps.AuthEnabled && cli.Username != "" {
+			warn("credentials are configured, but auth is disabled on the cluster")
+		}
+	}
+	if len(caps.Learners) > 0 {
+		if len(voters) > 0 {
+			cli.SetEndpoints(voters...)
+			warn("removed learner endpoint(s) %s, which only serve serializable reads", strings.Join(caps.Learners, ", "))
+		} else {
+			warn("all reachable endpoints are learners, which only serve serializable reads")
+		}
+	}
+	return caps, nil
+}
+
+// compareVersions compares two versions like "3.5.1" numerically part by part. Suffixes like "-rc.0" are ignored.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x = leadingInt(as[i])
+		}
+		if i < len(bs) {
+			y = leadingInt(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func leadingInt(s string) int {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	n, _ := strconv.Atoi(s[:i])
+	return n
+}
@@ -0,0 +1,194 @@
+package clientconfig
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// statEnvironment is implemented by Environments that can tell when a file changed without reading it.
+type statEnvironment interface {
+	Stat(name string) (os.FileInfo, error)
+}
+
+func (osEnvironment) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+// fileReloader re-reads a set of files when they change. If the Environment can Stat, unchanged files (by mtime and size) aren't read again.
+type fileReloader struct {
+	env   Environment
+	names []string
+	// interval is how long to keep using the contents before checking again. If zero, the files are checked on every call.
+	interval time.Duration
+
+	mtx       sync.Mutex
+	lastCheck time.Time
+	mtimes    []time.Time
+	sizes     []int64
+	contents  [][]byte
+}
+
+// newFileReloader returns a fileReloader for names, with their current contents.
+func newFileReloader(env Environment, names []string, contents [][]byte, interval time.Duration) *fileReloader {
+	return &fileReloader{env: env, names: names, contents: contents, interval: interval, lastCheck: time.Now()}
+}
+
+// read returns the contents of the files and whether they changed since the last call.
+func (f *fileReloader) read() ([][]byte, bool, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if f.interval > 0 && time.Since(f.lastCheck) < f.interval {
+		return f.contents, false, nil
+	}
+	f.lastCheck = time.Now()
+	var mtimes []time.Time
+	var sizes []int64
+	if st, ok := f.env.(statEnvironment); ok {
+		for _, n := range f.names {
+			fi, err := st.Stat(n)
+			if err != nil {
+				return f.contents, false, err
+			}
+			mtimes = append(mtimes, fi.ModTime())
+			sizes = append(sizes, fi.Size())
+		}
+		if f.mtimes != nil && sameStats(mtimes, sizes, f.mtimes, f.sizes) {
+			return f.contents, false, nil
+		}
+	}
+	contents := make([][]byte, len(f.names))
+	changed := false
+	for i, n := range f.names {
+		b, err := f.env.ReadFile(n)
+		if err != nil {
+			return f.contents, false, err
+		}
+		contents[i] = b
+		if !bytes.Equal(b, f.contents[i]) {
+			changed = true
+		}
+	}
+	f.mtimes, f.sizes, f.contents = mtimes, sizes, contents
+	return contents, changed, nil
+}
+
+func sameStats(mtimes []time.Time, sizes []int64, oldMtimes []time.Time, oldSizes []int64) bool {
+	for i := range mtimes {
+		if !mtimes[i].Equal(oldMtimes[i]) || sizes[i] != oldSizes[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// clientCertReloader serves the client certificate from ETCD_CLIENT_CERT_FILE and ETCD_CLIENT_KEY_FILE (or twice ETCD_CLIENT_CERT_AND_KEY_FILE), re-reading them when they change so rotated certificates are picked up on the next connection.
+type clientCertReloader struct {
+	o     *options
+	files *fileReloader
+	// password decrypts the key, if it's encrypted.
+	password []byte
+
+	mtx  sync.Mutex
+	cert tls.Certificate
+}
+
+// GetClientCertificate implements tls.Config.GetClientCertificate. If the files can't be read or don't form a valid pair (like halfway through a rotation), it keeps using the previous certificate.
+func (r *clientCertReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	contents, changed, err := r.files.read()
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if err != nil {
+		r.o.warnf("failed to reload the client certificate, using the previous certificate: %v", err)
+		r.o.tlsReloaded("client-cert", err)
+	} else if changed {
+		crt, err := clientKeyPair(contents[0], contents[1], r.password)
+		if err != nil {
+			r.o.warnf("failed to parse the reloaded client certificate, using the previous certificate: %v", err)
+		} else {
+			r.cert = crt
+		}
+		r.o.tlsReloaded("client-cert", err)
+	}
+	cert := r.cert
+	return &cert, nil
+}
+
+// rootCAReloader re-reads ETCD_SERVER_CA_FILE when it changes, so CA rotations are picked up on the next connection.
+type rootCAReloader struct {
+	o            *options
+	files        *fileReloader
+	appendSystem bool
+
+	mtx  sync.Mutex
+	pool *x509.CertPool
+}
+
+// rootCAs returns the current pool. If the file can't be read or parsed, it keeps using the previous one.
+func (r *rootCAReloader) rootCAs() *x509.CertPool {
+	contents, changed, err := r.files.read()
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if err != nil {
+		r.o.warnf("failed to reload ETCD_SERVER_CA_FILE, using the previous CA(s): %v", err)
+		r.o.tlsReloaded("ca", err)
+	} else if changed {
+		pool, err := newRootPool(r.appendSystem)
+		if err != nil {
+			r.o.warnf("failed to load the system certificate pool, using the previous CA(s): %v", err)
+		} else if pool.AppendCertsFromPEM(contents[0]) {
+			r.pool = pool
+		} else {
+			err = errors.New("reloaded ETCD_SERVER_CA_FILE doesn't contain valid PEM certificates")
+			r.o.warnf("%v, using the previous CA(s)", err)
+		}
+		r.o.tlsReloaded("ca", err)
+	}
+	return r.pool
+}
+
+// reloadingCredentials is like credentials.NewTLS(base), but gets the current root CAs for every handshake.
+// grpc clones the tls.Config when the client is created, so changing RootCAs afterwards wouldn't have any effect.
+type reloadingCredentials struct {
+	base  *tls.Config
+	roots func() *x509.CertPool
+}
+
+func (r *reloadingCredentials) ClientHandshake(ctx context.Context, authority string, rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	cfg := r.base.Clone()
+	cfg.RootCAs = r.roots()
+	return credentials.NewTLS(cfg).ClientHandshake(ctx, authority, rawConn)
+}
+
+func (r *reloadingCredentials) ServerHandshake(rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return credentials.NewTLS(r.base).ServerHandshake(rawConn)
+}
+
+func (r *reloadingCredentials) Info() credentials.ProtocolInfo {
+	return credentials.NewTLS(r.base).Info()
+}
+
+func (r *reloadingCredentials) Clone() credentials.TransportCredentials {
+	return &reloadingCredentials{base: r.base.Clone(), roots: r.roots}
+}
+
+func (r *reloadingCredentials) OverrideServerName(name string) error {
+	r.base.ServerName = name
+	return nil
+}
+
+// newRootPool returns an empty pool, or a copy of the system pool if appendSystem is set.
+func newRootPool(appendSystem bool) (*x509.CertPool, error) {
+	if appendSystem {
+		return x509.SystemCertPool()
+	}
+	return x509.NewCertPool(), nil
+}
@@ -0,0 +1,28 @@
+package clientconfig
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keychains are the paths of the keychains that aren't found by name.
+var keychains = map[string]string{
+	"System":                 "/Library/Keychains/System.keychain",
+	"SystemRootCertificates": "/System/Library/Keychains/SystemRootCertificates.keychain",
+}
+
+// systemStorePEM returns the certificates in the macOS keychain with the given name (like "System", "login.keychain" or a path) as PEM, with security(1).
+func systemStorePEM(name string) ([]byte, error) {
+	if p, ok := keychains[name]; ok {
+		name = p
+	}
+	out, err := exec.Command("/usr/bin/security", "find-certificate", "-a", "-p", name).Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+			return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(string(ee.Stderr)))
+		}
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,13 @@
+//go:build !windows && !darwin
+// +build !windows,!darwin
+
+package clientconfig
+
+import (
+	"errors"
+	"runtime"
+)
+
+func systemStorePEM(name string) ([]byte, error) {
+	return nil, errors.New("system certificate stores are only supported on Windows and macOS, not " + runtime.GOOS)
+}
@@ -0,0 +1,37 @@
+package clientconfig
+
+import (
+	"encoding/pem"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// systemStorePEM returns the certificates in the Windows system store with the given name (like "Root" or "CA") as PEM. The stores of the current user include the ones of the machine, like CAs distributed with group policy.
+func systemStorePEM(name string) ([]byte, error) {
+	n, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	store, err := windows.CertOpenSystemStore(0, n)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CertCloseStore(store, 0)
+	var ret []byte
+	var cert *windows.CertContext
+	for {
+		cert, err = windows.CertEnumCertificatesInStore(store, cert)
+		if err != nil {
+			if err == windows.Errno(windows.CRYPT_E_NOT_FOUND) {
+				return ret, nil
+			}
+			return nil, err
+		}
+		if cert == nil {
+			return ret, nil
+		}
+		der := unsafe.Slice(cert.EncodedCert, cert.Length)
+		ret = append(ret, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+}
@@ -0,0 +1,45 @@
+// Package clientconfigtest helps testing code that is configured with github.com/Jille/etcd-client-from-env: it applies variables from a map instead of the process environment, compares configs against golden descriptions, and starts an embedded etcd with the TLS and authentication settings under test.
+//
+// It's a separate module so the etcd server only becomes a dependency of your tests.
+package clientconfigtest
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	clientconfig "github.com/Jille/etcd-client-from-env"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// ApplyFromMap applies the variables in vars to clientconfig.Defaults(), ignoring the process environment. Files named by _FILE variables are still read from disk; pass clientconfig.WithFS to read them from somewhere else.
+func ApplyFromMap(vars map[string]string, opts ...clientconfig.Option) (clientv3.Config, error) {
+	return clientconfig.ApplyFrom(clientconfig.Defaults(), clientconfig.MapLookuper(vars), opts...)
+}
+
+// AssertConfig fails the test if the clientconfig.DumpRedacted description of c isn't want, like "endpoints=https://127.0.0.1:2379 tls=on(ca=custom) dial-timeout=15s auto-sync-interval=5m0s".
+func AssertConfig(t testing.TB, c clientv3.Config, want string) {
+	t.Helper()
+	if got := clientconfig.DumpRedacted(c); got != want {
+		t.Errorf("config mismatch:\n got: %s\nwant: %s", got, want)
+	}
+}
+
+// AssertGolden is like AssertConfig, but reads the expected description from the file golden (like testdata/production.golden). Run the test with CLIENTCONFIGTEST_UPDATE=1 to write the current description to it instead.
+func AssertGolden(t testing.TB, c clientv3.Config, golden string) {
+	t.Helper()
+	got := clientconfig.DumpRedacted(c)
+	if os.Getenv("CLIENTCONFIGTEST_UPDATE") != "" {
+		if err := os.WriteFile(golden, []byte(got+"\n"), 0644); err != nil {
+			t.Fatalf("failed to update %s: %v", golden, err)
+		}
+		return
+	}
+	b, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed to read golden file (set CLIENTCONFIGTEST_UPDATE=1 to create it): %v", err)
+	}
+	if want := strings.TrimRight(string(b), "\n"); got != want {
+		t.Errorf("config doesn't match %s (set CLIENTCONFIGTEST_UPDATE=1 to update it):\n got: %s\nwant: %s", golden, got, want)
+	}
+}
@@ -0,0 +1,220 @@
+package clientconfigtest
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/client/pkg/v3/transport"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+// EtcdOptions describes the etcd StartEtcd starts.
+type EtcdOptions struct {
+	// TLS makes etcd serve https with a certificate from a throwaway CA.
+	TLS bool
+	// ClientCerts makes etcd demand a client certificate from the same CA. It implies TLS.
+	ClientCerts bool
+	// Username and Password enable authentication, with this user (root if only a Password is given) in the root role. With ClientCerts, a Username without a Password authenticates with the CN of the client certificate instead.
+	Username, Password string
+}
+
+// Etcd is a running embedded etcd.
+type Etcd struct {
+	// Endpoint is its client URL, like https://127.0.0.1:41234.
+	Endpoint string
+	// Vars are the variables that configure a client for it, like ETCD_ENDPOINTS and ETCD_SERVER_CA_FILE. Merge them into the variables you pass to ApplyFromMap.
+	Vars map[string]string
+}
+
+// StartEtcd starts an embedded single node etcd for the duration of the test, with the TLS and authentication settings in eo. Certificates, keys and data are kept in t.TempDir().
+func StartEtcd(t testing.TB, eo EtcdOptions) *Etcd {
+	t.Helper()
+	dir := t.TempDir()
+	if eo.ClientCerts {
+		eo.TLS = true
+	}
+	user := eo.Username
+	if user == "" && eo.Password != "" {
+		user = "root"
+	}
+	if user != "" && eo.Password == "" && !eo.ClientCerts {
+		t.Fatalf("clientconfigtest: user %q needs a Password or ClientCerts to log in", user)
+	}
+	scheme := "http"
+	vars := map[string]string{}
+	cfg := embed.NewConfig()
+	cfg.Dir = filepath.Join(dir, "data")
+	cfg.LogLevel = "error"
+	if eo.TLS {
+		scheme = "https"
+		if err := writeCertificates(dir, user); err != nil {
+			t.Fatalf("clientconfigtest: failed to create certificates: %v", err)
+		}
+		cfg.ClientTLSInfo = transport.TLSInfo{
+			CertFile: filepath.Join(dir, "server.crt"),
+			KeyFile:  filepath.Join(dir, "server.key"),
+		}
+		vars["ETCD_SERVER_CA_FILE"] = filepath.Join(dir, "ca.crt")
+		if eo.ClientCerts {
+			// etcd demands a client certificate as soon as it has a CA to verify them with.
+			cfg.ClientTLSInfo.TrustedCAFile = filepath.Join(dir, "ca.crt")
+			cfg.ClientTLSInfo.ClientCertAuth = true
+			vars["ETCD_CLIENT_CERT_FILE"] = filepath.Join(dir, "client.crt")
+			vars["ETCD_CLIENT_KEY_FILE"] = filepath.Join(dir, "client.key")
+		}
+	}
+	clientURL := url.URL{Scheme: scheme, Host: freeAddress(t)}
+	peerURL := url.URL{Scheme: "http", Host: freeAddress(t)}
+	cfg.ListenClientUrls, cfg.AdvertiseClientUrls = []url.URL{clientURL}, []url.URL{clientURL}
+	cfg.ListenPeerUrls, cfg.AdvertisePeerUrls = []url.URL{peerURL}, []url.URL{peerURL}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		t.Fatalf("clientconfigtest: failed to start etcd: %v", err)
+	}
+	t.Cleanup(e.Close)
+	select {
+	case <-e.Server.ReadyNotify():
+	case err := <-e.Err():
+		t.Fatalf("clientconfigtest: etcd failed: %v", err)
+	case <-time.After(time.Minute):
+		t.Fatalf("clientconfigtest: etcd didn't become ready within a minute")
+	}
+	vars["ETCD_ENDPOINTS"] = clientURL.String()
+	if user != "" {
+		if err := enableAuth(vars, user, eo.Password); err != nil {
+			t.Fatalf("clientconfigtest: failed to enable authentication: %v", err)
+		}
+		if eo.Password != "" {
+			vars["ETCD_USERNAME"] = user
+			vars["ETCD_PASSWORD"] = eo.Password
+		}
+	}
+	return &Etcd{Endpoint: clientURL.String(), Vars: vars}
+}
+
+// enableAuth creates user (and root, which etcd requires) with the root role and enables authentication.
+func enableAuth(vars map[string]string, user, password string) error {
+	c, err := ApplyFromMap(vars)
+	if err != nil {
+		return err
+	}
+	cli, err := clientv3.New(c)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	users := []string{"root"}
+	if user != "root" {
+		users = append(users, user)
+	}
+	for _, u := range users {
+		if password == "" {
+			_, err = cli.UserAddWithOptions(ctx, u, "", &clientv3.UserAddOptions{NoPassword: true})
+		} else {
+			_, err = cli.UserAdd(ctx, u, password)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to add user %q: %v", u, err)
+		}
+		if _, err := cli.UserGrantRole(ctx, u, "root"); err != nil {
+			return fmt.Errorf("failed to grant the root role to %q: %v", u, err)
+		}
+	}
+	_, err = cli.AuthEnable(ctx)
+	return err
+}
+
+// freeAddress returns a 127.0.0.1 address with a port that was free a moment ago.
+func freeAddress(t testing.TB) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("clientconfigtest: failed to find a free port: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// writeCertificates writes a CA (ca.crt), a server certificate for 127.0.0.1 and localhost (server.crt and server.key) and a client certificate for user (client.crt and client.key) to dir.
+func writeCertificates(dir, user string) error {
+	if user == "" {
+		user = "root"
+	}
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	ca := certificateTemplate("clientconfigtest CA")
+	ca.IsCA = true
+	ca.BasicConstraintsValid = true
+	ca.KeyUsage = x509.KeyUsageCertSign
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, &caKey.PublicKey, caKey)
+	if err != nil {
+		return err
+	}
+	if err := writePEM(filepath.Join(dir, "ca.crt"), "CERTIFICATE", caDER); err != nil {
+		return err
+	}
+	server := certificateTemplate("localhost")
+	server.DNSNames = []string{"localhost"}
+	server.IPAddresses = []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+	server.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	if err := writeCertificate(dir, "server", server, ca, caKey); err != nil {
+		return err
+	}
+	client := certificateTemplate(user)
+	client.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	return writeCertificate(dir, "client", client, ca, caKey)
+}
+
+func certificateTemplate(cn string) *x509.Certificate {
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+}
+
+// writeCertificate signs tmpl with the CA and writes it to name.crt and its key to name.key.
+func writeCertificate(dir, name string, tmpl, ca *x509.Certificate, caKey *ecdsa.PrivateKey) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return err
+	}
+	if err := writePEM(filepath.Join(dir, name+".crt"), "CERTIFICATE", der); err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	return writePEM(filepath.Join(dir, name+".key"), "PRIVATE KEY", keyDER)
+}
+
+func writePEM(fn, typ string, der []byte) error {
+	return os.WriteFile(fn, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600)
+}
@@ -0,0 +1,53 @@
+package clientconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+)
+
+// clientKeyPair is like tls.X509KeyPair, but decrypts the key with password if it's encrypted. A key URI (like pkcs11:...) is passed to the registered key loader instead.
+// Only the legacy PEM encryption ("Proc-Type: 4,ENCRYPTED", as written by openssl's -aes256 and friends) is supported. Encrypted PKCS#8 keys ("ENCRYPTED PRIVATE KEY") have to be converted first.
+func clientKeyPair(cert, key, password []byte) (tls.Certificate, error) {
+	if scheme, ok := keyURIScheme(key); ok {
+		return loadKeyPair(scheme, cert, key, password)
+	}
+	key, err := decryptClientKey(key, password)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.X509KeyPair(cert, key)
+}
+
+func decryptClientKey(key, password []byte) ([]byte, error) {
+	var ret []byte
+	encrypted := false
+	for rest := key; ; {
+		var b *pem.Block
+		b, rest = pem.Decode(rest)
+		if b == nil {
+			break
+		}
+		switch {
+		case b.Type == "ENCRYPTED PRIVATE KEY":
+			return nil, errors.New("encrypted PKCS#8 keys aren't supported; convert it with `openssl rsa -aes256` or `openssl ec -aes256`")
+		case x509.IsEncryptedPEMBlock(b):
+			if len(password) == 0 {
+				return nil, errors.New("ETCD_CLIENT_KEY is encrypted, but ETCD_CLIENT_KEY_PASSWORD isn't set")
+			}
+			der, err := x509.DecryptPEMBlock(b, password)
+			if err != nil {
+				return nil, err
+			}
+			encrypted = true
+			ret = append(ret, pem.EncodeToMemory(&pem.Block{Type: b.Type, Bytes: der})...)
+		default:
+			ret = append(ret, pem.EncodeToMemory(b)...)
+		}
+	}
+	if !encrypted {
+		return key, nil
+	}
+	return ret, nil
+}
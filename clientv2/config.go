@@ -0,0 +1,57 @@
+// Package clientv2 constructs a Config for the etcd v2 API from the same environment variables as clientconfig.
+//
+// This is meant for services that still speak the v2 HTTP API during their migration, so both stacks share one source of configuration.
+package clientv2
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	clientconfig "github.com/Jille/etcd-client-from-env"
+	client "go.etcd.io/etcd/client/v2"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Get returns a client.Config for the v2 API based on clientconfig's defaults and the environment.
+func Get() (client.Config, error) {
+	return Apply(clientconfig.Defaults())
+}
+
+// Apply reads the environment variables into c (with clientconfig.Apply) and converts the result into a client.Config.
+func Apply(c clientv3.Config) (client.Config, error) {
+	c, err := clientconfig.Apply(c)
+	if err != nil {
+		return client.Config{}, err
+	}
+	return Convert(c), nil
+}
+
+// Convert maps the endpoints, TLS settings and basic auth of a clientv3.Config onto a client.Config.
+// Endpoints without a scheme get https:// if TLS is configured and http:// otherwise, as the v2 client requires full URLs.
+func Convert(c clientv3.Config) client.Config {
+	scheme := "http://"
+	if c.TLS != nil {
+		scheme = "https://"
+	}
+	ret := client.Config{
+		Username: c.Username,
+		Password: c.Password,
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   c.DialTimeout,
+				KeepAlive: c.DialKeepAliveTime,
+			}).DialContext,
+			TLSClientConfig:     c.TLS,
+			TLSHandshakeTimeout: c.DialTimeout,
+		},
+	}
+	for _, ep := range c.Endpoints {
+		if !strings.Contains(ep, "://") {
+			ep = scheme + ep
+		}
+		ret.Endpoints = append(ret.Endpoints, ep)
+	}
+	return ret
+}
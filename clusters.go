@@ -0,0 +1,67 @@
+package clientconfig
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// ApplyNamed is like ApplyWithOptions, but reads the variables of the named cluster, like ETCD_METRICS_ENDPOINTS for "metrics". See WithCluster.
+func ApplyNamed(c clientv3.Config, name string, opts ...Option) (clientv3.Config, error) {
+	return ApplyWithOptions(c, append(opts[:len(opts):len(opts)], WithCluster(name))...)
+}
+
+// WithCluster reads the variables of the named cluster, with the (upper cased) name after ETCD_: ETCD_METRICS_ENDPOINTS, ETCD_METRICS_SERVER_CA_FILE and so on for "metrics". Names consist of letters and digits.
+func WithCluster(name string) Option {
+	return func(o *options) {
+		o.cluster = strings.ToUpper(name)
+	}
+}
+
+// clusterEnvironment reads the variables of a named cluster.
+type clusterEnvironment struct {
+	Environment
+	cluster string
+}
+
+func (e clusterEnvironment) Getenv(key string) string {
+	if !strings.HasPrefix(key, "ETCD_") {
+		return e.Environment.Getenv(key)
+	}
+	return e.Environment.Getenv("ETCD_" + e.cluster + "_" + strings.TrimPrefix(key, "ETCD_"))
+}
+
+// ListClusters returns the (lower cased) names of the clusters that have variables in the process environment, for use with ApplyNamed.
+func ListClusters() []string {
+	known := knownVariableNames()
+	seen := map[string]bool{}
+	var ret []string
+	for _, kv := range os.Environ() {
+		k := strings.SplitN(kv, "=", 2)[0]
+		if !strings.HasPrefix(k, "ETCD_") || known[k] {
+			continue
+		}
+		sp := strings.SplitN(strings.TrimPrefix(k, "ETCD_"), "_", 2)
+		if len(sp) != 2 || !isClusterName(sp[0]) || !known["ETCD_"+sp[1]] {
+			continue
+		}
+		name := strings.ToLower(sp[0])
+		if !seen[name] {
+			seen[name] = true
+			ret = append(ret, name)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func isClusterName(s string) bool {
+	for _, r := range s {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,176 @@
+// Command etcd-env-check debugs "my service can't reach etcd": it reads the configuration exactly like github.com/Jille/etcd-client-from-env does, prints it with secrets redacted, tests connectivity and authentication and explains what's wrong.
+//
+// Usage:
+//
+//	etcd-env-check [--timeout=5s] [--key=/some/key]
+//
+// It exits 0 if an endpoint answered and the credentials were accepted, and 1 with a diagnosis otherwise. With --key, it also checks that the configured user may read that key.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	clientconfig "github.com/Jille/etcd-client-from-env"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+func main() {
+	timeout := flag.Duration("timeout", 5*time.Second, "How long to wait for etcd to answer")
+	key := flag.String("key", "", "Also check that the user may read this key")
+	flag.Parse()
+
+	ok := run(*timeout, *key)
+	clientconfig.StopDevEtcd()
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+// run prints the report and returns whether everything worked.
+func run(timeout time.Duration, key string) bool {
+	fmt.Println("Configuration:")
+	c, err := clientconfig.Get()
+	if err != nil {
+		fail("%s", describeConfigError(err))
+		return false
+	}
+	fmt.Printf("  %s\n", clientconfig.DumpRedacted(c))
+	if err := clientconfig.Validate(c); err != nil {
+		for _, e := range unjoin(err) {
+			fmt.Printf("  warning: %s\n", describeConfigError(e))
+		}
+	}
+
+	fmt.Println("Connectivity:")
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	c.Logger = zap.NewNop()
+	if c.DialTimeout == 0 || c.DialTimeout > timeout {
+		c.DialTimeout = timeout
+	}
+	info, err := clientconfig.Ping(ctx, c)
+	if err != nil {
+		if diag := describeAuthError(c, err); diag != "" {
+			fail("%s", diag)
+			return false
+		}
+		fail("no endpoint answered: %v", err)
+		diagnoseTLS(c, timeout)
+		return false
+	}
+	for _, s := range info.Endpoints {
+		if s.Err != nil {
+			fmt.Printf("  %s: unreachable: %v\n", s.Endpoint, s.Err)
+			continue
+		}
+		role := "follower"
+		if s.IsLearner {
+			role = "learner"
+		} else if s.Endpoint == info.LeaderEndpoint {
+			role = "leader"
+		}
+		fmt.Printf("  %s: ok, etcd %s, %s, %s\n", s.Endpoint, s.Version, role, s.RTT.Round(time.Millisecond))
+	}
+	fmt.Printf("  cluster %x, leader %x\n", info.ClusterID, info.Leader)
+
+	fmt.Println("Authentication:")
+	switch {
+	case c.Username != "":
+		fmt.Printf("  authenticated as %s\n", c.Username)
+	case c.TLS != nil && (len(c.TLS.Certificates) > 0 || c.TLS.GetClientCertificate != nil):
+		fmt.Println("  using the client certificate (etcd uses its common name as the user if auth is enabled)")
+	default:
+		fmt.Println("  no credentials configured")
+	}
+	if key == "" {
+		return true
+	}
+	cli, err := clientv3.New(c)
+	if err != nil {
+		fail("%v", err)
+		return false
+	}
+	defer cli.Close()
+	if _, err := cli.Get(ctx, key, clientv3.WithCountOnly()); err != nil {
+		if rpctypes.Error(err) == rpctypes.ErrPermissionDenied {
+			fail("the user may not read %q; grant it a role with read permission on that key", key)
+		} else if diag := describeAuthError(c, err); diag != "" {
+			fail("%s", diag)
+		} else {
+			fail("reading %q failed: %v", key, err)
+		}
+		return false
+	}
+	fmt.Printf("  may read %q\n", key)
+	return true
+}
+
+func fail(format string, args ...interface{}) {
+	fmt.Printf("  FAIL: "+format+"\n", args...)
+}
+
+// unjoin returns the errors joined by errors.Join.
+func unjoin(err error) []error {
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		return j.Unwrap()
+	}
+	return []error{err}
+}
+
+// describeConfigError adds the code and variable of a configuration error.
+func describeConfigError(err error) string {
+	var e *clientconfig.Error
+	if !errors.As(err, &e) {
+		return err.Error()
+	}
+	if e.Variable == "" {
+		return fmt.Sprintf("%s [%s]", e.Error(), e.Code)
+	}
+	return fmt.Sprintf("%s [%s, see %s]", e.Error(), e.Code, e.Variable)
+}
+
+// describeAuthError explains err if etcd rejected the credentials, and returns "" otherwise.
+func describeAuthError(c clientv3.Config, err error) string {
+	switch rpctypes.Error(err) {
+	case rpctypes.ErrAuthFailed:
+		return fmt.Sprintf("etcd rejected the password of user %q; check ETCD_USERNAME and ETCD_PASSWORD", c.Username)
+	case rpctypes.ErrUserEmpty:
+		return "authentication is enabled on the cluster, but no credentials are configured; set ETCD_USERNAME and ETCD_PASSWORD or use a client certificate"
+	case rpctypes.ErrPermissionDenied:
+		return "the user may not call Status; grant it a role"
+	}
+	return ""
+}
+
+// diagnoseTLS probes the endpoints to explain why they didn't answer.
+func diagnoseTLS(c clientv3.Config, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	_, probes := clientconfig.ProbeTLS(ctx, c)
+	hasCert := c.TLS != nil && (len(c.TLS.Certificates) > 0 || c.TLS.GetClientCertificate != nil)
+	for _, p := range probes {
+		wantTLS := strings.HasPrefix(p.Endpoint, "https://") || strings.HasPrefix(p.Endpoint, "unixs://") || (!strings.Contains(p.Endpoint, "://") && c.TLS != nil)
+		switch {
+		case p.Err != nil:
+			fmt.Printf("  %s: can't connect (%v); check the host, port and firewalls\n", p.Endpoint, p.Err)
+		case p.TLS && !wantTLS:
+			fmt.Printf("  %s: the server speaks TLS; use https:// in ETCD_ENDPOINTS and set ETCD_SERVER_CA_FILE\n", p.Endpoint)
+		case !p.TLS && wantTLS:
+			fmt.Printf("  %s: the server doesn't speak TLS; use http:// in ETCD_ENDPOINTS\n", p.Endpoint)
+		case p.TLS && p.VerifyErr != nil && (c.TLS == nil || !c.TLS.InsecureSkipVerify):
+			fmt.Printf("  %s: the server certificate isn't trusted (%v); set ETCD_SERVER_CA_FILE to the CA that signed it, or ETCD_TLS_SERVER_NAME if the name doesn't match\n", p.Endpoint, p.VerifyErr)
+		case p.ClientCertRequested && !hasCert:
+			fmt.Printf("  %s: the server wants a client certificate; set ETCD_CLIENT_CERT_FILE and ETCD_CLIENT_KEY_FILE\n", p.Endpoint)
+		default:
+			fmt.Printf("  %s: TLS looks fine, but etcd didn't answer in time\n", p.Endpoint)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Command etcd-env-exec runs a command with the configuration of github.com/Jille/etcd-client-from-env resolved into plain variables, for tools that only understand etcdctl's variables.
+//
+// Usage:
+//
+//	etcd-env-exec [--] command [args...]
+//
+// For example: etcd-env-exec etcdctl snapshot save /backups/etcd.db
+//
+// It resolves all _FILE, _COMMAND, _SOURCE and other variants, and starts the command with ETCDCTL_API=3, ETCDCTL_ENDPOINTS, ETCDCTL_CACERT, ETCDCTL_CERT, ETCDCTL_KEY, ETCDCTL_USER, ETCDCTL_PASSWORD, ETCDCTL_INSECURE_SKIP_TLS_VERIFY and ETCDCTL_DIAL_TIMEOUT, plus the equivalent ETCD_* variables (see clientconfig.ExportFiles).
+// Our own ETCD_* variables and any ETCDCTL_* variables are removed from the environment of the command, so nothing conflicts.
+//
+// Certificates and keys are written to a private temporary directory, which is removed when the command exits. SIGINT and SIGTERM are forwarded to the command, and its exit code is passed on.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	clientconfig "github.com/Jille/etcd-client-from-env"
+)
+
+// suffixes are the variants of each variable, which are resolved and thus removed from the environment.
+var suffixes = []string{"", "_FILE", "_B64", "_COMMAND", "_KEYRING", "_SOURCE"}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [--] command [args...]\n", os.Args[0])
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	code, err := run(flag.Args())
+	clientconfig.StopDevEtcd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "etcd-env-exec: %v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(code)
+}
+
+func run(args []string) (int, error) {
+	settings, err := clientconfig.ReadSettings()
+	if err != nil {
+		return 0, err
+	}
+	// The command connects by itself, so there's nothing to probe or start for it.
+	c, err := clientconfig.ApplySettings(clientconfig.Defaults(), settings, clientconfig.WithoutSideEffects())
+	if err != nil {
+		return 0, err
+	}
+	dir, err := os.MkdirTemp("", "etcd-env-exec")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(dir)
+	vars, err := clientconfig.ExportFiles(c, dir)
+	if err != nil {
+		return 0, err
+	}
+	if v := settings["ETCD_NAMESPACE"]; v != "" {
+		vars["ETCD_NAMESPACE"] = v
+	}
+	for k, v := range etcdctlVariables(vars) {
+		vars[k] = v
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = childEnvironment(os.Environ(), vars)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	go func() {
+		for s := range sigs {
+			cmd.Process.Signal(s)
+		}
+	}()
+	err = cmd.Wait()
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		return ee.ExitCode(), nil
+	}
+	return 0, err
+}
+
+// etcdctlVariables translates the exported ETCD_* variables to the ones etcdctl reads.
+func etcdctlVariables(vars map[string]string) map[string]string {
+	ret := map[string]string{"ETCDCTL_API": "3"}
+	for from, to := range map[string]string{
+		"ETCD_ENDPOINTS":            "ETCDCTL_ENDPOINTS",
+		"ETCD_SERVER_CA_FILE":       "ETCDCTL_CACERT",
+		"ETCD_CLIENT_CERT_FILE":     "ETCDCTL_CERT",
+		"ETCD_CLIENT_KEY_FILE":      "ETCDCTL_KEY",
+		"ETCD_USERNAME":             "ETCDCTL_USER",
+		"ETCD_PASSWORD":             "ETCDCTL_PASSWORD",
+		"ETCD_INSECURE_SKIP_VERIFY": "ETCDCTL_INSECURE_SKIP_TLS_VERIFY",
+		"ETCD_DIAL_TIMEOUT":         "ETCDCTL_DIAL_TIMEOUT",
+	} {
+		if v, ok := vars[from]; ok {
+			ret[to] = v
+		}
+	}
+	return ret
+}
+
+// childEnvironment returns env without our variables and any ETCDCTL_* variables, with vars added.
+func childEnvironment(env []string, vars map[string]string) []string {
+	ours := map[string]bool{}
+	for _, v := range clientconfig.EnvVars() {
+		for _, s := range suffixes {
+			ours[v.Name+s] = true
+		}
+	}
+	var ret []string
+	for _, kv := range env {
+		k, _, _ := strings.Cut(kv, "=")
+		if ours[k] || strings.HasPrefix(k, "ETCDCTL_") {
+			continue
+		}
+		ret = append(ret, kv)
+	}
+	for k, v := range vars {
+		ret = append(ret, k+"="+v)
+	}
+	return ret
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+)
+
+func check(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	quiet := fs.Bool("quiet", false, "Don't print anything on success")
+	timeout := fs.Duration("timeout", 3*time.Second, "How long to wait for etcd to answer")
+	fs.Parse(args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	ep, err := probe(ctx, *timeout)
+	if err != nil {
+		return err
+	}
+	if !*quiet {
+		fmt.Printf("etcd is reachable at %s\n", ep)
+	}
+	return nil
+}
+
+// probe connects to etcd and returns the first endpoint that answers a Status call.
+func probe(ctx context.Context, timeout time.Duration) (string, error) {
+	cli, err := newClient(timeout)
+	if err != nil {
+		return "", err
+	}
+	defer cli.Close()
+	eps := cli.Endpoints()
+	if len(eps) == 0 {
+		return "", errors.New("no endpoints configured")
+	}
+	var lastErr error
+	for _, ep := range eps {
+		if _, err := cli.Status(ctx, ep); err != nil {
+			lastErr = fmt.Errorf("%s: %v", ep, err)
+			continue
+		}
+		return ep, nil
+	}
+	return "", lastErr
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	clientconfig "github.com/Jille/etcd-client-from-env"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// newClient creates a quiet client from the environment.
+func newClient(timeout time.Duration) (*clientv3.Client, error) {
+	c, err := clientconfig.Get()
+	if err != nil {
+		return nil, err
+	}
+	if c.DialTimeout == 0 || c.DialTimeout > timeout {
+		c.DialTimeout = timeout
+	}
+	c.Logger = zap.NewNop()
+	return clientv3.New(c)
+}
+
+func printJSON(v interface{}) error {
+	e := json.NewEncoder(os.Stdout)
+	e.SetIndent("", "  ")
+	return e.Encode(v)
+}
+
+type member struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Started    bool     `json:"started"`
+	Learner    bool     `json:"learner"`
+	PeerURLs   []string `json:"peer_urls"`
+	ClientURLs []string `json:"client_urls"`
+}
+
+func memberList(args []string) error {
+	fs := flag.NewFlagSet("member-list", flag.ExitOnError)
+	timeout := fs.Duration("timeout", 5*time.Second, "How long to wait for etcd")
+	output := fs.String("output", "table", "Output format: table or json")
+	fs.Parse(args)
+
+	cli, err := newClient(*timeout)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	resp, err := cli.MemberList(ctx)
+	if err != nil {
+		return err
+	}
+	var members []member
+	for _, m := range resp.Members {
+		members = append(members, member{
+			ID:         fmt.Sprintf("%x", m.ID),
+			Name:       m.Name,
+			Started:    m.Name != "",
+			Learner:    m.IsLearner,
+			PeerURLs:   m.PeerURLs,
+			ClientURLs: m.ClientURLs,
+		})
+	}
+	switch *output {
+	case "json":
+		return printJSON(members)
+	case "table":
+		tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tSTATUS\tLEARNER\tPEER URLS\tCLIENT URLS")
+		for _, m := range members {
+			status := "started"
+			if !m.Started {
+				status = "unstarted"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%v\t%s\t%s\n", m.ID, m.Name, status, m.Learner, strings.Join(m.PeerURLs, ","), strings.Join(m.ClientURLs, ","))
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown --output %q", *output)
+	}
+}
+
+type endpointStatus struct {
+	Endpoint         string   `json:"endpoint"`
+	Error            string   `json:"error,omitempty"`
+	ID               string   `json:"id,omitempty"`
+	Version          string   `json:"version,omitempty"`
+	DBSize           int64    `json:"db_size,omitempty"`
+	DBSizeInUse      int64    `json:"db_size_in_use,omitempty"`
+	Leader           bool     `json:"leader"`
+	Learner          bool     `json:"learner"`
+	RaftTerm         uint64   `json:"raft_term,omitempty"`
+	RaftIndex        uint64   `json:"raft_index,omitempty"`
+	RaftAppliedIndex uint64   `json:"raft_applied_index,omitempty"`
+	Errors           []string `json:"errors,omitempty"`
+}
+
+type alarm struct {
+	MemberID string `json:"member_id"`
+	Alarm    string `json:"alarm"`
+}
+
+func endpointStatusCmd(args []string) error {
+	fs := flag.NewFlagSet("endpoint-status", flag.ExitOnError)
+	timeout := fs.Duration("timeout", 5*time.Second, "How long to wait for etcd")
+	output := fs.String("output", "table", "Output format: table or json")
+	fs.Parse(args)
+
+	cli, err := newClient(*timeout)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	// Query all endpoints concurrently, so an unreachable one doesn't eat the timeout of the others.
+	eps := cli.Endpoints()
+	statuses := make([]endpointStatus, len(eps))
+	var wg sync.WaitGroup
+	for i, ep := range eps {
+		wg.Add(1)
+		go func(i int, ep string) {
+			defer wg.Done()
+			statuses[i] = queryStatus(ctx, cli, ep)
+		}(i, ep)
+	}
+	var alarms []alarm
+	var alarmErr string
+	if resp, err := cli.AlarmList(ctx); err != nil {
+		alarmErr = err.Error()
+	} else {
+		for _, a := range resp.Alarms {
+			alarms = append(alarms, alarm{MemberID: fmt.Sprintf("%x", a.MemberID), Alarm: a.Alarm.String()})
+		}
+	}
+	wg.Wait()
+	switch *output {
+	case "json":
+		return printJSON(struct {
+			Endpoints  []endpointStatus `json:"endpoints"`
+			Alarms     []alarm          `json:"alarms"`
+			AlarmError string           `json:"alarm_error,omitempty"`
+		}{statuses, alarms, alarmErr})
+	case "table":
+		tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "ENDPOINT\tID\tVERSION\tDB SIZE\tIN USE\tLEADER\tLEARNER\tRAFT TERM\tRAFT INDEX\tAPPLIED\tERRORS")
+		for _, s := range statuses {
+			if s.Error != "" {
+				fmt.Fprintf(tw, "%s\t\t\t\t\t\t\t\t\t\t%s\n", s.Endpoint, s.Error)
+				continue
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%v\t%v\t%d\t%d\t%d\t%s\n", s.Endpoint, s.ID, s.Version, humanBytes(s.DBSize), humanBytes(s.DBSizeInUse), s.Leader, s.Learner, s.RaftTerm, s.RaftIndex, s.RaftAppliedIndex, strings.Join(s.Errors, "; "))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		if alarmErr != "" {
+			fmt.Printf("\nfailed to list alarms: %s\n", alarmErr)
+		}
+		for _, a := range alarms {
+			fmt.Printf("\nalarm: member %s raised %s\n", a.MemberID, a.Alarm)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown --output %q", *output)
+	}
+}
+
+func queryStatus(ctx context.Context, cli *clientv3.Client, ep string) endpointStatus {
+	resp, err := cli.Status(ctx, ep)
+	if err != nil {
+		return endpointStatus{Endpoint: ep, Error: err.Error()}
+	}
+	return endpointStatus{
+		Endpoint:         ep,
+		ID:               fmt.Sprintf("%x", resp.Header.MemberId),
+		Version:          resp.Version,
+		DBSize:           resp.DbSize,
+		DBSizeInUse:      resp.DbSizeInUse,
+		Leader:           resp.Leader == resp.Header.MemberId,
+		Learner:          resp.IsLearner,
+		RaftTerm:         resp.RaftTerm,
+		RaftIndex:        resp.RaftIndex,
+		RaftAppliedIndex: resp.RaftAppliedIndex,
+		Errors:           resp.Errors,
+	}
+}
+
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
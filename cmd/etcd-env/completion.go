@@ -0,0 +1,181 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	clientconfig "github.com/Jille/etcd-client-from-env"
+)
+
+// commandFlags are the flags of each command, for completion.
+var commandFlags = map[string][]string{
+	"check":           {"--quiet", "--timeout="},
+	"wait":            {"--quiet", "--timeout=", "--interval="},
+	"metrics":         {"--metrics-listen="},
+	"dry-run":         {"--strict"},
+	"probe-tls":       {"--timeout="},
+	"member-list":     {"--output=", "--timeout="},
+	"endpoint-status": {"--output=", "--timeout="},
+	"snapshot-save":   {"--endpoint=", "--timeout=", "--quiet"},
+	"compact":         {"--revision=", "--keep=", "--physical", "--yes", "--timeout="},
+	"defrag":          {"--yes", "--timeout=", "--pause="},
+	"convert":         {"--to=", "--pem-dir=", "--output="},
+	"vars":            {"--output="},
+	"completion":      nil,
+}
+
+var flagValues = map[string][]string{
+	"--output=": {"table", "json"},
+	"--to=":     {"clientv3-yaml"},
+}
+
+const bashCompletion = `_etcd_env() {
+	local line="${COMP_LINE:0:COMP_POINT}" words
+	read -ra words <<< "$line"
+	[[ $line == *' ' ]] && words+=("")
+	local IFS=$'\n'
+	COMPREPLY=($(etcd-env __complete "${words[@]}"))
+	# bash splits words on =, so only complete the part after it.
+	if [[ ${words[-1]} == *=* ]]; then
+		COMPREPLY=("${COMPREPLY[@]#*=}")
+	fi
+	if [[ ${#COMPREPLY[@]} == 1 && ${COMPREPLY[0]} == *= ]]; then
+		compopt -o nospace
+	fi
+}
+complete -F _etcd_env etcd-env
+`
+
+const zshCompletion = `#compdef etcd-env
+_etcd_env() {
+	local -a candidates
+	candidates=("${(@f)$(etcd-env __complete "${(@)words[1,CURRENT]}")}")
+	compadd -S '' -- ${(M)candidates:#*=}
+	compadd -- ${candidates:#*=}
+}
+compdef _etcd_env etcd-env
+`
+
+const fishCompletion = `complete -c etcd-env -f -a '(etcd-env __complete (commandline -opc) (commandline -ct | string collect -a))'
+`
+
+func completion(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: etcd-env completion bash|zsh|fish")
+	}
+	switch args[0] {
+	case "bash":
+		fmt.Print(bashCompletion)
+	case "zsh":
+		fmt.Print(zshCompletion)
+	case "fish":
+		fmt.Print(fishCompletion)
+	default:
+		return fmt.Errorf("unsupported shell %q", args[0])
+	}
+	return nil
+}
+
+// complete prints the candidates for the last word, given the whole command line up to the cursor (including the program name).
+func complete(words []string) {
+	for _, c := range candidates(words) {
+		fmt.Println(c)
+	}
+}
+
+func candidates(words []string) []string {
+	if len(words) < 2 {
+		return nil
+	}
+	cur := words[len(words)-1]
+	args := words[1 : len(words)-1]
+	for len(args) > 0 && isAssignment(args[0]) {
+		args = args[1:]
+	}
+	var ret []string
+	add := func(c string) {
+		if strings.HasPrefix(c, cur) {
+			ret = append(ret, c)
+		}
+	}
+	if len(args) == 0 {
+		if strings.HasPrefix(cur, "E") {
+			return variableCandidates(cur)
+		}
+		var cmds []string
+		for c := range commandFlags {
+			cmds = append(cmds, c)
+		}
+		sort.Strings(cmds)
+		for _, c := range cmds {
+			add(c)
+		}
+		return ret
+	}
+	switch args[0] {
+	case "completion":
+		for _, s := range []string{"bash", "zsh", "fish"} {
+			add(s)
+		}
+		return ret
+	case "vars":
+		if !strings.HasPrefix(cur, "-") {
+			for _, v := range clientconfig.EnvVars() {
+				add(v.Name)
+			}
+			return ret
+		}
+	}
+	if !strings.HasPrefix(cur, "-") {
+		return nil
+	}
+	if i := strings.Index(cur, "="); i != -1 {
+		for _, v := range flagValues[cur[:i+1]] {
+			add(cur[:i+1] + v)
+		}
+		return ret
+	}
+	for _, f := range commandFlags[args[0]] {
+		add(f)
+	}
+	return ret
+}
+
+// variableCandidates completes "ETCD_" to variable names and "ETCD_X=" to its values.
+func variableCandidates(cur string) []string {
+	var ret []string
+	for _, v := range clientconfig.EnvVars() {
+		if v.AliasOf != "" {
+			continue
+		}
+		if !strings.Contains(cur, "=") {
+			if strings.HasPrefix(v.Name+"=", cur) {
+				ret = append(ret, v.Name+"=")
+			}
+			continue
+		}
+		prefix := v.Name + "="
+		if !strings.HasPrefix(cur, prefix) {
+			continue
+		}
+		// Lists complete their last element.
+		value := cur[len(prefix):]
+		done := ""
+		if i := strings.LastIndex(value, ","); i != -1 {
+			done, value = value[:i+1], value[i+1:]
+		}
+		for _, val := range v.Values {
+			if strings.HasPrefix(val, value) {
+				ret = append(ret, prefix+done+val)
+			}
+		}
+	}
+	return ret
+}
+
+// isAssignment returns whether arg sets a variable, like ETCD_PROFILE=wan.
+func isAssignment(arg string) bool {
+	return strings.HasPrefix(arg, "ETCD_") && strings.Contains(arg, "=")
+}
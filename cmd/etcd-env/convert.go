@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	clientconfig "github.com/Jille/etcd-client-from-env"
+)
+
+func convert(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	to := fs.String("to", "clientv3-yaml", "Output format. Only clientv3-yaml is supported")
+	pemDir := fs.String("pem-dir", "", "Directory to write certificates and keys to that weren't read from a file")
+	output := fs.String("output", "", "File to write to (default: stdout)")
+	fs.Parse(args)
+	if *to != "clientv3-yaml" {
+		return fmt.Errorf("unknown --to %q", *to)
+	}
+
+	b, err := clientconfig.ToClientv3YAML(*pemDir)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err := os.Stdout.Write(b)
+		return err
+	}
+	// The file might contain a password.
+	return ioutil.WriteFile(*output, b, 0600)
+}
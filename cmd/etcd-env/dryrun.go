@@ -0,0 +1,24 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	clientconfig "github.com/Jille/etcd-client-from-env"
+)
+
+func dryRun(args []string) error {
+	fs := flag.NewFlagSet("dry-run", flag.ExitOnError)
+	strict := fs.Bool("strict", false, "Fail if there are any warnings")
+	fs.Parse(args)
+
+	r, err := clientconfig.DryRun()
+	fmt.Print(r)
+	if err != nil {
+		return err
+	}
+	if *strict && len(r.Warnings) > 0 {
+		return fmt.Errorf("%d warning(s)", len(r.Warnings))
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+// Command etcd-env is a small tool that uses the same environment variables as github.com/Jille/etcd-client-from-env.
+//
+// Usage:
+//
+//	etcd-env check [--quiet] [--timeout=3s]
+//	etcd-env wait [--quiet] [--timeout=2m] [--interval=1s]
+//	etcd-env metrics [--metrics-listen=:9101]
+//	etcd-env dry-run [--strict]
+//	etcd-env probe-tls [--timeout=5s]
+//	etcd-env member-list [--output=table|json] [--timeout=5s]
+//	etcd-env endpoint-status [--output=table|json] [--timeout=5s]
+//	etcd-env snapshot-save [--endpoint=host:port] [--timeout=10m] [--quiet] <file>
+//	etcd-env compact (--revision=N | --keep=N) [--physical] [--yes]
+//	etcd-env defrag [--yes] [--timeout=5m] [--pause=5s] [endpoint...]
+//	etcd-env convert --to=clientv3-yaml [--pem-dir=dir] [--output=file]
+//	etcd-env vars [--output=table|json] [name...]
+//	etcd-env completion bash|zsh|fish
+//
+// Like env(1), variables can be set before the command, like etcd-env ETCD_PROFILE=wan check.
+//
+// check connects to etcd and exits 0 if any endpoint answers. It's meant as a container HEALTHCHECK or Kubernetes exec probe; build it with CGO_ENABLED=0 to get a static binary.
+//
+// wait blocks until etcd is reachable and the credentials are accepted. It's meant as an init container to start applications only after etcd is available.
+//
+// metrics serves Prometheus metrics with the expiry time of the configured client certificate and CA(s).
+//
+// dry-run validates the configuration without connecting and prints the effective settings and warnings. With --strict, warnings are fatal.
+//
+// probe-tls reports which endpoints speak TLS and ask for a client certificate, and suggests ETCD_ENDPOINTS with matching schemes.
+//
+// member-list prints the members of the cluster. endpoint-status prints the status of every endpoint (leader, raft term, DB size) and the active alarms.
+//
+// snapshot-save streams a snapshot of the backend from one endpoint to a file, verifies etcd's integrity hash and prints its sha256.
+//
+// compact and defrag are maintenance commands. They only print what they would do unless --yes is passed. defrag does one endpoint at a time, because defragmenting blocks the member.
+//
+// convert writes the resolved configuration in the YAML format of go.etcd.io/etcd/client/v3/yaml, for tools that only accept that.
+//
+// vars lists the variables we read, with their accepted values.
+//
+// completion prints a completion script for the given shell, which completes commands, flags, variable names and their values. For bash, add `source <(etcd-env completion bash)` to your .bashrc.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	clientconfig "github.com/Jille/etcd-client-from-env"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [ETCD_X=value...] <command> [flags]\n\nCommands:\n  check            Check whether etcd is reachable\n  wait             Wait until etcd is reachable\n  metrics          Serve certificate expiry metrics\n  dry-run          Validate the configuration without connecting\n  probe-tls        Detect which endpoints use TLS\n  member-list      Print the cluster members\n  endpoint-status  Print the status of every endpoint and alarms\n  snapshot-save    Save a snapshot of the backend to a file\n  compact          Compact the key space history\n  defrag           Defragment the backend of every endpoint\n  convert          Write the configuration in another format\n  vars             List the environment variables\n  completion       Print a shell completion script\n\nVariables can be set before the command, like ETCD_PROFILE=wan check.\n", os.Args[0])
+}
+
+func main() {
+	args := os.Args[1:]
+	for len(args) > 0 && isAssignment(args[0]) {
+		kv := strings.SplitN(args[0], "=", 2)
+		os.Setenv(kv[0], kv[1])
+		args = args[1:]
+	}
+	if len(args) < 1 {
+		usage()
+		os.Exit(2)
+	}
+	var err error
+	switch args[0] {
+	case "check":
+		err = check(args[1:])
+	case "wait":
+		err = wait(args[1:])
+	case "metrics":
+		err = serveMetrics(args[1:])
+	case "dry-run":
+		err = dryRun(args[1:])
+	case "probe-tls":
+		err = probeTLS(args[1:])
+	case "member-list":
+		err = memberList(args[1:])
+	case "endpoint-status":
+		err = endpointStatusCmd(args[1:])
+	case "snapshot-save":
+		err = snapshotSave(args[1:])
+	case "compact":
+		err = compact(args[1:])
+	case "defrag":
+		err = defrag(args[1:])
+	case "convert":
+		err = convert(args[1:])
+	case "vars":
+		err = vars(args[1:])
+	case "completion":
+		err = completion(args[1:])
+	case "__complete":
+		complete(args[1:])
+		return
+	case "help", "-h", "--help":
+		usage()
+		return
+	default:
+		usage()
+		os.Exit(2)
+	}
+	clientconfig.StopDevEtcd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "etcd-env: %v\n", err)
+		os.Exit(1)
+	}
+}
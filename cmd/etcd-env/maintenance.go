@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func compact(args []string) error {
+	fs := flag.NewFlagSet("compact", flag.ExitOnError)
+	revision := fs.Int64("revision", 0, "Compact up to this revision")
+	keep := fs.Int64("keep", 0, "Compact everything but the latest N revisions")
+	physical := fs.Bool("physical", false, "Wait until the compaction has been applied to the backend of all members")
+	yes := fs.Bool("yes", false, "Actually compact; without this flag only prints what would be done")
+	timeout := fs.Duration("timeout", time.Minute, "How long to wait for etcd")
+	fs.Parse(args)
+	if (*revision > 0) == (*keep > 0) {
+		return errors.New("pass exactly one of --revision or --keep")
+	}
+
+	cli, err := newClient(*timeout)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	rev := *revision
+	if *keep > 0 {
+		// Any read returns the current revision in its header.
+		resp, err := cli.Get(ctx, "\x00", clientv3.WithCountOnly())
+		if err != nil {
+			return err
+		}
+		rev = resp.Header.Revision - *keep
+		if rev <= 0 {
+			return fmt.Errorf("current revision is %d, nothing to compact while keeping %d", resp.Header.Revision, *keep)
+		}
+	}
+	if !*yes {
+		fmt.Printf("would compact to revision %d; pass --yes to do so\n", rev)
+		return nil
+	}
+	var opts []clientv3.CompactOption
+	if *physical {
+		opts = append(opts, clientv3.WithCompactPhysical())
+	}
+	if _, err := cli.Compact(ctx, rev, opts...); err != nil {
+		return err
+	}
+	fmt.Printf("compacted to revision %d\n", rev)
+	return nil
+}
+
+func defrag(args []string) error {
+	fs := flag.NewFlagSet("defrag", flag.ExitOnError)
+	yes := fs.Bool("yes", false, "Actually defragment; without this flag only prints what would be done")
+	timeout := fs.Duration("timeout", 5*time.Minute, "How long to wait for each endpoint")
+	pause := fs.Duration("pause", 5*time.Second, "How long to wait between endpoints, to let a member catch up")
+	fs.Parse(args)
+
+	cli, err := newClient(*timeout)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+	eps := cli.Endpoints()
+	if fs.NArg() > 0 {
+		eps = fs.Args()
+	}
+	if !*yes {
+		for _, ep := range eps {
+			fmt.Printf("would defragment %s; pass --yes to do so\n", ep)
+		}
+		return nil
+	}
+	// Defragmenting blocks a member, so do one at a time.
+	for i, ep := range eps {
+		if i > 0 {
+			time.Sleep(*pause)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		before, err := cli.Status(ctx, ep)
+		if err != nil {
+			cancel()
+			return fmt.Errorf("%s: %v", ep, err)
+		}
+		if _, err := cli.Defragment(ctx, ep); err != nil {
+			cancel()
+			return fmt.Errorf("failed to defragment %s: %v", ep, err)
+		}
+		after, err := cli.Status(ctx, ep)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("%s: %v", ep, err)
+		}
+		fmt.Printf("defragmented %s: %s -> %s\n", ep, humanBytes(before.DbSize), humanBytes(after.DbSize))
+	}
+	return nil
+}
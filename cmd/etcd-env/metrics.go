@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+
+	"github.com/Jille/etcd-client-from-env/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func serveMetrics(args []string) error {
+	fs := flag.NewFlagSet("metrics", flag.ExitOnError)
+	listen := fs.String("metrics-listen", ":9101", "Address to serve /metrics on")
+	fs.Parse(args)
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(metrics.NewCertExpiryCollector())
+	http.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	return http.ListenAndServe(*listen, nil)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	clientconfig "github.com/Jille/etcd-client-from-env"
+)
+
+func probeTLS(args []string) error {
+	fs := flag.NewFlagSet("probe-tls", flag.ExitOnError)
+	timeout := fs.Duration("timeout", 5*time.Second, "How long to wait for each endpoint")
+	fs.Parse(args)
+
+	c, err := clientconfig.Get()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	c, probes := clientconfig.ProbeTLS(ctx, c)
+	for _, p := range probes {
+		switch {
+		case p.Err != nil:
+			fmt.Printf("%s: unreachable: %v\n", p.Endpoint, p.Err)
+		case !p.TLS:
+			fmt.Printf("%s: plaintext\n", p.Endpoint)
+		default:
+			cert := "no client certificate requested"
+			if p.ClientCertRequested {
+				cert = "client certificate requested"
+			}
+			trust := "server certificate trusted"
+			if p.VerifyErr != nil {
+				trust = fmt.Sprintf("server certificate not trusted: %v", p.VerifyErr)
+			}
+			fmt.Printf("%s: TLS, %s, %s\n", p.Endpoint, cert, trust)
+		}
+	}
+	fmt.Printf("suggested ETCD_ENDPOINTS=%s\n", strings.Join(c.Endpoints, ","))
+	return nil
+}
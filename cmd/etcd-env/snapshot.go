@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	clientconfig "github.com/Jille/etcd-client-from-env"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+func snapshotSave(args []string) error {
+	fs := flag.NewFlagSet("snapshot-save", flag.ExitOnError)
+	endpoint := fs.String("endpoint", "", "Endpoint to take the snapshot from (default: the first one)")
+	timeout := fs.Duration("timeout", 10*time.Minute, "How long the whole snapshot may take")
+	quiet := fs.Bool("quiet", false, "Don't print progress")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: etcd-env snapshot-save [flags] <file>")
+	}
+	path := fs.Arg(0)
+
+	c, err := clientconfig.Get()
+	if err != nil {
+		return err
+	}
+	if *endpoint != "" {
+		c.Endpoints = []string{*endpoint}
+	} else if len(c.Endpoints) > 0 {
+		c.Endpoints = c.Endpoints[:1]
+	}
+	// Don't let auto syncing move us to another member.
+	c.AutoSyncInterval = 0
+	c.Logger = zap.NewNop()
+	cli, err := clientv3.New(c)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	rc, err := cli.Snapshot(ctx)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".part")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	var written int64
+	done := make(chan struct{})
+	if !*quiet {
+		go func() {
+			t := time.NewTicker(time.Second)
+			defer t.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-t.C:
+					fmt.Fprintf(os.Stderr, "received %s\n", humanBytes(atomic.LoadInt64(&written)))
+				}
+			}
+		}()
+	}
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, h, writeCounter{&written}), rc)
+	close(done)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to receive snapshot from %s: %v", c.Endpoints[0], err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := verifySnapshot(f.Name()); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+	if !*quiet {
+		fmt.Printf("saved %s snapshot from %s to %s (sha256 %s)\n", humanBytes(written), c.Endpoints[0], path, hex.EncodeToString(h.Sum(nil)))
+	}
+	return nil
+}
+
+type writeCounter struct {
+	n *int64
+}
+
+func (w writeCounter) Write(p []byte) (int, error) {
+	atomic.AddInt64(w.n, int64(len(p)))
+	return len(p), nil
+}
+
+// verifySnapshot checks the sha256 that etcd appends to the snapshot.
+func verifySnapshot(fn string) error {
+	f, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if st.Size() < sha256.Size {
+		return errors.New("snapshot is truncated")
+	}
+	h := sha256.New()
+	if _, err := io.CopyN(h, f, st.Size()-sha256.Size); err != nil {
+		return err
+	}
+	want := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(f, want); err != nil {
+		return err
+	}
+	if !bytes.Equal(h.Sum(nil), want) {
+		return errors.New("snapshot integrity check failed: sha256 doesn't match")
+	}
+	return nil
+}
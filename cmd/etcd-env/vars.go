@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	clientconfig "github.com/Jille/etcd-client-from-env"
+)
+
+func vars(args []string) error {
+	fs := flag.NewFlagSet("vars", flag.ExitOnError)
+	output := fs.String("output", "table", "Output format: table or json")
+	fs.Parse(args)
+
+	var list []clientconfig.EnvVar
+	for _, v := range clientconfig.EnvVars() {
+		if fs.NArg() > 0 && !contains(fs.Args(), v.Name) {
+			continue
+		}
+		list = append(list, v)
+	}
+	switch *output {
+	case "json":
+		return printJSON(list)
+	case "table":
+		tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tVALUES\tDESCRIPTION")
+		for _, v := range list {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", v.Name, strings.Join(v.Values, ","), v.Description)
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown --output %q", *output)
+	}
+}
+
+func contains(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
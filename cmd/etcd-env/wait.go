@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+func wait(args []string) error {
+	fs := flag.NewFlagSet("wait", flag.ExitOnError)
+	timeout := fs.Duration("timeout", 2*time.Minute, "How long to wait for etcd before giving up")
+	interval := fs.Duration("interval", time.Second, "How long to wait between attempts")
+	quiet := fs.Bool("quiet", false, "Don't print progress")
+	fs.Parse(args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	for {
+		actx, acancel := context.WithTimeout(ctx, 5*time.Second)
+		ep, err := probe(actx, 5*time.Second)
+		acancel()
+		if err == nil {
+			if !*quiet {
+				fmt.Printf("etcd is reachable at %s\n", ep)
+			}
+			return nil
+		}
+		if !*quiet {
+			fmt.Fprintf(os.Stderr, "Waiting for etcd: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("gave up waiting for etcd after %s: %v", *timeout, err)
+		case <-time.After(*interval):
+		}
+	}
+}
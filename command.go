@@ -0,0 +1,44 @@
+package clientconfig
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// commandVariables can also be produced by a command by setting k_COMMAND, like git's credential helpers. That way secrets can come from pass, op or internal tooling without being in the environment.
+var commandVariables = map[string]bool{"ETCD_PASSWORD": true, "ETCD_USERNAME_AND_PASSWORD": true}
+
+// commandWaitDelay is how long a _COMMAND's output is waited for after it's killed.
+const commandWaitDelay = time.Second
+
+// runCommand runs command with the shell and returns its stdout without trailing newlines. Stdin and stderr are passed through, so the command can prompt for a passphrase.
+func runCommand(ctx context.Context, k, command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	}
+	var stdout bytes.Buffer
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	// Children of the shell that are still writing to stdout would otherwise keep Run from returning after ctx is done.
+	cmd.WaitDelay = commandWaitDelay
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", errorf(CodeCommandFailed, k, "%s_COMMAND was killed: %v", k, ctx.Err())
+		}
+		return "", errorf(CodeCommandFailed, k, "%s_COMMAND failed: %v", k, err)
+	}
+	v := strings.TrimRight(stdout.String(), "\r\n")
+	if v == "" {
+		return "", errorf(CodeCommandFailed, k, "%s_COMMAND didn't print anything", k)
+	}
+	return v, nil
+}
@@ -5,17 +5,14 @@
 package clientconfig
 
 import (
-	"crypto/tls"
-	"crypto/x509"
-	"errors"
-	"fmt"
-	"io/ioutil"
-	"os"
+	"context"
+	"encoding/base64"
 	"strconv"
 	"strings"
 	"time"
 
 	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc"
 )
 
 // Get is the easiest way to get a clientv3.Config if you don't have any defaults that have less priority than client configuration.
@@ -23,43 +20,196 @@ func Get() (clientv3.Config, error) {
 	return Apply(Defaults())
 }
 
+// GetContext is like Get, but gives up when ctx is done, like ApplyContext.
+func GetContext(ctx context.Context) (clientv3.Config, error) {
+	return ApplyContext(ctx, Defaults())
+}
+
 // Defaults are the defaults used by this library, but you can overwrite them.
 // After overwriting them, pass the Config to Apply to get the configuration from the environment.
 func Defaults() clientv3.Config {
-	return clientv3.Config{
+	c := clientv3.Config{
 		DialTimeout:      15 * time.Second,
 		AutoSyncInterval: 5 * time.Minute,
 	}
+	if defaultEndpoints != "" {
+		c.Endpoints = strings.Split(defaultEndpoints, ",")
+	}
+	return c
 }
 
+// secretVariables are the variables whose values must not be shown.
+var secretVariables = map[string]bool{"ETCD_PASSWORD": true, "ETCD_USERNAME_AND_PASSWORD": true, "ETCD_CLIENT_KEY": true, "ETCD_CLIENT_KEY_PASSWORD": true, "ETCD_CLIENT_CERT_AND_KEY": true, "ETCD_CONFIG_JSON": true, "ETCD_CONFIG_YAML": true, "ETCD_URL": true, "ETCD_PROXY": true, "ETCD_SSH_KEY": true, "ETCD_AUTH_TOKEN": true, "ETCD_OIDC_CLIENT_SECRET": true}
+
+// keyringVariables can also be read from the OS keyring by setting k_KEYRING to service/account, if the keyring module is imported. That's meant for developer workstations, so passwords don't end up in shell rc files.
+var keyringVariables = map[string]bool{"ETCD_PASSWORD": true, "ETCD_CLIENT_KEY_PASSWORD": true}
+
+// trimmedFileVariables lose a single trailing newline when read from k_FILE, because `echo` and most secret stores add one; see WithKeepNewlines. PEM variables don't need that.
+var trimmedFileVariables = map[string]bool{"ETCD_USERNAME": true, "ETCD_PASSWORD": true, "ETCD_USERNAME_AND_PASSWORD": true, "ETCD_AUTH_TOKEN": true, "ETCD_CLIENT_KEY_PASSWORD": true, "ETCD_OIDC_CLIENT_ID": true, "ETCD_OIDC_CLIENT_SECRET": true}
+
+// base64Variables can also be given base64 encoded by setting k_B64, for secret stores that don't preserve newlines.
+var base64Variables = map[string]bool{"ETCD_SERVER_CA": true, "ETCD_CLIENT_CERT": true, "ETCD_CLIENT_KEY": true, "ETCD_CLIENT_CERT_AND_KEY": true}
+
+// variables are all the built-in environment variables we read; see also RegisterVariable. Each of them can also be read from a file by setting k_FILE.
+var variables = []string{"ETCD_ENDPOINTS", "ETCD_USERNAME", "ETCD_PASSWORD", "ETCD_USERNAME_AND_PASSWORD", "ETCD_INSECURE_SKIP_VERIFY", "ETCD_SERVER_CA", "ETCD_CLIENT_CERT", "ETCD_CLIENT_KEY", "ETCD_GATEWAY_ENDPOINT", "ETCD_GATEWAY_SERVER_NAME", "ETCD_DIAL_OPTIONS", "ETCD_GRPC_METADATA", "ETCD_DNS_REFRESH_INTERVAL", "ETCD_PROFILE", "ETCD_AUTH_MODE", "ETCD_EXPECTED_IDENTITY", "ETCD_DEV_TLS", "ETCD_DEV_TLS_DIR", "ETCD_DEV_AUTOSTART", "ETCD_DEBUG_RPC", "ETCD_NAMESPACE", "ETCD_DISCOVERY_SRV", "ETCD_DISCOVERY_SRV_NAME", "ETCD_CONNECT_TIMEOUT", "ETCD_DIAL_TIMEOUT", "ETCD_DIAL_KEEPALIVE_TIME", "ETCD_DIAL_KEEPALIVE_TIMEOUT", "ETCD_AUTO_SYNC_INTERVAL", "ETCD_TLS_RELOAD_INTERVAL", "ETCD_SERVER_CA_APPEND_SYSTEM", "ETCD_TLS_SERVER_NAME", "ETCD_TLS_MIN_VERSION", "ETCD_TLS_MAX_VERSION", "ETCD_TLS_CIPHER_SUITES", "ETCD_CLIENT_KEY_PASSWORD", "ETCD_CLIENT_CERT_AND_KEY", "ETCD_CREDENTIALS_DIR", "ETCD_PROXY", "ETCD_SSH_JUMP_HOST", "ETCD_SSH_USER", "ETCD_SSH_KEY", "ETCD_SSH_KNOWN_HOSTS", "ETCD_MAX_CALL_SEND_MSG_SIZE", "ETCD_MAX_CALL_RECV_MSG_SIZE", "ETCD_REJECT_OLD_CLUSTER", "ETCD_PERMIT_WITHOUT_STREAM", "ETCD_BACKOFF_WAIT_BETWEEN", "ETCD_BACKOFF_JITTER_FRACTION", "ETCD_MAX_UNARY_RETRIES", "ETCD_LOG_LEVEL", "ETCD_LOG_FORMAT", "ETCD_USER_AGENT", "ETCD_AUTH_TOKEN", "ETCD_OIDC_ISSUER", "ETCD_OIDC_CLIENT_ID", "ETCD_OIDC_CLIENT_SECRET", "ETCD_OIDC_SCOPES", "ETCD_REQUIRE", "ETCD_ENDPOINT_SHUFFLE", "ETCD_ENDPOINT_PREFER", "ETCD_LB_POLICY", "ETCD_VIA", "ETCD_DISABLE_AUTO_SYNC", "ETCD_ENDPOINT_HEALTH_CHECK", "ETCD_REQUEST_TIMEOUT", "ETCD_CALL_OPTIONS"}
+
 // Apply reads the environment variables and returns a modified copy of the given config.
 func Apply(c clientv3.Config) (clientv3.Config, error) {
-	settings := map[string]string{}
-	for _, k := range []string{"ETCD_ENDPOINTS", "ETCD_USERNAME", "ETCD_PASSWORD", "ETCD_USERNAME_AND_PASSWORD", "ETCD_INSECURE_SKIP_VERIFY", "ETCD_SERVER_CA", "ETCD_CLIENT_CERT", "ETCD_CLIENT_KEY"} {
-		ev := os.Getenv(k)
-		fn := os.Getenv(k + "_FILE")
-		if ev != "" && fn != "" {
-			return c, fmt.Errorf("conflicting value for %s: both %s and %s_FILE are set", k, k, k)
-		} else if ev != "" {
-			settings[k] = ev
-		} else if fn != "" {
-			b, err := ioutil.ReadFile(fn)
+	return ApplyWithOptions(c)
+}
+
+// ApplyWithOptions is like Apply, but with Options to customize the behavior.
+func ApplyWithOptions(c clientv3.Config, opts ...Option) (clientv3.Config, error) {
+	return ApplyContext(context.Background(), c, opts...)
+}
+
+// ApplyContext is like ApplyWithOptions, but gives up reading files, running _COMMANDs, resolving _SOURCEs and config sources, looking up ETCD_DISCOVERY_SRV and probing for ETCD_ENDPOINT_HEALTH_CHECK when ctx is done.
+// Note that ctx only bounds reading the configuration. Use WithContext to bind the lifetime of the client.
+func ApplyContext(ctx context.Context, c clientv3.Config, opts ...Option) (clientv3.Config, error) {
+	settings, err := ReadSettingsContext(ctx, opts...)
+	if err != nil {
+		return c, err
+	}
+	return applySettingsContext(ctx, c, settings, opts)
+}
+
+// Settings are the raw values of the variables we know about, keyed by variable name (without _FILE). Values read from files contain the file's contents.
+type Settings map[string]string
+
+// ReadSettings reads the environment variables (and the files they point at) without interpreting them. Variables that aren't set are taken from the sources in ETCD_CONFIG_SOURCES.
+func ReadSettings(opts ...Option) (Settings, error) {
+	return ReadSettingsContext(context.Background(), opts...)
+}
+
+// ReadSettingsContext is like ReadSettings, but gives up when ctx is done.
+func ReadSettingsContext(ctx context.Context, opts ...Option) (Settings, error) {
+	o := newOptions(opts)
+	s, err := readSettings(ctx, o)
+	if err != nil {
+		// Successes are reported by ApplySettings, once the configuration is complete.
+		o.configLoaded(err)
+	}
+	return s, err
+}
+
+func readSettings(ctx context.Context, o *options) (Settings, error) {
+	if v := o.env.Getenv("ETCD_EXPAND_VARIABLES"); v != "" {
+		if _, err := strconv.ParseBool(v); err != nil {
+			return nil, errorf(CodeInvalidValue, "ETCD_EXPAND_VARIABLES", "failed to parse ETCD_EXPAND_VARIABLES as bool (%q)", v)
+		}
+	}
+	if strict, err := strictMode(ctx, o); err != nil {
+		return nil, err
+	} else if strict {
+		if err := checkUnknownVariables(o); err != nil {
+			return nil, err
+		}
+	}
+	settings := Settings{}
+	for _, k := range allVariables() {
+		v, err := readVariable(ctx, o, k)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range aliasesFor(k) {
+			av, err := readVariable(ctx, o, a)
 			if err != nil {
-				return c, fmt.Errorf("error reading %q (for %s_FILE): %v", fn, k, err)
+				return nil, err
+			}
+			if av == "" {
+				continue
 			}
-			settings[k] = string(b)
+			if v != "" && av != v {
+				return nil, errorf(CodeConflictingVariables, k, "conflicting value for %s: both %s and its deprecated alias %s are set", k, k, a)
+			}
+			o.warnf("%s is deprecated, use %s instead", a, k)
+			v = av
+		}
+		if v != "" {
+			settings[k] = v
 		}
 	}
-	if v := settings["ETCD_ENDPOINTS"]; v != "" {
-		c.Endpoints = strings.Split(v, ",")
+	if err := readEtcdURL(ctx, o, settings); err != nil {
+		return nil, err
+	}
+	if err := readConfigBlob(ctx, o, settings); err != nil {
+		return nil, err
+	}
+	if err := readConfigFile(ctx, o, settings); err != nil {
+		return nil, err
 	}
+	if err := readEnvFile(ctx, o, settings); err != nil {
+		return nil, err
+	}
+	if o.etcdctlCompat {
+		if err := readEtcdctlVariables(o, settings); err != nil {
+			return nil, err
+		}
+	}
+	if err := loadConfigSources(ctx, o, settings); err != nil {
+		return nil, err
+	}
+	applyInClusterEndpoint(o, settings)
+	return settings, nil
+}
+
+// readVariable returns the value of k, the contents of the file named by k_FILE, (for base64Variables) the decoded k_B64 or (for commandVariables) the output of k_COMMAND, (for keyringVariables) the password in the OS keyring named by k_KEYRING or the value resolved from the URI in k_SOURCE. It gives up waiting for the file when ctx is done, which matters for files on network filesystems.
+func readVariable(ctx context.Context, o *options, k string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	ev := o.env.Getenv(k)
+	fn := o.env.Getenv(k + "_FILE")
+	for suffix, ok := range map[string]bool{"_B64": base64Variables[k], "_COMMAND": commandVariables[k], "_SOURCE": resolvable(k), "_KEYRING": keyringVariables[k]} {
+		if ok && o.env.Getenv(k+suffix) != "" && (ev != "" || fn != "") {
+			return "", errorf(CodeConflictingVariables, k, "conflicting value for %s: %s%s can't be combined with %s or %s_FILE", k, k, suffix, k, k)
+		}
+	}
+	if ev != "" && fn != "" {
+		return "", errorf(CodeConflictingFile, k, "conflicting value for %s: both %s and %s_FILE are set", k, k, k)
+	} else if fn != "" {
+		b, err := readFileContext(ctx, o.env, fn)
+		if err != nil {
+			return "", errorf(CodeUnreadableFile, k, "error reading %q (for %s_FILE): %v", fn, k, err)
+		}
+		return trimFileValue(o, k, string(b)), nil
+	}
+	if base64Variables[k] {
+		if bv := o.env.Getenv(k + "_B64"); bv != "" {
+			b, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(bv), ""))
+			if err != nil {
+				return "", errorf(CodeInvalidValue, k, "failed to base64 decode %s_B64: %v", k, err)
+			}
+			return string(b), nil
+		}
+	}
+	if commandVariables[k] {
+		if cmd := o.env.Getenv(k + "_COMMAND"); cmd != "" {
+			return runCommand(ctx, k, cmd)
+		}
+	}
+	if keyringVariables[k] {
+		if kr := o.env.Getenv(k + "_KEYRING"); kr != "" {
+			return resolveSource(ctx, o, k, k+"_KEYRING", "keyring://"+kr)
+		}
+	}
+	if resolvable(k) {
+		if src := o.env.Getenv(k + "_SOURCE"); src != "" {
+			return resolveSource(ctx, o, k, k+"_SOURCE", src)
+		}
+	}
+	return ev, nil
+}
+
+// applyCredentials sets the username and password of c from ETCD_USERNAME and ETCD_PASSWORD or ETCD_USERNAME_AND_PASSWORD.
+func applyCredentials(c *clientv3.Config, settings Settings) error {
 	if v := settings["ETCD_USERNAME_AND_PASSWORD"]; v != "" {
 		if settings["ETCD_USERNAME"] != "" || settings["ETCD_PASSWORD"] != "" {
-			return c, errors.New("you can't set both ETCD_USERNAME_AND_PASSWORD and ETCD_USERNAME or ETCD_PASSWORD")
+			return errorf(CodeConflictingVariables, "ETCD_USERNAME_AND_PASSWORD", "you can't set both ETCD_USERNAME_AND_PASSWORD and ETCD_USERNAME or ETCD_PASSWORD")
 		}
 		sp := strings.SplitN(v, ":", 2)
 		if len(sp) != 2 {
-			return c, errors.New("invalid ETCD_USERNAME_AND_PASSWORD: user and password should be separated with a colon (:)")
+			return errorf(CodeInvalidValue, "ETCD_USERNAME_AND_PASSWORD", "invalid ETCD_USERNAME_AND_PASSWORD: user and password should be separated with a colon (:)")
 		}
 		settings["ETCD_USERNAME"] = sp[0]
 		settings["ETCD_PASSWORD"] = sp[1]
@@ -70,38 +220,400 @@ func Apply(c clientv3.Config) (clientv3.Config, error) {
 	if v := settings["ETCD_PASSWORD"]; v != "" {
 		c.Password = v
 	}
-	if v := settings["ETCD_INSECURE_SKIP_VERIFY"]; v != "" {
+	return nil
+}
+
+// trimFileValue removes the trailing newline from the contents of k_FILE if k is one of the trimmedFileVariables, unless WithKeepNewlines is used.
+func trimFileValue(o *options, k, v string) string {
+	if trimmedFileVariables[k] && !o.keepNewlines {
+		return trimNewline(v)
+	}
+	return v
+}
+
+// readFileContext reads a file, but returns early if ctx is done. The read itself can't be interrupted and finishes in the background.
+func readFileContext(ctx context.Context, env Environment, fn string) ([]byte, error) {
+	if ctx.Done() == nil {
+		return env.ReadFile(fn)
+	}
+	type result struct {
+		b   []byte
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		b, err := env.ReadFile(fn)
+		ch <- result{b, err}
+	}()
+	select {
+	case r := <-ch:
+		return r.b, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// ApplySettings interprets settings (from ReadSettings or elsewhere) and returns a modified copy of the given config.
+func ApplySettings(c clientv3.Config, s Settings, opts ...Option) (clientv3.Config, error) {
+	return applySettingsContext(context.Background(), c, s, opts)
+}
+
+// applySettingsContext is ApplySettings, but gives up the SRV lookups of ETCD_DISCOVERY_SRV and the probes of ETCD_ENDPOINT_HEALTH_CHECK when ctx is done.
+func applySettingsContext(ctx context.Context, c clientv3.Config, s Settings, opts []Option) (clientv3.Config, error) {
+	o := newOptions(opts)
+	c, err := applySettings(ctx, o, c, s)
+	o.configLoaded(err)
+	return c, err
+}
+
+func applySettings(ctx context.Context, o *options, c clientv3.Config, s Settings) (clientv3.Config, error) {
+	var ic interceptors
+	var dial dialFunc
+	inputEndpoints := c.Endpoints
+	settings := Settings{}
+	for k, v := range s {
+		settings[k] = v
+	}
+	if v := settings["ETCD_PROFILE"]; v != "" {
+		if err := applyProfiles(&c, v); err != nil {
+			return c, err
+		}
+	}
+	if o.logger != nil {
+		c.Logger = o.logger
+	}
+	if err := applyLogConfig(o, &c, settings); err != nil {
+		return c, err
+	}
+	if v := settings["ETCD_ENDPOINTS"]; v != "" {
+		eps, err := parseEndpoints("ETCD_ENDPOINTS", v)
+		if err != nil {
+			return c, err
+		}
+		c.Endpoints = eps
+	}
+	if v := settings["ETCD_GATEWAY_ENDPOINT"]; v != "" {
+		if settings["ETCD_ENDPOINTS"] != "" {
+			return c, errorf(CodeConflictingVariables, "ETCD_GATEWAY_ENDPOINT", "you can't set both ETCD_GATEWAY_ENDPOINT and ETCD_ENDPOINTS")
+		}
+		v = strings.TrimSpace(v)
+		if err := validateEndpoint(v); err != nil {
+			return c, errorf(CodeInvalidValue, "ETCD_GATEWAY_ENDPOINT", "invalid ETCD_GATEWAY_ENDPOINT %q: %v", v, err)
+		}
+		c.Endpoints = []string{v}
+		// Auto syncing would replace the gateway with the members behind it.
+		c.AutoSyncInterval = 0
+	}
+	if v := settings["ETCD_DISCOVERY_SRV"]; v != "" {
+		if settings["ETCD_ENDPOINTS"] != "" || settings["ETCD_GATEWAY_ENDPOINT"] != "" {
+			return c, errorf(CodeConflictingVariables, "ETCD_DISCOVERY_SRV", "you can't set both ETCD_DISCOVERY_SRV and ETCD_ENDPOINTS or ETCD_GATEWAY_ENDPOINT")
+		}
+		if o.fingerprinting {
+			// Changed uses the result of the last lookup rather than doing one.
+			c.Endpoints = lastDiscoveredEndpoints(v, settings["ETCD_DISCOVERY_SRV_NAME"])
+		} else {
+			eps, err := discoverEndpoints(ctx, v, settings["ETCD_DISCOVERY_SRV_NAME"])
+			if err != nil {
+				return c, err
+			}
+			c.Endpoints = eps
+		}
+	} else if settings["ETCD_DISCOVERY_SRV_NAME"] != "" {
+		return c, errorf(CodeConflictingVariables, "ETCD_DISCOVERY_SRV_NAME", "ETCD_DISCOVERY_SRV_NAME can only be used together with ETCD_DISCOVERY_SRV")
+	}
+	if err := applyEndpointOrder(o, &c, settings); err != nil {
+		return c, err
+	}
+	viaProxy, err := applyVia(&c, settings)
+	if err != nil {
+		return c, err
+	}
+	if err := applyCredentials(&c, settings); err != nil {
+		return c, err
+	}
+	reloadInterval, appendSystem, err := applyTLS(o, &c, settings)
+	if err != nil {
+		return c, err
+	}
+	if err := checkAuthMode(c, settings["ETCD_AUTH_MODE"], settings["ETCD_EXPECTED_IDENTITY"]); err != nil {
+		return c, err
+	}
+	if v := settings["ETCD_LB_POLICY"]; v != "" {
+		if err := applyLBPolicy(&c, v); err != nil {
+			return c, err
+		}
+	}
+	if v := settings["ETCD_CALL_OPTIONS"]; v != "" {
+		if err := applyCallOptions(&c, v); err != nil {
+			return c, err
+		}
+	}
+	if v := settings["ETCD_DIAL_OPTIONS"]; v != "" {
+		opts, err := namedDialOptions(v)
+		if err != nil {
+			return c, err
+		}
+		appendDialOptions(&c, opts...)
+	}
+	if v := settings["ETCD_GRPC_METADATA"]; v != "" {
+		kv, err := parseMetadata(v)
+		if err != nil {
+			return c, err
+		}
+		ic.addMetadata(kv)
+	}
+	for _, d := range []struct {
+		name      string
+		dst       *time.Duration
+		allowZero bool
+	}{
+		{"ETCD_DIAL_TIMEOUT", &c.DialTimeout, false},
+		{"ETCD_DIAL_KEEPALIVE_TIME", &c.DialKeepAliveTime, false},
+		{"ETCD_DIAL_KEEPALIVE_TIMEOUT", &c.DialKeepAliveTimeout, false},
+		{"ETCD_AUTO_SYNC_INTERVAL", &c.AutoSyncInterval, true},
+		{"ETCD_BACKOFF_WAIT_BETWEEN", &c.BackoffWaitBetween, false},
+	} {
+		v := settings[d.name]
+		if v == "" {
+			continue
+		}
+		dur, err := time.ParseDuration(v)
+		if err != nil || dur < 0 || (dur == 0 && !d.allowZero) {
+			return c, errorf(CodeInvalidValue, d.name, "failed to parse %s as a positive duration (%q)", d.name, v)
+		}
+		*d.dst = dur
+	}
+	if v := settings["ETCD_BACKOFF_JITTER_FRACTION"]; v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f <= 0 || f > 1 {
+			return c, errorf(CodeInvalidValue, "ETCD_BACKOFF_JITTER_FRACTION", "failed to parse ETCD_BACKOFF_JITTER_FRACTION as a fraction between 0 and 1 (%q)", v)
+		}
+		c.BackoffJitterFraction = f
+	}
+	if v := settings["ETCD_MAX_UNARY_RETRIES"]; v != "" {
+		n, err := strconv.ParseUint(v, 10, 0)
+		if err != nil || n == 0 {
+			return c, errorf(CodeInvalidValue, "ETCD_MAX_UNARY_RETRIES", "failed to parse ETCD_MAX_UNARY_RETRIES as a positive number (%q)", v)
+		}
+		c.MaxUnaryRetries = uint(n)
+	}
+	for _, b := range []struct {
+		name string
+		dst  *bool
+	}{
+		{"ETCD_REJECT_OLD_CLUSTER", &c.RejectOldCluster},
+		{"ETCD_PERMIT_WITHOUT_STREAM", &c.PermitWithoutStream},
+	} {
+		if v := settings[b.name]; v != "" {
+			pb, err := strconv.ParseBool(v)
+			if err != nil {
+				return c, errorf(CodeInvalidValue, b.name, "failed to parse %s as bool (%q)", b.name, v)
+			}
+			*b.dst = pb
+		}
+	}
+	for _, m := range []struct {
+		name string
+		dst  *int
+	}{
+		{"ETCD_MAX_CALL_SEND_MSG_SIZE", &c.MaxCallSendMsgSize},
+		{"ETCD_MAX_CALL_RECV_MSG_SIZE", &c.MaxCallRecvMsgSize},
+	} {
+		if v := settings[m.name]; v != "" {
+			n, err := parseSize(m.name, v)
+			if err != nil {
+				return c, err
+			}
+			*m.dst = n
+		}
+	}
+	if v := settings["ETCD_DISABLE_AUTO_SYNC"]; v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return c, errorf(CodeInvalidValue, "ETCD_DISABLE_AUTO_SYNC", "failed to parse ETCD_DISABLE_AUTO_SYNC as bool (%q)", v)
+		}
+		if b {
+			if settings["ETCD_AUTO_SYNC_INTERVAL"] != "" && c.AutoSyncInterval != 0 {
+				return c, errorf(CodeConflictingVariables, "ETCD_DISABLE_AUTO_SYNC", "you can't set both ETCD_DISABLE_AUTO_SYNC and ETCD_AUTO_SYNC_INTERVAL")
+			}
+			c.AutoSyncInterval = 0
+		}
+	}
+	if viaProxy && c.AutoSyncInterval != 0 {
+		return c, errorf(CodeConflictingVariables, "ETCD_AUTO_SYNC_INTERVAL", "ETCD_AUTO_SYNC_INTERVAL can't be used together with ETCD_GATEWAY_ENDPOINT or ETCD_VIA, because syncing would bypass the proxy")
+	}
+	if v := settings["ETCD_DEBUG_RPC"]; v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return c, errorf(CodeInvalidValue, "ETCD_DEBUG_RPC", "failed to parse ETCD_DEBUG_RPC as bool (%q)", v)
+		}
+		if b {
+			ic.addDebugLogging(newRPCLogger(c.Logger))
+		}
+	}
+	if v := settings["ETCD_PROXY"]; v != "" {
+		d, err := newProxyDialer(o, v)
+		if err != nil {
+			return c, err
+		}
+		dial = d
+	}
+	if settings["ETCD_SSH_JUMP_HOST"] != "" {
+		d, err := newSSHDialer(o, settings, dial)
+		if err != nil {
+			return c, err
+		}
+		dial = d
+	} else {
+		for _, k := range sshVariables {
+			if settings[k] != "" {
+				return c, errorf(CodeConflictingVariables, k, "%s can only be used together with ETCD_SSH_JUMP_HOST", k)
+			}
+		}
+	}
+	if v := settings["ETCD_DNS_REFRESH_INTERVAL"]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return c, errorf(CodeInvalidValue, "ETCD_DNS_REFRESH_INTERVAL", "failed to parse ETCD_DNS_REFRESH_INTERVAL as a positive duration (%q)", v)
+		}
+		if dial == nil {
+			dial = baseDial
+		}
+		dial = newDNSRefresher(d, dial).dial
+	}
+	if v := settings["ETCD_DEV_AUTOSTART"]; v != "" {
 		b, err := strconv.ParseBool(v)
 		if err != nil {
-			return c, fmt.Errorf("failed to parse ETCD_INSECURE_SKIP_VERIFY as bool (%q)", v)
+			return c, errorf(CodeInvalidValue, "ETCD_DEV_AUTOSTART", "failed to parse ETCD_DEV_AUTOSTART as bool (%q)", v)
 		}
-		if c.TLS == nil {
-			c.TLS = new(tls.Config)
+		if b {
+			if err := applyDevAutostart(o, &c, settings); err != nil {
+				return c, err
+			}
 		}
-		c.TLS.InsecureSkipVerify = b
 	}
-	if v := settings["ETCD_SERVER_CA"]; v != "" {
-		pool := x509.NewCertPool()
-		if !pool.AppendCertsFromPEM([]byte(v)) {
-			return c, errors.New("certificate(s) in ETCD_SERVER_CA(_FILE) were invalid PEM certificates")
+	if _, err := connectTimeout(settings); err != nil {
+		return c, err
+	}
+	if _, err := requestTimeout(settings); err != nil {
+		return c, err
+	}
+	appliedGroups := map[*variableGroup]bool{}
+	for _, cv := range registeredVariables() {
+		if g := cv.group; g != nil {
+			if appliedGroups[g] {
+				continue
+			}
+			appliedGroups[g] = true
+			values := map[string]string{}
+			for _, k := range g.names {
+				if v := settings[k]; v != "" {
+					values[k] = v
+				}
+			}
+			if len(values) > 0 {
+				if err := g.apply(&c, values); err != nil {
+					return c, errorf(CodeRegisteredFailure, g.names[0], "failed to apply %s: %v", strings.Join(g.names, "/"), err)
+				}
+			}
+			continue
 		}
-		if c.TLS == nil {
-			c.TLS = new(tls.Config)
+		if v := settings[cv.name]; v != "" {
+			if err := cv.apply(&c, v); err != nil {
+				return c, errorf(CodeRegisteredFailure, cv.name, "failed to apply %s: %v", cv.name, err)
+			}
+		}
+	}
+	if fn := o.env.Getenv("ETCD_SERVER_CA_FILE"); fn != "" && c.TLS != nil && c.TLS.RootCAs != nil && !c.TLS.InsecureSkipVerify {
+		// Our credentials override the ones clientv3 picks per endpoint, so only use them if all endpoints use TLS.
+		if plain, _ := splitSchemes(c); len(plain) == 0 {
+			r := &rootCAReloader{o: o, files: newFileReloader(o.env, []string{fn}, [][]byte{[]byte(settings["ETCD_SERVER_CA"])}, reloadInterval), appendSystem: appendSystem, pool: c.TLS.RootCAs}
+			appendDialOptions(&c, grpc.WithTransportCredentials(&reloadingCredentials{base: c.TLS, roots: r.rootCAs}))
 		}
-		c.TLS.RootCAs = pool
 	}
-	vc, vk := settings["ETCD_CLIENT_CERT"], settings["ETCD_CLIENT_KEY"]
-	if vc != "" && vk != "" {
-		crt, err := tls.X509KeyPair([]byte(vc), []byte(vk))
+	if err := applyEndpointHealthCheck(ctx, o, &c, settings, dial); err != nil {
+		return c, err
+	}
+	if o.hasDialHook() {
+		if dial == nil {
+			dial = baseDial
+		}
+		dial = o.dialHook(dial)
+	}
+	if dial != nil {
+		appendDialOptions(&c, grpc.WithContextDialer(dial))
+	}
+	if len(o.hooks) > 0 {
+		ic.addAuthFailureHooks(o)
+	}
+	if v := settings["ETCD_USER_AGENT"]; v != "" {
+		appendDialOptions(&c, grpc.WithUserAgent(v))
+	}
+	tokenVar := ""
+	if v := settings["ETCD_AUTH_TOKEN"]; v != "" {
+		appendDialOptions(&c, grpc.WithPerRPCCredentials(newTokenCredentials(o, v, reloadInterval)))
+		tokenVar = "ETCD_AUTH_TOKEN"
+	}
+	if settings["ETCD_OIDC_ISSUER"] != "" {
+		if tokenVar != "" {
+			return c, errorf(CodeConflictingVariables, "ETCD_OIDC_ISSUER", "you can't set both ETCD_OIDC_ISSUER and ETCD_AUTH_TOKEN")
+		}
+		ts, err := newOIDCTokenSource(settings)
 		if err != nil {
-			return c, fmt.Errorf("failed to parse ETCD_CLIENT_CERT+ETCD_CLIENT_KEY: %v", err)
+			return c, err
 		}
-		if c.TLS == nil {
-			c.TLS = new(tls.Config)
+		appendDialOptions(&c, grpc.WithPerRPCCredentials(tokenSourceCredentials{ts}))
+		tokenVar = "ETCD_OIDC_ISSUER"
+	} else {
+		for _, k := range []string{"ETCD_OIDC_CLIENT_ID", "ETCD_OIDC_CLIENT_SECRET", "ETCD_OIDC_SCOPES"} {
+			if settings[k] != "" {
+				return c, errorf(CodeConflictingVariables, k, "%s can only be used together with ETCD_OIDC_ISSUER", k)
+			}
+		}
+	}
+	if o.tokenSource != nil {
+		if tokenVar != "" {
+			return c, errorf(CodeConflictingVariables, tokenVar, "you can't use both %s and WithTokenSource", tokenVar)
+		}
+		appendDialOptions(&c, grpc.WithPerRPCCredentials(tokenSourceCredentials{o.tokenSource}))
+		tokenVar = "WithTokenSource"
+	}
+	appendDialOptions(&c, ic.dialOptions(o)...)
+	appendDialOptions(&c, o.dialOptions...)
+	if o.ctx != nil {
+		c.Context = o.ctx
+	}
+	required, err := parseRequire(o, settings)
+	if err != nil {
+		return c, err
+	}
+	if required["endpoints"] {
+		if err := checkEndpointsConfigured(c, settings, inputEndpoints); err != nil {
+			return c, err
+		}
+	}
+	inferSchemes(&c)
+	if err := checkRequireTLS(c); err != nil {
+		return c, err
+	}
+	if required["tls"] {
+		if err := checkTLSUsed(c, "ETCD_REQUIRE (or RequireTLS or ETCD_PROFILE=production)"); err != nil {
+			return c, err
+		}
+	}
+	if required["auth"] {
+		if err := checkAuthConfigured(c, tokenVar); err != nil {
+			return c, err
+		}
+	}
+	if err := o.violation(checkSchemes(c)); err != nil {
+		return c, err
+	}
+	if tokenVar != "" {
+		if err := o.violation(checkTokenTransport(c, tokenVar)); err != nil {
+			return c, err
 		}
-		c.TLS.Certificates = []tls.Certificate{crt}
-	} else if vc != "" || vk != "" {
-		return c, errors.New("either both of ETCD_CLIENT_CERT(_FILE) and ETCD_CLIENT_KEY(_FILE) must be given or neither")
 	}
+	warnSuspicious(o, c, settings)
 	return c, nil
 }
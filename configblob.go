@@ -0,0 +1,108 @@
+package clientconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// readConfigBlob fills the variables missing from settings from ETCD_CONFIG_JSON or ETCD_CONFIG_YAML, for orchestration systems that can only pass one value.
+// The document is an object whose keys are variable names, with or without ETCD_ and in any case: {"endpoints": ["https://a:2379"], "server_ca_file": "/etc/etcd/ca.crt", "dial_timeout": "5s"}. Keys ending in _file name a file to read the value from, and lists are joined with commas.
+func readConfigBlob(ctx context.Context, o *options, settings Settings) error {
+	js, err := readVariable(ctx, o, "ETCD_CONFIG_JSON")
+	if err != nil {
+		return err
+	}
+	ys, err := readVariable(ctx, o, "ETCD_CONFIG_YAML")
+	if err != nil {
+		return err
+	}
+	k, doc := "ETCD_CONFIG_JSON", []byte(js)
+	switch {
+	case js != "" && ys != "":
+		return errorf(CodeConflictingVariables, "ETCD_CONFIG_JSON", "you can't set both ETCD_CONFIG_JSON and ETCD_CONFIG_YAML")
+	case ys != "":
+		k = "ETCD_CONFIG_YAML"
+		doc, err = yaml.YAMLToJSON([]byte(ys))
+		if err != nil {
+			return errorf(CodeInvalidValue, k, "failed to parse ETCD_CONFIG_YAML: %v", err)
+		}
+	case js == "":
+		return nil
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(doc, &m); err != nil {
+		return errorf(CodeInvalidValue, k, "failed to parse %s: %v", k, err)
+	}
+	return applyBlob(ctx, o, k, m, settings)
+}
+
+// applyBlob fills the variables missing from settings from m, which came from variable k. See readConfigBlob for the format.
+func applyBlob(ctx context.Context, o *options, k string, m map[string]interface{}, settings Settings) error {
+	known := map[string]bool{}
+	for _, v := range allVariables() {
+		known[v] = true
+	}
+	blob := Settings{}
+	for name, raw := range m {
+		v := strings.ToUpper(name)
+		if !strings.HasPrefix(v, "ETCD_") {
+			v = "ETCD_" + v
+		}
+		file := strings.HasSuffix(v, "_FILE")
+		v = strings.TrimSuffix(v, "_FILE")
+		if !known[v] {
+			return errorf(CodeInvalidValue, k, "unknown key %q in %s", name, k)
+		}
+		if _, dup := blob[v]; dup {
+			return errorf(CodeConflictingFile, v, "conflicting value for %s in %s: set both directly and with _file", v, k)
+		}
+		val, err := blobValue(raw)
+		if err != nil {
+			return errorf(CodeInvalidValue, k, "invalid value for %q in %s: %v", name, k, err)
+		}
+		if file {
+			b, err := readFileContext(ctx, o.env, val)
+			if err != nil {
+				return errorf(CodeUnreadableFile, v, "error reading %q (for %q in %s): %v", val, name, k, err)
+			}
+			val = string(b)
+		}
+		blob[v] = val
+	}
+	for v, val := range blob {
+		if settings[v] == "" && val != "" {
+			settings[v] = val
+		}
+	}
+	return nil
+}
+
+// blobValue converts a JSON value to the string a variable would contain.
+func blobValue(raw interface{}) (string, error) {
+	switch v := raw.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case nil:
+		return "", nil
+	case []interface{}:
+		var parts []string
+		for _, e := range v {
+			s, err := blobValue(e)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, s)
+		}
+		return strings.Join(parts, ","), nil
+	}
+	return "", fmt.Errorf("unsupported type %T", raw)
+}
@@ -0,0 +1,91 @@
+package clientconfig
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// LoadFile reads a configuration file in the YAML format of go.etcd.io/etcd/client/v3/yaml (the one ToClientv3YAML writes) into Settings, for use with ApplySettings. The certificate and key files it names are read; relative paths are relative to the file.
+// Settings the variables can't express are ignored.
+func LoadFile(path string) (Settings, error) {
+	return loadConfigFile(context.Background(), osEnvironment{}, "ETCD_CONFIG_FILE", path)
+}
+
+func loadConfigFile(ctx context.Context, env Environment, k, path string) (Settings, error) {
+	b, err := readFileContext(ctx, env, path)
+	if err != nil {
+		return nil, errorf(CodeUnreadableFile, k, "error reading %q (for %s): %v", path, k, err)
+	}
+	var y clientv3YAML
+	if err := yaml.Unmarshal(b, &y); err != nil {
+		return nil, errorf(CodeInvalidValue, k, "failed to parse %q: %v", path, err)
+	}
+	s := Settings{}
+	if len(y.Endpoints) > 0 {
+		s["ETCD_ENDPOINTS"] = strings.Join(y.Endpoints, ",")
+	}
+	s["ETCD_USERNAME"] = y.Username
+	s["ETCD_PASSWORD"] = y.Password
+	if y.InsecureSkipTLSVerify {
+		s["ETCD_INSECURE_SKIP_VERIFY"] = strconv.FormatBool(true)
+	}
+	if y.BackoffJitterFraction != 0 {
+		s["ETCD_BACKOFF_JITTER_FRACTION"] = strconv.FormatFloat(y.BackoffJitterFraction, 'g', -1, 64)
+	}
+	if y.MaxUnaryRetries != 0 {
+		s["ETCD_MAX_UNARY_RETRIES"] = strconv.FormatUint(uint64(y.MaxUnaryRetries), 10)
+	}
+	if y.RejectOldCluster {
+		s["ETCD_REJECT_OLD_CLUSTER"] = strconv.FormatBool(true)
+	}
+	if y.PermitWithoutStream {
+		s["ETCD_PERMIT_WITHOUT_STREAM"] = strconv.FormatBool(true)
+	}
+	for v, d := range map[string]time.Duration{"ETCD_AUTO_SYNC_INTERVAL": y.AutoSyncInterval, "ETCD_DIAL_TIMEOUT": y.DialTimeout, "ETCD_DIAL_KEEPALIVE_TIME": y.DialKeepAliveTime, "ETCD_DIAL_KEEPALIVE_TIMEOUT": y.DialKeepAliveTimeout, "ETCD_BACKOFF_WAIT_BETWEEN": y.BackoffWaitBetween} {
+		if d != 0 {
+			s[v] = d.String()
+		}
+	}
+	for v, fn := range map[string]string{"ETCD_SERVER_CA": y.TrustedCAfile, "ETCD_CLIENT_CERT": y.Certfile, "ETCD_CLIENT_KEY": y.Keyfile} {
+		if fn == "" {
+			continue
+		}
+		if !filepath.IsAbs(fn) {
+			fn = filepath.Join(filepath.Dir(path), fn)
+		}
+		b, err := readFileContext(ctx, env, fn)
+		if err != nil {
+			return nil, errorf(CodeUnreadableFile, v, "error reading %q (named in %q): %v", fn, path, err)
+		}
+		s[v] = string(b)
+	}
+	for v, val := range s {
+		if val == "" {
+			delete(s, v)
+		}
+	}
+	return s, nil
+}
+
+// readConfigFile fills the variables missing from settings from the file named by ETCD_CONFIG_FILE.
+func readConfigFile(ctx context.Context, o *options, settings Settings) error {
+	path := o.env.Getenv("ETCD_CONFIG_FILE")
+	if path == "" {
+		return nil
+	}
+	s, err := loadConfigFile(ctx, o.env, "ETCD_CONFIG_FILE", path)
+	if err != nil {
+		return err
+	}
+	for k, v := range s {
+		if settings[k] == "" {
+			settings[k] = v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package clientconfig
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc"
+)
+
+// defaultConnectTimeout is how long Connect and Dial wait for etcd if ETCD_CONNECT_TIMEOUT isn't set.
+const defaultConnectTimeout = 30 * time.Second
+
+// Connect reads the configuration, connects to etcd and waits until at least one endpoint answers a Status call, for at most ETCD_CONNECT_TIMEOUT (default 30 seconds) or until ctx is done.
+// The client's KV, Watcher and Lease are confined to ETCD_NAMESPACE if that's set, see Wrap.
+// ctx only bounds Connect itself, use WithContext to bind the lifetime of the client.
+// If etcd rejects the password later on, the configuration is read again (at most every 10 seconds) and a rotated password is used to authenticate again, without recreating the client.
+func Connect(ctx context.Context, opts ...Option) (*clientv3.Client, error) {
+	settings, err := ReadSettingsContext(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return connectSettings(ctx, settings, opts)
+}
+
+// connectSettings is Connect with the settings already read.
+func connectSettings(ctx context.Context, settings Settings, opts []Option) (*clientv3.Client, error) {
+	c, err := applySettingsContext(ctx, Defaults(), settings, opts)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := connectTimeout(settings)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.Endpoints) == 0 {
+		return nil, errorf(CodeNotConfigured, "ETCD_ENDPOINTS", "etcd isn't configured: set ETCD_ENDPOINTS (or ETCD_GATEWAY_ENDPOINT or ETCD_DISCOVERY_SRV)")
+	}
+	if c.Username != "" && c.Password != "" {
+		appendDialOptions(&c, grpc.WithChainUnaryInterceptor(newCredentialRefresher(c, opts).interceptor()))
+	}
+	cli, err := clientv3.New(c)
+	if err != nil {
+		return nil, err
+	}
+	vctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if _, err := verify(vctx, cli); err != nil {
+		cli.Close()
+		return nil, fmt.Errorf("etcd isn't ready: %v", err)
+	}
+	wrapNamespace(cli, namespaceFromSettings(settings))
+	// ApplySettings already validated it.
+	rt, _ := requestTimeout(settings)
+	wrapRequestTimeout(cli, rt)
+	return cli, nil
+}
+
+// connectTimeout parses ETCD_CONNECT_TIMEOUT.
+func connectTimeout(s Settings) (time.Duration, error) {
+	v := s["ETCD_CONNECT_TIMEOUT"]
+	if v == "" {
+		return defaultConnectTimeout, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, errorf(CodeInvalidValue, "ETCD_CONNECT_TIMEOUT", "failed to parse ETCD_CONNECT_TIMEOUT as a positive duration (%q)", v)
+	}
+	return d, nil
+}
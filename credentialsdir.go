@@ -0,0 +1,88 @@
+package clientconfig
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// credentialsDirFiles are the files ETCD_CREDENTIALS_DIR and systemd's $CREDENTIALS_DIRECTORY provide, and the variables they're read as. The names in ETCD_CREDENTIALS_DIR match Kubernetes' kubernetes.io/basic-auth and kubernetes.io/tls Secrets.
+var credentialsDirFiles = []struct {
+	name string
+	// credential is the name of the systemd credential, after the "etcd." prefix.
+	credential string
+	variable   string
+	// alternatives are variables that replace this one. If any of them are set, the file isn't used either.
+	alternatives []string
+}{
+	{"endpoints", "endpoints", "ETCD_ENDPOINTS", []string{"ETCD_GATEWAY_ENDPOINT", "ETCD_DISCOVERY_SRV"}},
+	{"username", "username", "ETCD_USERNAME", []string{"ETCD_USERNAME_AND_PASSWORD"}},
+	{"password", "password", "ETCD_PASSWORD", []string{"ETCD_USERNAME_AND_PASSWORD"}},
+	{"ca.crt", "server-ca", "ETCD_SERVER_CA", nil},
+	{"tls.crt", "client-cert", "ETCD_CLIENT_CERT", []string{"ETCD_CLIENT_CERT_AND_KEY"}},
+	{"tls.key", "client-key", "ETCD_CLIENT_KEY", []string{"ETCD_CLIENT_CERT_AND_KEY"}},
+}
+
+// credentialsDirEnvironment makes the files in ETCD_CREDENTIALS_DIR and the systemd credentials (from LoadCredential=) look like k_FILE variables, so they're read (and reloaded) exactly like those.
+// A file is only used if none of the ways to set its variable (or its alternatives) are used. ETCD_CREDENTIALS_DIR wins over systemd credentials.
+type credentialsDirEnvironment struct {
+	Environment
+	// systemdDir is $CREDENTIALS_DIRECTORY, which is read without any prefix.
+	systemdDir string
+	// systemdPrefix is what the systemd credential names start with, like "etcd." or "etcd-metrics." for WithCluster("metrics").
+	systemdPrefix string
+}
+
+// newCredentialsDirEnvironment wraps env, which already has o's prefix and cluster applied. base is the unwrapped Environment.
+func newCredentialsDirEnvironment(env, base Environment, o *options) credentialsDirEnvironment {
+	prefix := "etcd"
+	if o.cluster != "" {
+		prefix += "-" + strings.ToLower(o.cluster)
+	}
+	if o.prefix != "" {
+		prefix = strings.ToLower(strings.TrimSuffix(o.prefix, "_")) + "-" + prefix
+	}
+	return credentialsDirEnvironment{env, base.Getenv("CREDENTIALS_DIRECTORY"), prefix + "."}
+}
+
+func (e credentialsDirEnvironment) Getenv(key string) string {
+	if v := e.Environment.Getenv(key); v != "" {
+		return v
+	}
+	dir := e.Environment.Getenv("ETCD_CREDENTIALS_DIR")
+	if dir == "" && e.systemdDir == "" {
+		return ""
+	}
+	for _, f := range credentialsDirFiles {
+		if key != f.variable+"_FILE" {
+			continue
+		}
+		for _, k := range append([]string{f.variable}, f.alternatives...) {
+			for _, suffix := range []string{"", "_FILE", "_B64", "_COMMAND", "_SOURCE", "_KEYRING"} {
+				if e.Environment.Getenv(k+suffix) != "" {
+					return ""
+				}
+			}
+		}
+		if dir != "" {
+			if fn := filepath.Join(dir, f.name); e.exists(fn) {
+				return fn
+			}
+		}
+		if e.systemdDir != "" {
+			if fn := filepath.Join(e.systemdDir, e.systemdPrefix+f.credential); e.exists(fn) {
+				return fn
+			}
+		}
+		return ""
+	}
+	return ""
+}
+
+func (e credentialsDirEnvironment) exists(fn string) bool {
+	if st, ok := e.Environment.(statEnvironment); ok {
+		_, err := st.Stat(fn)
+		return err == nil
+	}
+	_, err := e.Environment.ReadFile(fn)
+	return err == nil
+}
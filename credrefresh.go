@@ -0,0 +1,109 @@
+package clientconfig
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc"
+)
+
+// credentialRefreshInterval is how often a client from Connect re-reads its credentials at most, so a wrong password doesn't hammer the secret manager.
+const credentialRefreshInterval = 10 * time.Second
+
+const authenticateMethod = "/etcdserverpb.Auth/Authenticate"
+
+// credentialRefresher re-reads the credentials when etcd rejects the password of a client from Connect, so a rotated password is picked up without restarting.
+// Only ETCD_USERNAME, ETCD_PASSWORD and ETCD_USERNAME_AND_PASSWORD (and their _FILE, _COMMAND, _SOURCE and other variants) are read again; the rest of the configuration is left alone.
+// clientv3 keeps authenticating with the password from its config, so rather than changing the client, we swap the credentials in its Authenticate calls.
+type credentialRefresher struct {
+	o *options
+	// configured are the credentials the client was created with.
+	configured etcdserverpb.AuthenticateRequest
+
+	mtx         sync.Mutex
+	current     etcdserverpb.AuthenticateRequest
+	lastRefresh time.Time
+}
+
+func newCredentialRefresher(c clientv3.Config, opts []Option) *credentialRefresher {
+	creds := etcdserverpb.AuthenticateRequest{Name: c.Username, Password: c.Password}
+	return &credentialRefresher{o: newOptions(opts), configured: creds, current: creds, lastRefresh: time.Now()}
+}
+
+// credentials returns the latest credentials.
+func (r *credentialRefresher) credentials() etcdserverpb.AuthenticateRequest {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.current
+}
+
+// credentialVariables are the variables credentialRefresher reads again.
+var credentialVariables = []string{"ETCD_USERNAME", "ETCD_PASSWORD", "ETCD_USERNAME_AND_PASSWORD"}
+
+// refresh reads the credentials again and returns the latest credentials and whether this call changed them.
+func (r *credentialRefresher) refresh(ctx context.Context) (etcdserverpb.AuthenticateRequest, bool) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if time.Since(r.lastRefresh) < credentialRefreshInterval {
+		return r.current, false
+	}
+	r.lastRefresh = time.Now()
+	settings := Settings{}
+	var err error
+	for _, k := range credentialVariables {
+		if settings[k], err = readVariable(ctx, r.o, k); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		var c clientv3.Config
+		err = applyCredentials(&c, settings)
+		if err == nil {
+			if c.Username == "" || c.Password == "" {
+				r.o.warnf("etcd rejected the credentials, but the configuration no longer has a username and password; keeping the old ones")
+				return r.current, false
+			}
+			if c.Username == r.current.Name && c.Password == r.current.Password {
+				return r.current, false
+			}
+			r.current = etcdserverpb.AuthenticateRequest{Name: c.Username, Password: c.Password}
+			return r.current, true
+		}
+	}
+	r.o.warnf("etcd rejected the credentials, and re-reading them failed: %v", err)
+	return r.current, false
+}
+
+// interceptor returns a unary interceptor that uses the latest credentials for the client's own Authenticate calls, and re-reads them when etcd rejects them.
+// An expired token (ErrInvalidAuthToken) makes clientv3 authenticate again, which ends up here too. On ErrPermissionDenied with new credentials, we tell clientv3 the token is invalid so it gets a new one and retries the call.
+func (r *credentialRefresher) interceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if method != authenticateMethod {
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			if rpctypes.Error(err) == rpctypes.ErrPermissionDenied {
+				if _, changed := r.refresh(ctx); changed {
+					return rpctypes.ErrGRPCInvalidAuthToken
+				}
+			}
+			return err
+		}
+		ar, ok := req.(*etcdserverpb.AuthenticateRequest)
+		if !ok || ar.Name != r.configured.Name || ar.Password != r.configured.Password {
+			// Someone calling Auth.Authenticate with other credentials.
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		creds := r.credentials()
+		err := invoker(ctx, method, &creds, reply, cc, opts...)
+		if rpctypes.Error(err) != rpctypes.ErrAuthFailed {
+			return err
+		}
+		if latest, _ := r.refresh(ctx); latest.Name != creds.Name || latest.Password != creds.Password {
+			return invoker(ctx, method, &latest, reply, cc, opts...)
+		}
+		return err
+	}
+}
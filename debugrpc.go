@@ -0,0 +1,115 @@
+package clientconfig
+
+import (
+	"context"
+	"io"
+	"log"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// debugKeyLength is how much of a key ETCD_DEBUG_RPC logs.
+const debugKeyLength = 64
+
+// rpcLogger logs a single call for ETCD_DEBUG_RPC.
+type rpcLogger func(method, key string, took time.Duration, size int, err error)
+
+// newRPCLogger logs to the zap logger if the config has one and to the standard logger otherwise.
+func newRPCLogger(lg *zap.Logger) rpcLogger {
+	if lg != nil {
+		return func(method, key string, took time.Duration, size int, err error) {
+			lg.Info("etcd rpc", zap.String("method", method), zap.String("key", key), zap.Duration("took", took), zap.Int("response-bytes", size), zap.Error(err))
+		}
+	}
+	return func(method, key string, took time.Duration, size int, err error) {
+		log.Printf("clientconfig: rpc %s key=%q took=%s response-bytes=%d error=%v", method, key, took, size, err)
+	}
+}
+
+// addDebugLogging installs interceptors that log every call.
+func (i *interceptors) addDebugLogging(logRPC rpcLogger) {
+	i.unary = append(i.unary, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		logRPC(method, debugKey(req), time.Since(start), messageSize(reply), err)
+		return err
+	})
+	i.stream = append(i.stream, func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		s, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			logRPC(method, "", time.Since(start), 0, err)
+			return nil, err
+		}
+		return &debugStream{ClientStream: s, method: method, start: start, logRPC: logRPC}, nil
+	})
+}
+
+// debugStream logs a stream when it ends, with the key of the first request sent on it and the total size of the responses.
+type debugStream struct {
+	grpc.ClientStream
+	method string
+	start  time.Time
+	logRPC rpcLogger
+	key    string
+	size   int
+	done   bool
+}
+
+func (s *debugStream) SendMsg(m interface{}) error {
+	if s.key == "" {
+		s.key = debugKey(m)
+	}
+	return s.ClientStream.SendMsg(m)
+}
+
+func (s *debugStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == nil {
+		s.size += messageSize(m)
+		return nil
+	}
+	if !s.done {
+		s.done = true
+		lerr := err
+		if lerr == io.EOF {
+			lerr = nil
+		}
+		s.logRPC(s.method, s.key, time.Since(s.start), s.size, lerr)
+	}
+	return err
+}
+
+// debugKey returns the (truncated) key of an etcd request, if it has one.
+func debugKey(req interface{}) string {
+	var key []byte
+	switch r := req.(type) {
+	case *pb.RangeRequest:
+		key = r.Key
+	case *pb.PutRequest:
+		key = r.Key
+	case *pb.DeleteRangeRequest:
+		key = r.Key
+	case *pb.WatchRequest:
+		key = r.GetCreateRequest().GetKey()
+	case *pb.TxnRequest:
+		if len(r.Compare) > 0 {
+			key = r.Compare[0].Key
+		}
+	}
+	if len(key) > debugKeyLength {
+		return string(key[:debugKeyLength]) + "..."
+	}
+	return string(key)
+}
+
+// messageSize returns the encoded size of a protobuf message.
+func messageSize(m interface{}) int {
+	if s, ok := m.(interface{ Size() int }); ok {
+		return s.Size()
+	}
+	return 0
+}
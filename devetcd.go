@@ -0,0 +1,250 @@
+package clientconfig
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// devEtcdImage is the image ETCD_DEV_AUTOSTART runs if there's no etcd binary in $PATH.
+const devEtcdImage = "gcr.io/etcd-development/etcd:v3.5.1"
+
+var (
+	devEtcdMtx sync.Mutex
+	// devEtcd is the etcd started by ETCD_DEV_AUTOSTART. It's shared by all configs in the process.
+	devEtcd *devEtcdProcess
+)
+
+type devEtcdProcess struct {
+	endpoint string
+	cmd      *exec.Cmd
+	// container is the name of the Docker container, if we used Docker.
+	container string
+	dataDir   string
+	output    *startupOutput
+	exited    chan struct{}
+}
+
+// StopDevEtcd tears down the etcd started by ETCD_DEV_AUTOSTART, if any. Call it after closing your client(s).
+// It's also stopped when the Context passed with WithContext is done, and (on Linux, when running the etcd binary) when the process exits.
+func StopDevEtcd() {
+	devEtcdMtx.Lock()
+	p := devEtcd
+	devEtcd = nil
+	devEtcdMtx.Unlock()
+	if p != nil {
+		p.stop()
+	}
+}
+
+// stopDevEtcdProcess stops p, unless it was already stopped.
+func stopDevEtcdProcess(p *devEtcdProcess) {
+	devEtcdMtx.Lock()
+	current := devEtcd == p
+	if current {
+		devEtcd = nil
+	}
+	devEtcdMtx.Unlock()
+	if current {
+		p.stop()
+	}
+}
+
+// applyDevAutostart points c at a disposable local etcd if none of its endpoints are reachable.
+func applyDevAutostart(o *options, c *clientv3.Config, settings Settings) error {
+	devEtcdMtx.Lock()
+	defer devEtcdMtx.Unlock()
+	if o.dryRun && devEtcd == nil {
+		o.skip("ETCD_DEV_AUTOSTART: the endpoints weren't probed and no etcd was started")
+		return nil
+	}
+	if devEtcd == nil && anyEndpointReachable(c.Endpoints) {
+		return nil
+	}
+	devTLS := c.TLS != nil
+	if devTLS {
+		if b, _ := strconv.ParseBool(settings["ETCD_DEV_TLS"]); !b {
+			return errorf(CodeConflictingVariables, "ETCD_DEV_AUTOSTART", "ETCD_DEV_AUTOSTART can only start etcd with TLS together with ETCD_DEV_TLS")
+		}
+	}
+	if devEtcd == nil {
+		p, err := startDevEtcd(devTLS, devTLSDir(settings))
+		if err != nil {
+			return errorf(CodeDevEtcdFailed, "ETCD_DEV_AUTOSTART", "failed to start a local etcd for ETCD_DEV_AUTOSTART: %v", err)
+		}
+		devEtcd = p
+		if o.devEtcdStarted != nil {
+			o.devEtcdStarted(p)
+		}
+		o.warnf("ETCD_DEV_AUTOSTART is enabled and no endpoint was reachable: started a disposable etcd at %s. NEVER use this in production.", p.endpoint)
+	}
+	c.Endpoints = []string{devEtcd.endpoint}
+	c.AutoSyncInterval = 0
+	if o.ctx != nil && !o.dryRun {
+		p := devEtcd
+		go func() {
+			<-o.ctx.Done()
+			stopDevEtcdProcess(p)
+		}()
+	}
+	return nil
+}
+
+// anyEndpointReachable returns whether a TCP connection can be made to any of eps.
+func anyEndpointReachable(eps []string) bool {
+	for _, ep := range eps {
+		addr := ep
+		if u, err := url.Parse(ep); err == nil && u.Host != "" {
+			addr = u.Host
+		}
+		network, address := splitDialTarget(addr)
+		conn, err := net.DialTimeout(network, address, 300*time.Millisecond)
+		if err == nil {
+			conn.Close()
+			return true
+		}
+	}
+	return false
+}
+
+// startDevEtcd runs the etcd binary from $PATH or a Docker container, and waits until it accepts connections.
+func startDevEtcd(devTLS bool, tlsDir string) (*devEtcdProcess, error) {
+	port, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+	scheme := "http"
+	if devTLS {
+		scheme = "https"
+	}
+	p := &devEtcdProcess{
+		endpoint: fmt.Sprintf("%s://127.0.0.1:%d", scheme, port),
+		output:   &startupOutput{},
+		exited:   make(chan struct{}),
+	}
+	tlsFlags := func(dir string) []string {
+		if !devTLS {
+			return nil
+		}
+		return []string{"--client-cert-auth", "--trusted-ca-file=" + filepath.Join(dir, "ca.crt"), "--cert-file=" + filepath.Join(dir, "server.crt"), "--key-file=" + filepath.Join(dir, "server.key")}
+	}
+	if bin, err := exec.LookPath("etcd"); err == nil {
+		peerPort, err := freePort()
+		if err != nil {
+			return nil, err
+		}
+		p.dataDir, err = ioutil.TempDir("", "etcd-dev-data")
+		if err != nil {
+			return nil, err
+		}
+		peer := fmt.Sprintf("http://127.0.0.1:%d", peerPort)
+		args := append([]string{"--data-dir=" + p.dataDir, "--listen-client-urls=" + p.endpoint, "--advertise-client-urls=" + p.endpoint, "--listen-peer-urls=" + peer, "--initial-advertise-peer-urls=" + peer, "--initial-cluster=default=" + peer}, tlsFlags(tlsDir)...)
+		p.cmd = exec.Command(bin, args...)
+		p.cmd.SysProcAttr = devEtcdSysProcAttr()
+	} else if docker, err := exec.LookPath("docker"); err == nil {
+		p.container = fmt.Sprintf("etcd-client-from-env-dev-%d-%d", os.Getpid(), port)
+		args := []string{"run", "--rm", "--name", p.container, "-p", fmt.Sprintf("127.0.0.1:%d:2379", port)}
+		if devTLS {
+			args = append(args, "-v", tlsDir+":/tls:ro")
+		}
+		args = append(args, devEtcdImage, "/usr/local/bin/etcd", "--listen-client-urls="+scheme+"://0.0.0.0:2379", "--advertise-client-urls="+p.endpoint)
+		p.cmd = exec.Command(docker, append(args, tlsFlags("/tls")...)...)
+	} else {
+		return nil, fmt.Errorf("neither etcd nor docker found in $PATH")
+	}
+	p.cmd.Stdout = p.output
+	p.cmd.Stderr = p.output
+	if err := p.cmd.Start(); err != nil {
+		p.cleanup()
+		return nil, err
+	}
+	go func() {
+		p.cmd.Wait()
+		close(p.exited)
+	}()
+	// Pulling the image can take a while.
+	deadline := time.Now().Add(2 * time.Minute)
+	for !anyEndpointReachable([]string{p.endpoint}) {
+		select {
+		case <-p.exited:
+			p.cleanup()
+			return nil, fmt.Errorf("%s exited: %s", filepath.Base(p.cmd.Path), p.output.lastLines(5))
+		case <-time.After(100 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			p.stop()
+			return nil, fmt.Errorf("etcd didn't start listening on %s", p.endpoint)
+		}
+	}
+	p.output.discard()
+	return p, nil
+}
+
+func (p *devEtcdProcess) stop() {
+	if p.container != "" {
+		exec.Command("docker", "rm", "-f", p.container).Run()
+	}
+	p.cmd.Process.Kill()
+	<-p.exited
+	p.cleanup()
+}
+
+func (p *devEtcdProcess) cleanup() {
+	if p.dataDir != "" {
+		os.RemoveAll(p.dataDir)
+	}
+}
+
+// freePort returns a TCP port on localhost that's currently not in use.
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// startupOutput collects etcd's output until it's up, to explain why it failed to start.
+type startupOutput struct {
+	mtx       sync.Mutex
+	buf       strings.Builder
+	discarded bool
+}
+
+func (o *startupOutput) Write(b []byte) (int, error) {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	if !o.discarded {
+		o.buf.Write(b)
+	}
+	return len(b), nil
+}
+
+// lastLines returns the last n lines of output.
+func (o *startupOutput) lastLines(n int) string {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	lines := strings.Split(strings.TrimSpace(o.buf.String()), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (o *startupOutput) discard() {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	o.discarded = true
+	o.buf.Reset()
+}
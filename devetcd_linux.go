@@ -0,0 +1,9 @@
+package clientconfig
+
+import "syscall"
+
+// devEtcdSysProcAttr makes the kernel kill the dev etcd when we exit.
+// Pdeathsig actually fires when the thread that started it exits, which Go doesn't guarantee to keep around; StopDevEtcd is the reliable way.
+func devEtcdSysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Pdeathsig: syscall.SIGKILL}
+}
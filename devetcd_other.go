@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package clientconfig
+
+import "syscall"
+
+func devEtcdSysProcAttr() *syscall.SysProcAttr {
+	return nil
+}
@@ -0,0 +1,175 @@
+package clientconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// devTLSValidity is how long the throwaway CA and certificates of ETCD_DEV_TLS are valid.
+const devTLSValidity = 7 * 24 * time.Hour
+
+// devCAName is the common name of the ETCD_DEV_TLS CA, by which Fingerprint recognizes the throwaway material.
+const devCAName = "etcd-client-from-env dev CA"
+
+// devTLSDir returns the directory in which ETCD_DEV_TLS keeps the CA and the server certificate.
+func devTLSDir(s Settings) string {
+	if d := s["ETCD_DEV_TLS_DIR"]; d != "" {
+		return d
+	}
+	return filepath.Join(os.TempDir(), "etcd-dev-tls")
+}
+
+// applyDevTLS fills ETCD_SERVER_CA, ETCD_CLIENT_CERT and ETCD_CLIENT_KEY in settings with throwaway material.
+// The CA is kept in dir (and reused while it's valid for at least another day), so a dev etcd started with the server certificate from dir keeps trusting our client certificates across restarts. The client certificate is freshly generated and only kept in memory.
+func applyDevTLS(o *options, settings Settings) error {
+	for _, k := range []string{"ETCD_SERVER_CA", "ETCD_CLIENT_CERT", "ETCD_CLIENT_KEY", "ETCD_CLIENT_CERT_AND_KEY"} {
+		if settings[k] != "" {
+			return errorf(CodeConflictingVariables, "ETCD_DEV_TLS", "you can't set both ETCD_DEV_TLS and %s", k)
+		}
+	}
+	dir := devTLSDir(settings)
+	if o.dryRun {
+		// Generating keys and writing the CA are side effects, and Fingerprint ignores the certificates anyway. Only the CA that's already there is read.
+		o.skip("ETCD_DEV_TLS: no certificates were generated, only an existing CA is used")
+		if b, err := os.ReadFile(filepath.Join(dir, "ca.crt")); err == nil {
+			settings["ETCD_SERVER_CA"] = string(b)
+		}
+		return nil
+	}
+	ca, caKey, err := loadOrCreateDevCA(dir)
+	if err != nil {
+		return errorf(CodeInvalidCertificate, "ETCD_DEV_TLS", "failed to set up the ETCD_DEV_TLS CA in %q: %v", dir, err)
+	}
+	cert, key, err := issueDevCertificate(ca, caKey, "etcd-dev-client", x509.ExtKeyUsageClientAuth, nil, nil)
+	if err != nil {
+		return errorf(CodeInvalidCertificate, "ETCD_DEV_TLS", "failed to issue an ETCD_DEV_TLS client certificate: %v", err)
+	}
+	settings["ETCD_SERVER_CA"] = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}))
+	settings["ETCD_CLIENT_CERT"] = string(cert)
+	settings["ETCD_CLIENT_KEY"] = string(key)
+	o.warnf("ETCD_DEV_TLS is enabled: using throwaway certificates. NEVER use this in production. Start a dev etcd with: etcd --listen-client-urls=https://127.0.0.1:2379 --advertise-client-urls=https://127.0.0.1:2379 --client-cert-auth --trusted-ca-file=%s --cert-file=%s --key-file=%s", filepath.Join(dir, "ca.crt"), filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"))
+	return nil
+}
+
+// loadOrCreateDevCA returns the CA in dir, or creates a new one (with a server certificate for localhost) if there is none or it's about to expire.
+func loadOrCreateDevCA(dir string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	if pair, err := tls.LoadX509KeyPair(filepath.Join(dir, "ca.crt"), filepath.Join(dir, "ca.key")); err == nil {
+		ca, err := x509.ParseCertificate(pair.Certificate[0])
+		if key, ok := pair.PrivateKey.(*ecdsa.PrivateKey); err == nil && ok && time.Until(ca.NotAfter) > 24*time.Hour {
+			return ca, key, nil
+		}
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, nil, err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl, err := devCertificateTemplate(devCAName)
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl.IsCA = true
+	tmpl.BasicConstraintsValid = true
+	tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	serverCert, serverKey, err := issueDevCertificate(ca, key, "localhost", x509.ExtKeyUsageServerAuth, []string{"localhost"}, []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback})
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"server.crt", serverCert},
+		{"server.key", serverKey},
+		{"ca.key", pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})},
+		// ca.crt goes last, so a partial write doesn't leave a CA we'd reuse next time.
+		{"ca.crt", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})},
+	}
+	for _, f := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, f.name), f.data, 0600); err != nil {
+			return nil, nil, err
+		}
+	}
+	return ca, key, nil
+}
+
+// issueDevCertificate returns a PEM encoded certificate and key signed by ca.
+func issueDevCertificate(ca *x509.Certificate, caKey *ecdsa.PrivateKey, cn string, usage x509.ExtKeyUsage, dnsNames []string, ips []net.IP) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl, err := devCertificateTemplate(cn)
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+	tmpl.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+	tmpl.DNSNames = dnsNames
+	tmpl.IPAddresses = ips
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), nil
+}
+
+func devCertificateTemplate(cn string) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(devTLSValidity),
+	}, nil
+}
+
+// isDevTLSPool returns whether p only has the ETCD_DEV_TLS CA (besides the system roots).
+func isDevTLSPool(p *x509.CertPool) bool {
+	// Subjects is deprecated because it leaves out the system roots, which is what we want here.
+	subjects := p.Subjects()
+	if len(subjects) != 1 {
+		return false
+	}
+	var name pkix.RDNSequence
+	if _, err := asn1.Unmarshal(subjects[0], &name); err != nil {
+		return false
+	}
+	var n pkix.Name
+	n.FillFromRDNSequence(&name)
+	return n.CommonName == devCAName
+}
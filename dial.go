@@ -0,0 +1,31 @@
+package clientconfig
+
+import (
+	"context"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Dial is like Connect, but also returns the client's (namespaced) KV and a cleanup function that closes the client; defer it.
+// If ETCD_DEV_AUTOSTART started an etcd for this client, cleanup stops it too.
+func Dial(ctx context.Context, opts ...Option) (client *clientv3.Client, kv clientv3.KV, cleanup func(), err error) {
+	var started *devEtcdProcess
+	opts = append(opts[:len(opts):len(opts)], func(o *options) {
+		o.devEtcdStarted = func(p *devEtcdProcess) {
+			started = p
+		}
+	})
+	cli, err := Connect(ctx, opts...)
+	if err != nil {
+		if started != nil {
+			stopDevEtcdProcess(started)
+		}
+		return nil, nil, nil, err
+	}
+	return cli, cli.KV, func() {
+		cli.Close()
+		if started != nil {
+			stopDevEtcdProcess(started)
+		}
+	}, nil
+}
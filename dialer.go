@@ -0,0 +1,28 @@
+package clientconfig
+
+import (
+	"context"
+	"net"
+	"strings"
+)
+
+// dialFunc is a dial function as used by grpc.WithContextDialer.
+type dialFunc func(ctx context.Context, addr string) (net.Conn, error)
+
+// splitDialTarget returns the network and address for addr, like gRPC's default dialer does. etcd passes unix sockets as unix:path or unix://path.
+func splitDialTarget(addr string) (network, address string) {
+	if strings.HasPrefix(addr, "unix://") {
+		return "unix", strings.TrimPrefix(addr, "unix://")
+	}
+	if strings.HasPrefix(addr, "unix:") {
+		return "unix", strings.TrimPrefix(addr, "unix:")
+	}
+	return "tcp", addr
+}
+
+// baseDial is what gRPC would do without a custom dialer.
+func baseDial(ctx context.Context, addr string) (net.Conn, error) {
+	var d net.Dialer
+	network, address := splitDialTarget(addr)
+	return d.DialContext(ctx, network, address)
+}
@@ -0,0 +1,58 @@
+package clientconfig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+var (
+	dialOptionsMtx sync.Mutex
+	dialOptions    = map[string]func() (grpc.DialOption, error){}
+)
+
+// RegisterDialOption makes a grpc.DialOption available under the given name. Operators can enable it per deployment by listing the name in ETCD_DIAL_OPTIONS.
+// The factory is called every time a config is built with the option enabled.
+// RegisterDialOption is meant to be called from init functions and panics if the name is already taken.
+func RegisterDialOption(name string, factory func() (grpc.DialOption, error)) {
+	dialOptionsMtx.Lock()
+	defer dialOptionsMtx.Unlock()
+	if strings.ContainsAny(name, ", ") {
+		panic(fmt.Sprintf("clientconfig: invalid dial option name %q", name))
+	}
+	if _, found := dialOptions[name]; found {
+		panic(fmt.Sprintf("clientconfig: dial option %q registered twice", name))
+	}
+	dialOptions[name] = factory
+}
+
+// namedDialOptions returns the DialOptions for the given comma separated list of registered names.
+func namedDialOptions(names string) ([]grpc.DialOption, error) {
+	dialOptionsMtx.Lock()
+	defer dialOptionsMtx.Unlock()
+	var ret []grpc.DialOption
+	for _, n := range strings.Split(names, ",") {
+		n = strings.TrimSpace(n)
+		if n == "" {
+			continue
+		}
+		f, found := dialOptions[n]
+		if !found {
+			var known []string
+			for k := range dialOptions {
+				known = append(known, k)
+			}
+			sort.Strings(known)
+			return nil, errorf(CodeUnknownName, "ETCD_DIAL_OPTIONS", "unknown dial option %q in ETCD_DIAL_OPTIONS (registered: %s)", n, strings.Join(known, ", "))
+		}
+		o, err := f()
+		if err != nil {
+			return nil, errorf(CodeRegisteredFailure, "ETCD_DIAL_OPTIONS", "failed to create dial option %q: %v", n, err)
+		}
+		ret = append(ret, o)
+	}
+	return ret, nil
+}
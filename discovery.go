@@ -0,0 +1,48 @@
+package clientconfig
+
+import (
+	"context"
+	"sync"
+
+	"go.etcd.io/etcd/client/pkg/v3/srv"
+)
+
+// discoverEndpoints looks up the _etcd-client-ssl._tcp and _etcd-client._tcp SRV records of domain (with a -serviceName suffix if given), like etcdctl --discovery-srv does.
+// srv can't be cancelled, so if ctx is done first the lookup is abandoned in the background.
+func discoverEndpoints(ctx context.Context, domain, serviceName string) ([]string, error) {
+	type result struct {
+		srvs *srv.SRVClients
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		srvs, err := srv.GetClient("etcd-client", domain, serviceName)
+		ch <- result{srvs, err}
+	}()
+	var r result
+	select {
+	case r = <-ch:
+	case <-ctx.Done():
+		r.err = ctx.Err()
+	}
+	if r.err != nil {
+		return nil, errorf(CodeDiscoveryFailed, "ETCD_DISCOVERY_SRV", "failed to discover endpoints through SRV records of %q: %v", domain, r.err)
+	}
+	if len(r.srvs.Endpoints) == 0 {
+		return nil, errorf(CodeDiscoveryFailed, "ETCD_DISCOVERY_SRV", "no SRV records found for %q", domain)
+	}
+	discovered.Store(domain+"/"+serviceName, r.srvs.Endpoints)
+	return r.srvs.Endpoints, nil
+}
+
+// discovered has the endpoints of the last successful discoverEndpoints per domain and service name.
+var discovered sync.Map
+
+// lastDiscoveredEndpoints returns the endpoints found by the last successful discoverEndpoints for domain and serviceName, or nil if it didn't run yet.
+func lastDiscoveredEndpoints(domain, serviceName string) []string {
+	v, ok := discovered.Load(domain + "/" + serviceName)
+	if !ok {
+		return nil
+	}
+	return append([]string(nil), v.([]string)...)
+}
@@ -0,0 +1,111 @@
+package clientconfig
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// dnsRefresher dials hostnames itself, so it knows which IP each connection went to.
+// It periodically re-resolves the hostnames and closes connections to IPs that are no longer listed. gRPC then reconnects, which resolves the name again.
+type dnsRefresher struct {
+	interval time.Duration
+	next     dialFunc
+
+	mtx   sync.Mutex
+	conns map[*trackedConn]bool
+}
+
+type trackedConn struct {
+	net.Conn
+	r    *dnsRefresher
+	host string
+	ip   string
+	once sync.Once
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(func() {
+		c.r.mtx.Lock()
+		delete(c.r.conns, c)
+		c.r.mtx.Unlock()
+	})
+	return c.Conn.Close()
+}
+
+func newDNSRefresher(interval time.Duration, next dialFunc) *dnsRefresher {
+	return &dnsRefresher{
+		interval: interval,
+		next:     next,
+		conns:    map[*trackedConn]bool{},
+	}
+}
+
+func (r *dnsRefresher) dial(ctx context.Context, addr string) (net.Conn, error) {
+	if network, _ := splitDialTarget(addr); network != "tcp" {
+		return r.next(ctx, addr)
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		return r.next(ctx, addr)
+	}
+	// We resolve on every dial, so a connection error always leads to a fresh lookup.
+	ips, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var lastErr error
+	for _, ip := range ips {
+		c, err := r.next(ctx, net.JoinHostPort(ip, port))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		tc := &trackedConn{Conn: c, r: r, host: host, ip: ip}
+		r.mtx.Lock()
+		r.conns[tc] = true
+		if len(r.conns) == 1 {
+			go r.refreshLoop()
+		}
+		r.mtx.Unlock()
+		return tc, nil
+	}
+	return nil, lastErr
+}
+
+// refreshLoop runs as long as there are tracked connections.
+func (r *dnsRefresher) refreshLoop() {
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+	for range t.C {
+		r.mtx.Lock()
+		if len(r.conns) == 0 {
+			r.mtx.Unlock()
+			return
+		}
+		byHost := map[string][]*trackedConn{}
+		for c := range r.conns {
+			byHost[c.host] = append(byHost[c.host], c)
+		}
+		r.mtx.Unlock()
+		for host, conns := range byHost {
+			ctx, cancel := context.WithTimeout(context.Background(), r.interval)
+			ips, err := net.DefaultResolver.LookupHost(ctx, host)
+			cancel()
+			if err != nil {
+				// Keep the existing connections if DNS is temporarily broken.
+				continue
+			}
+			current := map[string]bool{}
+			for _, ip := range ips {
+				current[ip] = true
+			}
+			for _, c := range conns {
+				if !current[c.ip] {
+					c.Close()
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package clientconfig
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// LoadDotenv reads a .env file with KEY=value lines into Settings, for use with ApplySettings. The process environment isn't changed.
+// Only ETCD_* variables are used, so the file can be shared with the rest of the application. _FILE variables in it are read (relative to the working directory), and values are taken literally without expanding $VAR.
+func LoadDotenv(path string) (Settings, error) {
+	o := &options{env: osEnvironment{}}
+	o.base = o.env
+	return loadDotenv(context.Background(), o, "ETCD_ENV_FILE", path, false)
+}
+
+// readEnvFile fills the variables missing from settings from the .env file named by ETCD_ENV_FILE.
+func readEnvFile(ctx context.Context, o *options, settings Settings) error {
+	path := o.env.Getenv("ETCD_ENV_FILE")
+	if path == "" {
+		return nil
+	}
+	strict, err := strictMode(ctx, o)
+	if err != nil {
+		return err
+	}
+	s, err := loadDotenv(ctx, o, "ETCD_ENV_FILE", path, strict)
+	if err != nil {
+		return err
+	}
+	for k, v := range s {
+		if settings[k] == "" {
+			settings[k] = v
+		}
+	}
+	return nil
+}
+
+// loadDotenv reads the .env file at path, which came from variable k. Unknown ETCD_* variables in it are an error if strict is set.
+func loadDotenv(ctx context.Context, o *options, k, path string, strict bool) (Settings, error) {
+	b, err := readFileContext(ctx, o.env, path)
+	if err != nil {
+		return nil, errorf(CodeUnreadableFile, k, "error reading %q (for %s): %v", path, k, err)
+	}
+	vars, err := parseDotenv(string(b))
+	if err != nil {
+		return nil, errorf(CodeInvalidValue, k, "failed to parse %q: %v", path, err)
+	}
+	if strict {
+		known := knownVariableNames()
+		for v := range vars {
+			if strings.HasPrefix(v, "ETCD_") && !known[v] {
+				if s := closestVariable(v, known); s != "" {
+					return nil, errorf(CodeUnknownVariable, v, "unknown variable %s in %q in strict mode (did you mean %s?)", v, path, s)
+				}
+				return nil, errorf(CodeUnknownVariable, v, "unknown variable %s in %q in strict mode", v, path)
+			}
+		}
+	}
+	do := *o
+	do.env = dotenvEnvironment{o.env, vars}
+	s := Settings{}
+	for _, v := range allVariables() {
+		val, err := readVariable(ctx, &do, v)
+		if err != nil {
+			return nil, err
+		}
+		if val != "" {
+			s[v] = val
+		}
+	}
+	return s, nil
+}
+
+// dotenvEnvironment reads variables from a parsed .env file, and files from the underlying Environment.
+type dotenvEnvironment struct {
+	Environment
+	vars map[string]string
+}
+
+func (e dotenvEnvironment) Getenv(key string) string {
+	return e.vars[key]
+}
+
+// parseDotenv parses the contents of a .env file. Lines look like KEY=value or export KEY=value, and # starts a comment.
+// Values can be quoted: single quotes are taken literally and double quotes support \n, \t, \" and \\. Both can span multiple lines, for PEM encoded certificates.
+func parseDotenv(s string) (map[string]string, error) {
+	ret := map[string]string{}
+	line := 1
+	for s != "" {
+		var l string
+		l, s, _ = strings.Cut(s, "\n")
+		start := line
+		line++
+		l = strings.TrimSpace(l)
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		l = strings.TrimPrefix(l, "export ")
+		key, val, ok := strings.Cut(l, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t'\"") {
+			return nil, fmt.Errorf("line %d: expected KEY=value", start)
+		}
+		val = strings.TrimLeft(val, " \t")
+		if val != "" && (val[0] == '"' || val[0] == '\'') {
+			// Quoted values can continue on the following lines.
+			q := val[0]
+			val = val[1:]
+			var sb strings.Builder
+			for {
+				end := closingQuote(val, q)
+				if end >= 0 {
+					sb.WriteString(unescapeDotenv(val[:end], q))
+					if rest := strings.TrimSpace(val[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+						return nil, fmt.Errorf("line %d: unexpected %q after the closing quote", line-1, rest)
+					}
+					break
+				}
+				if s == "" {
+					return nil, fmt.Errorf("line %d: missing closing quote for %s", start, key)
+				}
+				sb.WriteString(unescapeDotenv(val, q))
+				sb.WriteByte('\n')
+				val, s, _ = strings.Cut(s, "\n")
+				line++
+			}
+			ret[key] = sb.String()
+			continue
+		}
+		if i := strings.Index(val, " #"); i >= 0 {
+			val = val[:i]
+		}
+		ret[key] = strings.TrimSpace(val)
+	}
+	return ret, nil
+}
+
+// closingQuote returns the index of the quote q that ends s, or -1.
+func closingQuote(s string, q byte) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && q == '"':
+			i++
+		case s[i] == q:
+			return i
+		}
+	}
+	return -1
+}
+
+// unescapeDotenv resolves the escape sequences in the inside of a double quoted value.
+func unescapeDotenv(s string, q byte) string {
+	if q != '"' || !strings.Contains(s, `\`) {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case 'r':
+			sb.WriteByte('\r')
+		case '"', '\\', '$':
+			sb.WriteByte(s[i])
+		default:
+			sb.WriteByte('\\')
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,93 @@
+package clientconfig
+
+import (
+	"sort"
+	"strings"
+)
+
+// DryRunReport is the result of DryRun.
+type DryRunReport struct {
+	// Settings are the effective settings after applying aliases and config sources. Secrets are redacted.
+	Settings Settings
+	// Endpoints the client would connect to.
+	Endpoints []string
+	// TLS is whether the client would use TLS.
+	TLS bool
+	// Username the client would authenticate as, if any.
+	Username string
+	// Warnings that would be logged.
+	Warnings []string
+	// Skipped are the checks and actions with side effects that DryRun doesn't do, like probing the endpoints for ETCD_ENDPOINT_HEALTH_CHECK or generating ETCD_DEV_TLS certificates. Endpoints doesn't reflect their effect.
+	Skipped []string
+}
+
+// DryRun resolves and validates the configuration like Get, but doesn't log warnings. It never connects to etcd, although config sources might and ETCD_DISCOVERY_SRV still does its DNS lookup. ETCD_DEV_TLS doesn't generate certificates; only a CA that's already there is used.
+// It returns what the client would use and all warnings, which makes it suitable for admission webhooks and pre-deploy checks. The report is also returned if validation fails.
+func DryRun(opts ...Option) (*DryRunReport, error) {
+	r := &DryRunReport{Settings: Settings{}}
+	opts = append(opts[:len(opts):len(opts)], func(o *options) {
+		o.warn = func(w string) {
+			r.Warnings = append(r.Warnings, w)
+		}
+	}, withDryRun(func(s string) {
+		r.Skipped = append(r.Skipped, s)
+	}))
+	s, err := ReadSettings(opts...)
+	if err != nil {
+		return r, err
+	}
+	for k, v := range s {
+		if secretVariables[k] {
+			v = "<redacted>"
+		}
+		r.Settings[k] = v
+	}
+	c, err := ApplySettings(Defaults(), s, opts...)
+	r.Endpoints = c.Endpoints
+	r.TLS = c.TLS != nil || usesTLSScheme(c.Endpoints)
+	r.Username = c.Username
+	return r, err
+}
+
+// usesTLSScheme returns whether any of the endpoints explicitly requests TLS.
+func usesTLSScheme(eps []string) bool {
+	for _, ep := range eps {
+		if strings.HasPrefix(ep, "https://") || strings.HasPrefix(ep, "unixs://") {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns a human readable summary.
+func (r *DryRunReport) String() string {
+	var sb strings.Builder
+	var keys []string
+	for k := range r.Settings {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := r.Settings[k]
+		if strings.Contains(v, "\n") {
+			v = "<" + strings.Split(strings.TrimSpace(v), "\n")[0] + " ...>"
+		}
+		sb.WriteString(k + "=" + v + "\n")
+	}
+	sb.WriteString("endpoints: " + strings.Join(r.Endpoints, ",") + "\n")
+	if r.TLS {
+		sb.WriteString("tls: yes\n")
+	} else {
+		sb.WriteString("tls: no\n")
+	}
+	if r.Username != "" {
+		sb.WriteString("username: " + r.Username + "\n")
+	}
+	for _, w := range r.Warnings {
+		sb.WriteString("warning: " + w + "\n")
+	}
+	for _, s := range r.Skipped {
+		sb.WriteString("skipped: " + s + "\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,91 @@
+package clientconfig
+
+import (
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// DumpRedacted returns a one line description of c that's safe to log: endpoints, timeouts, TLS mode and username, but not the password or key material.
+// Use it instead of logging a clientv3.Config with %v, which prints the password.
+func DumpRedacted(c clientv3.Config) string {
+	parts := []string{"endpoints=" + strings.Join(c.Endpoints, ",")}
+	if c.Username != "" {
+		parts = append(parts, "username="+c.Username)
+	}
+	if c.Password != "" {
+		parts = append(parts, "password=<redacted>")
+	}
+	parts = append(parts, "tls="+tlsMode(c))
+	for _, d := range []struct {
+		name string
+		v    time.Duration
+	}{
+		{"dial-timeout", c.DialTimeout},
+		{"dial-keepalive-time", c.DialKeepAliveTime},
+		{"dial-keepalive-timeout", c.DialKeepAliveTimeout},
+		{"auto-sync-interval", c.AutoSyncInterval},
+		{"backoff-wait-between", c.BackoffWaitBetween},
+	} {
+		if d.v != 0 {
+			parts = append(parts, d.name+"="+d.v.String())
+		}
+	}
+	if c.MaxCallSendMsgSize != 0 {
+		parts = append(parts, fmt.Sprintf("max-call-send-msg-size=%d", c.MaxCallSendMsgSize))
+	}
+	if c.MaxCallRecvMsgSize != 0 {
+		parts = append(parts, fmt.Sprintf("max-call-recv-msg-size=%d", c.MaxCallRecvMsgSize))
+	}
+	if c.BackoffJitterFraction != 0 {
+		parts = append(parts, fmt.Sprintf("backoff-jitter-fraction=%g", c.BackoffJitterFraction))
+	}
+	if c.MaxUnaryRetries != 0 {
+		parts = append(parts, fmt.Sprintf("max-unary-retries=%d", c.MaxUnaryRetries))
+	}
+	if c.RejectOldCluster {
+		parts = append(parts, "reject-old-cluster")
+	}
+	if c.PermitWithoutStream {
+		parts = append(parts, "permit-without-stream")
+	}
+	if len(c.DialOptions) > 0 {
+		parts = append(parts, fmt.Sprintf("dial-options=%d", len(c.DialOptions)))
+	}
+	return strings.Join(parts, " ")
+}
+
+// tlsMode describes the TLS settings of c, like "mtls(cn=myapp,ca=custom)".
+func tlsMode(c clientv3.Config) string {
+	if c.TLS == nil {
+		if usesTLSScheme(c.Endpoints) {
+			return "on(ca=system)"
+		}
+		return "off"
+	}
+	var attrs []string
+	if len(c.TLS.Certificates) > 0 && len(c.TLS.Certificates[0].Certificate) > 0 {
+		if crt, err := x509.ParseCertificate(c.TLS.Certificates[0].Certificate[0]); err == nil {
+			attrs = append(attrs, "cn="+crt.Subject.CommonName)
+		}
+	}
+	switch {
+	case c.TLS.InsecureSkipVerify:
+		attrs = append(attrs, "insecure-skip-verify")
+	case c.TLS.RootCAs != nil:
+		attrs = append(attrs, "ca=custom")
+	default:
+		attrs = append(attrs, "ca=system")
+	}
+	if c.TLS.ServerName != "" {
+		attrs = append(attrs, "server-name="+c.TLS.ServerName)
+	}
+	mode := "on"
+	if len(c.TLS.Certificates) > 0 || c.TLS.GetClientCertificate != nil {
+		mode = "mtls"
+	}
+	return mode + "(" + strings.Join(attrs, ",") + ")"
+}
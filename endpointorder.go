@@ -0,0 +1,44 @@
+package clientconfig
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// applyEndpointOrder applies ETCD_ENDPOINT_SHUFFLE and ETCD_ENDPOINT_PREFER to the endpoints of c, so a fleet of clients that restarts at once doesn't all dial the first endpoint in the list.
+func applyEndpointOrder(o *options, c *clientv3.Config, settings Settings) error {
+	if v := settings["ETCD_ENDPOINT_SHUFFLE"]; v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return errorf(CodeInvalidValue, "ETCD_ENDPOINT_SHUFFLE", "failed to parse ETCD_ENDPOINT_SHUFFLE as bool (%q)", v)
+		}
+		if b {
+			eps := append([]string(nil), c.Endpoints...)
+			rand.Shuffle(len(eps), func(i, j int) { eps[i], eps[j] = eps[j], eps[i] })
+			c.Endpoints = eps
+		}
+	}
+	if v := strings.TrimSpace(settings["ETCD_ENDPOINT_PREFER"]); v != "" {
+		for i, ep := range c.Endpoints {
+			if ep == v || stripScheme(ep) == stripScheme(v) {
+				eps := append([]string{ep}, c.Endpoints[:i]...)
+				c.Endpoints = append(eps, c.Endpoints[i+1:]...)
+				return nil
+			}
+		}
+		// The same ETCD_ENDPOINT_PREFER=${NODE_IP}:2379 is typically used everywhere, including nodes without a member.
+		o.warnf("ETCD_ENDPOINT_PREFER %q is not one of the endpoints; ignoring it", v)
+	}
+	return nil
+}
+
+// stripScheme returns ep without its http:// or https:// prefix.
+func stripScheme(ep string) string {
+	if _, hostport, ok := strings.Cut(ep, "://"); ok {
+		return hostport
+	}
+	return ep
+}
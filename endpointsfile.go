@@ -0,0 +1,86 @@
+package clientconfig
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// WriteEndpoints fetches the member list and writes the client URLs of all voting members to path, in the format expected by ETCD_ENDPOINTS_FILE.
+// The file is replaced atomically and left alone if the list didn't change, so other processes (or the next restart) can consume it at any time.
+// Behind a gateway or gRPC proxy (see ETCD_VIA) the member list has the addresses of the members rather than the proxy, so don't use it there.
+func WriteEndpoints(ctx context.Context, cli *clientv3.Client, path string) error {
+	resp, err := cli.MemberList(ctx)
+	if err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	var eps []string
+	for _, m := range resp.Members {
+		if m.IsLearner {
+			continue
+		}
+		for _, u := range m.ClientURLs {
+			if !seen[u] {
+				seen[u] = true
+				eps = append(eps, u)
+			}
+		}
+	}
+	if len(eps) == 0 {
+		return errors.New("member list contains no client URLs")
+	}
+	sort.Strings(eps)
+	return writeFileAtomic(path, []byte(strings.Join(eps, ",")), 0644)
+}
+
+// PersistEndpoints calls WriteEndpoints every interval until ctx is cancelled. Errors are passed to onError, which may be nil.
+func PersistEndpoints(ctx context.Context, cli *clientv3.Client, path string, interval time.Duration, onError func(error)) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if err := WriteEndpoints(ctx, cli, path); err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// writeFileAtomic writes data to a temporary file and renames it over path, unless path already has that content.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if old, err := ioutil.ReadFile(path); err == nil && bytes.Equal(old, data) {
+		return nil
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
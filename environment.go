@@ -0,0 +1,89 @@
+package clientconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Environment is where variables and the files named by _FILE variables are read from. The default is the process environment and filesystem.
+// Pass your own with WithEnvironment on platforms where those aren't available (like some WASI hosts), or to configure a client from somewhere else entirely.
+type Environment interface {
+	Getenv(key string) string
+	ReadFile(name string) ([]byte, error)
+}
+
+// Lookuper is a source of variables, like os.LookupEnv. See ApplyFrom.
+type Lookuper interface {
+	Lookup(key string) (string, bool)
+}
+
+// LookupFunc adapts a function like os.LookupEnv to a Lookuper.
+type LookupFunc func(key string) (string, bool)
+
+func (f LookupFunc) Lookup(key string) (string, bool) {
+	return f(key)
+}
+
+// MapLookuper is a Lookuper backed by a map, like a parsed .env file.
+type MapLookuper map[string]string
+
+func (m MapLookuper) Lookup(key string) (string, bool) {
+	v, ok := m[key]
+	return v, ok
+}
+
+// ApplyFrom is like ApplyWithOptions, but reads the variables from l instead of the process environment. Files named by _FILE variables are still read from the filesystem; use WithEnvironment to change that too.
+func ApplyFrom(c clientv3.Config, l Lookuper, opts ...Option) (clientv3.Config, error) {
+	return ApplyWithOptions(c, append(opts[:len(opts):len(opts)], WithEnvironment(lookuperEnvironment{l}))...)
+}
+
+type lookuperEnvironment struct {
+	Lookuper
+}
+
+func (e lookuperEnvironment) Getenv(key string) string {
+	v, _ := e.Lookup(key)
+	return v
+}
+
+func (lookuperEnvironment) ReadFile(name string) ([]byte, error) {
+	return ioutil.ReadFile(name)
+}
+
+type osEnvironment struct{}
+
+func (osEnvironment) Getenv(key string) string {
+	return os.Getenv(key)
+}
+
+func (osEnvironment) ReadFile(name string) ([]byte, error) {
+	return ioutil.ReadFile(name)
+}
+
+// prefixedEnvironment reads variables with a prefix, like MYAPP_ETCD_ENDPOINTS.
+type prefixedEnvironment struct {
+	Environment
+	prefix string
+}
+
+func (e prefixedEnvironment) Getenv(key string) string {
+	return e.Environment.Getenv(e.prefix + key)
+}
+
+// expandingEnvironment expands $VAR and ${VAR} in values from the unprefixed environment, like ETCD_ENDPOINTS=https://${NODE_NAME}:2379.
+// Secrets are more likely to contain a literal $ than to need expansion, and commands are expanded by the shell.
+type expandingEnvironment struct {
+	Environment
+	base Environment
+}
+
+func (e expandingEnvironment) Getenv(key string) string {
+	v := e.Environment.Getenv(key)
+	if secretVariables[key] || strings.HasSuffix(key, "_COMMAND") || key == "ETCD_EXPAND_VARIABLES" || !strings.Contains(v, "$") {
+		return v
+	}
+	return os.Expand(v, e.base.Getenv)
+}
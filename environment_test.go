@@ -0,0 +1,103 @@
+package clientconfig
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestApplyFrom(t *testing.T) {
+	// ApplyFrom must ignore the process environment.
+	t.Setenv("ETCD_ENDPOINTS", "http://from-the-environment:2379")
+	t.Setenv("ETCD_USERNAME", "mallory")
+	t.Setenv("ETCD_PASSWORD", "from-the-environment")
+
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(passwordFile, []byte("from-a-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		lookuper      Lookuper
+		opts          []Option
+		wantEndpoints []string
+		wantUsername  string
+		wantPassword  string
+		wantErr       string
+	}{
+		{
+			name:          "MapLookuper",
+			lookuper:      MapLookuper{"ETCD_ENDPOINTS": "http://etcd1:2379,http://etcd2:2379", "ETCD_USERNAME": "alice", "ETCD_PASSWORD": "secret"},
+			wantEndpoints: []string{"http://etcd1:2379", "http://etcd2:2379"},
+			wantUsername:  "alice",
+			wantPassword:  "secret",
+		},
+		{
+			name: "LookupFunc",
+			lookuper: LookupFunc(func(key string) (string, bool) {
+				if key == "ETCD_ENDPOINTS" {
+					return "http://etcd1:2379", true
+				}
+				return "", false
+			}),
+			wantEndpoints: []string{"http://etcd1:2379"},
+		},
+		{
+			name:          "_FILE variables are read from the filesystem",
+			lookuper:      MapLookuper{"ETCD_ENDPOINTS": "http://etcd1:2379", "ETCD_USERNAME": "alice", "ETCD_PASSWORD_FILE": passwordFile},
+			wantEndpoints: []string{"http://etcd1:2379"},
+			wantUsername:  "alice",
+			wantPassword:  "from-a-file",
+		},
+		{
+			name:          "with a prefix",
+			lookuper:      MapLookuper{"MYAPP_ETCD_ENDPOINTS": "http://etcd1:2379", "ETCD_ENDPOINTS": "http://wrong:2379"},
+			opts:          []Option{WithPrefix("MYAPP_")},
+			wantEndpoints: []string{"http://etcd1:2379"},
+		},
+		{
+			name:     "missing _FILE",
+			lookuper: MapLookuper{"ETCD_ENDPOINTS": "http://etcd1:2379", "ETCD_USERNAME": "alice", "ETCD_PASSWORD_FILE": filepath.Join(t.TempDir(), "nonexistent")},
+			wantErr:  "ETCD_PASSWORD_FILE",
+		},
+		{
+			name:     "strict mode lists a MapLookuper",
+			lookuper: MapLookuper{"ETCD_ENDPOINTS": "http://etcd1:2379", "ETCD_ENPOINTS": "http://etcd2:2379"},
+			opts:     []Option{WithStrict()},
+			wantErr:  "ETCD_ENPOINTS",
+		},
+		{
+			name:     "ETCD_USERNAME_AND_PASSWORD without a colon",
+			lookuper: MapLookuper{"ETCD_ENDPOINTS": "http://etcd1:2379", "ETCD_USERNAME_AND_PASSWORD": "alice"},
+			wantErr:  "should be separated with a colon",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := ApplyFrom(clientv3.Config{}, tc.lookuper, tc.opts...)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("ApplyFrom() = %v; want an error containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyFrom: %v", err)
+			}
+			if !reflect.DeepEqual(c.Endpoints, tc.wantEndpoints) {
+				t.Errorf("Endpoints = %q, want %q", c.Endpoints, tc.wantEndpoints)
+			}
+			if c.Username != tc.wantUsername {
+				t.Errorf("Username = %q, want %q", c.Username, tc.wantUsername)
+			}
+			if c.Password != tc.wantPassword {
+				t.Errorf("Password = %q, want %q", c.Password, tc.wantPassword)
+			}
+		})
+	}
+}
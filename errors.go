@@ -0,0 +1,81 @@
+package clientconfig
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error codes for configuration problems. They are stable, so log pipelines and support tooling can classify failures without parsing the message.
+const (
+	// CodeInvalidValue means a variable couldn't be parsed.
+	CodeInvalidValue = "ETCDCFG-0001"
+	// CodeUnreadableFile means the file named by a _FILE variable couldn't be read.
+	CodeUnreadableFile = "ETCDCFG-0002"
+	// CodeConflictingFile means both a variable and its _FILE variant are set.
+	CodeConflictingFile = "ETCDCFG-0003"
+	// CodeConflictingVariables means two variables that exclude each other are both set, or one requires another that's missing.
+	CodeConflictingVariables = "ETCDCFG-0004"
+	// CodeInvalidCertificate means a certificate or key couldn't be parsed.
+	CodeInvalidCertificate = "ETCDCFG-0005"
+	// CodeUnknownName means ETCD_DIAL_OPTIONS, ETCD_CONFIG_SOURCES or a _SOURCE or _KEYRING variable contains a name that wasn't registered.
+	CodeUnknownName = "ETCDCFG-0006"
+	// CodeRegisteredFailure means a registered dial option, config source, resolver or variable returned an error.
+	CodeRegisteredFailure = "ETCDCFG-0007"
+	// CodeTLSRequired means the binary was built to require TLS, but the configuration doesn't use it.
+	CodeTLSRequired = "ETCDCFG-0008"
+	// CodeNetworkForbidden means offline resolution is enabled, but the configuration needs the network.
+	CodeNetworkForbidden = "ETCDCFG-0009"
+	// CodeInconsistentTLS means the endpoints don't agree with each other or with the TLS settings on whether to use TLS.
+	CodeInconsistentTLS = "ETCDCFG-0010"
+	// CodeIdentityMismatch means the client certificate isn't for ETCD_EXPECTED_IDENTITY.
+	CodeIdentityMismatch = "ETCDCFG-0011"
+	// CodeDevEtcdFailed means ETCD_DEV_AUTOSTART couldn't start a local etcd.
+	CodeDevEtcdFailed = "ETCDCFG-0012"
+	// CodeDiscoveryFailed means the SRV records for ETCD_DISCOVERY_SRV couldn't be resolved.
+	CodeDiscoveryFailed = "ETCDCFG-0013"
+	// CodeCommandFailed means the command in a _COMMAND variable failed or printed nothing.
+	CodeCommandFailed = "ETCDCFG-0014"
+	// CodeUnknownVariable means strict mode found ETCD_* variables we don't know, probably typos.
+	CodeUnknownVariable = "ETCDCFG-0015"
+	// CodeNotConfigured means no endpoints are configured at all (as opposed to badly), like when none of the variables are set.
+	CodeNotConfigured = "ETCDCFG-0016"
+	// CodeAuthRequired means ETCD_REQUIRE=auth (or RequireAuth) is set, but no credentials are configured.
+	CodeAuthRequired = "ETCDCFG-0017"
+	// CodeUnreachable means ETCD_ENDPOINT_HEALTH_CHECK is set, but none of the endpoints could be connected to.
+	CodeUnreachable = "ETCDCFG-0018"
+)
+
+// Error is the type of errors returned for configuration problems. Use errors.As to get the Code.
+type Error struct {
+	// Code is one of the Code constants.
+	Code string
+	// Variable is the variable the problem is about, if any.
+	Variable string
+
+	msg string
+	err error
+}
+
+func (e *Error) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the underlying error, if any. For example, errors.Is(err, fs.ErrNotExist) reports whether a _FILE variable points at a file that doesn't exist.
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// errorf returns an *Error. If the last argument is an error, it becomes the underlying error.
+func errorf(code, variable, format string, args ...interface{}) error {
+	e := &Error{Code: code, Variable: variable, msg: fmt.Sprintf(format, args...)}
+	if len(args) > 0 {
+		e.err, _ = args[len(args)-1].(error)
+	}
+	return e
+}
+
+// IsNotConfigured returns whether err means etcd wasn't configured at all, rather than configured badly. Applications with optional etcd integration can use it to skip etcd.
+func IsNotConfigured(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Code == CodeNotConfigured
+}
@@ -0,0 +1,118 @@
+package clientconfig
+
+import (
+	"strings"
+)
+
+// ParseEtcdctlArgs translates the connection flags of an etcdctl command line (--endpoints, --discovery-srv, --discovery-srv-name, --cacert, --cert, --key, --user, --password and --insecure-skip-tls-verify) into Settings, so runbooks written around etcdctl can be turned into environment variables or passed to ApplySettings.
+// Files named by --cacert, --cert and --key are read. Other flags and arguments (like "get foo") are ignored. Both "--flag=value" and "--flag value" are understood.
+func ParseEtcdctlArgs(args []string) (Settings, error) {
+	return parseEtcdctlArgs(osEnvironment{}, args)
+}
+
+func parseEtcdctlArgs(env Environment, args []string) (Settings, error) {
+	files := map[string]string{
+		"cacert": "ETCD_SERVER_CA",
+		"cert":   "ETCD_CLIENT_CERT",
+		"key":    "ETCD_CLIENT_KEY",
+	}
+	values := map[string]string{
+		"endpoints":          "ETCD_ENDPOINTS",
+		"password":           "ETCD_PASSWORD",
+		"discovery-srv":      "ETCD_DISCOVERY_SRV",
+		"discovery-srv-name": "ETCD_DISCOVERY_SRV_NAME",
+	}
+	s := Settings{}
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if !strings.HasPrefix(a, "-") {
+			continue
+		}
+		name := strings.TrimLeft(a, "-")
+		value, hasValue := "", false
+		if j := strings.Index(name, "="); j != -1 {
+			name, value, hasValue = name[:j], name[j+1:], true
+		}
+		takeValue := func() string {
+			if !hasValue && i+1 < len(args) {
+				i++
+				return args[i]
+			}
+			return value
+		}
+		switch {
+		case name == "insecure-skip-tls-verify":
+			if !hasValue {
+				value = "true"
+			}
+			s["ETCD_INSECURE_SKIP_VERIFY"] = value
+		case name == "user":
+			v := takeValue()
+			if strings.Contains(v, ":") {
+				s["ETCD_USERNAME_AND_PASSWORD"] = v
+			} else {
+				s["ETCD_USERNAME"] = v
+			}
+		case values[name] != "":
+			s[values[name]] = takeValue()
+		case files[name] != "":
+			k := files[name]
+			fn := takeValue()
+			b, err := env.ReadFile(fn)
+			if err != nil {
+				return nil, errorf(CodeUnreadableFile, k, "error reading %q (for --%s): %v", fn, name, err)
+			}
+			s[k] = string(b)
+		}
+	}
+	if s["ETCD_USERNAME_AND_PASSWORD"] != "" && s["ETCD_PASSWORD"] != "" {
+		return nil, errorf(CodeConflictingVariables, "ETCD_PASSWORD", "you can't pass both --user with a password and --password")
+	}
+	return s, nil
+}
+
+// etcdctlVariables are the ETCDCTL_* variables WithEtcdctlCompat reads. They're translated to the flag with the same name.
+var etcdctlVariables = []string{"ETCDCTL_ENDPOINTS", "ETCDCTL_DISCOVERY_SRV", "ETCDCTL_DISCOVERY_SRV_NAME", "ETCDCTL_CACERT", "ETCDCTL_CERT", "ETCDCTL_KEY", "ETCDCTL_USER", "ETCDCTL_PASSWORD", "ETCDCTL_INSECURE_SKIP_TLS_VERIFY"}
+
+// etcdctlGroups are settings that belong together. ETCDCTL_* variables only fill a group if none of its ETCD_* variables are set, so we don't mix for example ETCD_USERNAME with ETCDCTL_PASSWORD.
+var etcdctlGroups = [][]string{
+	{"ETCD_ENDPOINTS", "ETCD_GATEWAY_ENDPOINT", "ETCD_DISCOVERY_SRV", "ETCD_DISCOVERY_SRV_NAME"},
+	{"ETCD_USERNAME", "ETCD_PASSWORD", "ETCD_USERNAME_AND_PASSWORD"},
+	{"ETCD_CLIENT_CERT", "ETCD_CLIENT_KEY", "ETCD_CLIENT_CERT_AND_KEY"},
+	{"ETCD_SERVER_CA"},
+	{"ETCD_INSECURE_SKIP_VERIFY"},
+}
+
+// readEtcdctlVariables adds the settings from ETCDCTL_* variables that aren't already set.
+func readEtcdctlVariables(o *options, settings Settings) error {
+	var args []string
+	for _, k := range etcdctlVariables {
+		if v := o.env.Getenv(k); v != "" {
+			args = append(args, "--"+strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(k, "ETCDCTL_"), "_", "-"))+"="+v)
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	s, err := parseEtcdctlArgs(o.env, args)
+	if err != nil {
+		return err
+	}
+	for _, g := range etcdctlGroups {
+		set := false
+		for _, k := range g {
+			if settings[k] != "" {
+				set = true
+			}
+		}
+		if set {
+			continue
+		}
+		for _, k := range g {
+			if s[k] != "" {
+				settings[k] = s[k]
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package clientconfig
+
+import (
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEtcdctlArgs(t *testing.T) {
+	env := testFileEnvironment{
+		"/etc/etcd/ca.crt":  "ca",
+		"/etc/etcd/cli.crt": "cert",
+		"/etc/etcd/cli.key": "key",
+	}
+	tests := []struct {
+		name    string
+		args    []string
+		want    Settings
+		wantErr string
+	}{
+		{
+			name: "flag=value",
+			args: []string{"--endpoints=https://etcd1:2379,https://etcd2:2379", "--user=alice", "--password=secret"},
+			want: Settings{"ETCD_ENDPOINTS": "https://etcd1:2379,https://etcd2:2379", "ETCD_USERNAME": "alice", "ETCD_PASSWORD": "secret"},
+		},
+		{
+			name: "flag value",
+			args: []string{"--endpoints", "https://etcd1:2379", "--user", "alice", "--password", "secret"},
+			want: Settings{"ETCD_ENDPOINTS": "https://etcd1:2379", "ETCD_USERNAME": "alice", "ETCD_PASSWORD": "secret"},
+		},
+		{
+			name: "single dash",
+			args: []string{"-endpoints=https://etcd1:2379"},
+			want: Settings{"ETCD_ENDPOINTS": "https://etcd1:2379"},
+		},
+		{
+			name: "--user with a password",
+			args: []string{"--user=alice:se:cret"},
+			want: Settings{"ETCD_USERNAME_AND_PASSWORD": "alice:se:cret"},
+		},
+		{
+			name: "files are read",
+			args: []string{"--cacert=/etc/etcd/ca.crt", "--cert", "/etc/etcd/cli.crt", "--key=/etc/etcd/cli.key"},
+			want: Settings{"ETCD_SERVER_CA": "ca", "ETCD_CLIENT_CERT": "cert", "ETCD_CLIENT_KEY": "key"},
+		},
+		{
+			name: "discovery",
+			args: []string{"--discovery-srv=example.com", "--discovery-srv-name", "prod"},
+			want: Settings{"ETCD_DISCOVERY_SRV": "example.com", "ETCD_DISCOVERY_SRV_NAME": "prod"},
+		},
+		{
+			name: "--insecure-skip-tls-verify without a value",
+			args: []string{"--insecure-skip-tls-verify", "get", "foo"},
+			want: Settings{"ETCD_INSECURE_SKIP_VERIFY": "true"},
+		},
+		{
+			name: "--insecure-skip-tls-verify=false",
+			args: []string{"--insecure-skip-tls-verify=false"},
+			want: Settings{"ETCD_INSECURE_SKIP_VERIFY": "false"},
+		},
+		{
+			name: "other flags and arguments are ignored",
+			args: []string{"etcdctl", "--write-out=json", "get", "--prefix", "/foo", "--endpoints=http://localhost:2379"},
+			want: Settings{"ETCD_ENDPOINTS": "http://localhost:2379"},
+		},
+		{
+			name: "the last occurrence wins",
+			args: []string{"--endpoints=http://a:2379", "--endpoints=http://b:2379"},
+			want: Settings{"ETCD_ENDPOINTS": "http://b:2379"},
+		},
+		{
+			name: "nothing",
+			args: nil,
+			want: Settings{},
+		},
+		{
+			name:    "unreadable file",
+			args:    []string{"--cacert=/nonexistent/ca.crt"},
+			wantErr: `error reading "/nonexistent/ca.crt" (for --cacert)`,
+		},
+		{
+			name:    "--user with a password and --password",
+			args:    []string{"--user=alice:secret", "--password=other"},
+			wantErr: "you can't pass both --user with a password and --password",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseEtcdctlArgs(env, tc.args)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("parseEtcdctlArgs(%q) = %v, %v; want an error containing %q", tc.args, got, err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseEtcdctlArgs(%q): %v", tc.args, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("parseEtcdctlArgs(%q) = %v, want %v", tc.args, got, tc.want)
+			}
+		})
+	}
+}
+
+// testFileEnvironment has no variables, and only the files in the map.
+type testFileEnvironment map[string]string
+
+func (testFileEnvironment) Getenv(string) string { return "" }
+
+func (e testFileEnvironment) ReadFile(name string) ([]byte, error) {
+	if v, ok := e[name]; ok {
+		return []byte(v), nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
@@ -0,0 +1,177 @@
+package clientconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Export turns c back into the ETCD_* variables that Apply understands, so a subprocess (like a migration job or etcdctl through etcd-env) can be handed exactly the configuration the parent resolved. Certificates and keys are passed inline; use ExportFiles to write them to files.
+// Dial options aren't exported, as there are no variables for them. A CertPool can't be turned back into PEM, so RootCAs are exported from ETCD_SERVER_CA (read again with opts), and only if that still gives the same pool.
+func Export(c clientv3.Config, opts ...Option) (map[string]string, error) {
+	return export(c, "", opts)
+}
+
+// ExportFiles is like Export, but writes the CA, client certificate and key to dir (ca.crt, client.crt and client.key, the key with mode 0600) and returns the _FILE variables pointing at them.
+func ExportFiles(c clientv3.Config, dir string, opts ...Option) (map[string]string, error) {
+	if dir == "" {
+		return nil, errors.New("ExportFiles needs a directory")
+	}
+	return export(c, dir, opts)
+}
+
+func export(c clientv3.Config, dir string, opts []Option) (map[string]string, error) {
+	ret := map[string]string{}
+	if len(c.Endpoints) > 0 {
+		ret["ETCD_ENDPOINTS"] = strings.Join(c.Endpoints, ",")
+	}
+	if c.Username != "" {
+		ret["ETCD_USERNAME"] = c.Username
+	}
+	if c.Password != "" {
+		ret["ETCD_PASSWORD"] = c.Password
+	}
+	// Always exported, because zero (disabled) is not the default.
+	ret["ETCD_AUTO_SYNC_INTERVAL"] = c.AutoSyncInterval.String()
+	for k, d := range map[string]time.Duration{"ETCD_DIAL_TIMEOUT": c.DialTimeout, "ETCD_DIAL_KEEPALIVE_TIME": c.DialKeepAliveTime, "ETCD_DIAL_KEEPALIVE_TIMEOUT": c.DialKeepAliveTimeout, "ETCD_BACKOFF_WAIT_BETWEEN": c.BackoffWaitBetween} {
+		if d != 0 {
+			ret[k] = d.String()
+		}
+	}
+	for k, n := range map[string]int{"ETCD_MAX_CALL_SEND_MSG_SIZE": c.MaxCallSendMsgSize, "ETCD_MAX_CALL_RECV_MSG_SIZE": c.MaxCallRecvMsgSize} {
+		if n != 0 {
+			ret[k] = strconv.Itoa(n)
+		}
+	}
+	if c.BackoffJitterFraction != 0 {
+		ret["ETCD_BACKOFF_JITTER_FRACTION"] = strconv.FormatFloat(c.BackoffJitterFraction, 'g', -1, 64)
+	}
+	if c.MaxUnaryRetries != 0 {
+		ret["ETCD_MAX_UNARY_RETRIES"] = strconv.FormatUint(uint64(c.MaxUnaryRetries), 10)
+	}
+	if c.RejectOldCluster {
+		ret["ETCD_REJECT_OLD_CLUSTER"] = "true"
+	}
+	if c.PermitWithoutStream {
+		ret["ETCD_PERMIT_WITHOUT_STREAM"] = "true"
+	}
+	put := func(k, name, value string, perm os.FileMode) error {
+		if dir == "" {
+			ret[k] = value
+			return nil
+		}
+		fn := filepath.Join(dir, name)
+		if err := writeFileAtomic(fn, []byte(value), perm); err != nil {
+			return err
+		}
+		ret[k+"_FILE"] = fn
+		return nil
+	}
+	if c.TLS == nil {
+		return ret, nil
+	}
+	t := c.TLS
+	if t.InsecureSkipVerify {
+		ret["ETCD_INSECURE_SKIP_VERIFY"] = "true"
+	}
+	if t.RootCAs != nil {
+		ca, appendSystem, err := exportRootCAs(t.RootCAs, opts)
+		if err != nil {
+			return nil, err
+		}
+		if err := put("ETCD_SERVER_CA", "ca.crt", ca, 0644); err != nil {
+			return nil, err
+		}
+		if appendSystem {
+			ret["ETCD_SERVER_CA_APPEND_SYSTEM"] = "true"
+		}
+	}
+	crt, err := currentClientCertificate(t)
+	if err != nil {
+		return nil, err
+	}
+	if crt != nil {
+		var certPEM []byte
+		for _, der := range crt.Certificate {
+			certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+		}
+		keyDER, err := x509.MarshalPKCS8PrivateKey(crt.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("the client key can't be exported (is it in a hardware token?): %v", err)
+		}
+		if err := put("ETCD_CLIENT_CERT", "client.crt", string(certPEM), 0644); err != nil {
+			return nil, err
+		}
+		if err := put("ETCD_CLIENT_KEY", "client.key", string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})), 0600); err != nil {
+			return nil, err
+		}
+	}
+	if t.ServerName != "" {
+		ret["ETCD_TLS_SERVER_NAME"] = t.ServerName
+	}
+	for k, ver := range map[string]uint16{"ETCD_TLS_MIN_VERSION": t.MinVersion, "ETCD_TLS_MAX_VERSION": t.MaxVersion} {
+		for n, v := range tlsVersions {
+			if v == ver {
+				ret[k] = n
+			}
+		}
+	}
+	if len(t.CipherSuites) > 0 {
+		var names []string
+		for _, id := range t.CipherSuites {
+			names = append(names, tls.CipherSuiteName(id))
+		}
+		ret["ETCD_TLS_CIPHER_SUITES"] = strings.Join(names, ",")
+	}
+	return ret, nil
+}
+
+// currentClientCertificate returns the client certificate of t, asking GetClientCertificate for the current one if it's set.
+func currentClientCertificate(t *tls.Config) (*tls.Certificate, error) {
+	if t.GetClientCertificate != nil {
+		crt, err := t.GetClientCertificate(&tls.CertificateRequestInfo{})
+		if err != nil || len(crt.Certificate) == 0 {
+			return nil, err
+		}
+		return crt, nil
+	}
+	if len(t.Certificates) > 0 {
+		return &t.Certificates[0], nil
+	}
+	return nil, nil
+}
+
+// exportRootCAs returns the PEM (and ETCD_SERVER_CA_APPEND_SYSTEM) that pool was built from, by reading ETCD_SERVER_CA (or the ETCD_DEV_TLS CA) again and checking that it gives an equal pool.
+func exportRootCAs(pool *x509.CertPool, opts []Option) (string, bool, error) {
+	s, err := ReadSettings(opts...)
+	if err != nil {
+		return "", false, err
+	}
+	v := s["ETCD_SERVER_CA"]
+	if b, _ := strconv.ParseBool(s["ETCD_DEV_TLS"]); b && v == "" {
+		if ca, err := os.ReadFile(filepath.Join(devTLSDir(s), "ca.crt")); err == nil {
+			v = string(ca)
+		}
+	}
+	if v, err = serverCAPEM(v); err != nil {
+		return "", false, err
+	}
+	appendSystem, _ := strconv.ParseBool(s["ETCD_SERVER_CA_APPEND_SYSTEM"])
+	candidate, err := newRootPool(appendSystem)
+	if err != nil {
+		return "", false, err
+	}
+	if v == "" || !candidate.AppendCertsFromPEM([]byte(v)) || !candidate.Equal(pool) {
+		return "", false, errors.New("can't export RootCAs that don't match the current ETCD_SERVER_CA (they weren't set up by clientconfig, or the CA changed since)")
+	}
+	return v, appendSystem, nil
+}
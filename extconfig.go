@@ -0,0 +1,89 @@
+package clientconfig
+
+import (
+	"sort"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Config is a clientv3.Config together with the settings of this package that a clientv3.Config can't hold. Get it with FromEnv.
+type Config struct {
+	clientv3.Config
+
+	// Namespace is the key prefix the client should be confined to (ETCD_NAMESPACE, or the one set at build time). New applies it.
+	Namespace string
+	// ConnectTimeout is how long Connect and Dial wait for etcd to answer (ETCD_CONNECT_TIMEOUT).
+	ConnectTimeout time.Duration
+	// RequestTimeout is the deadline KV calls without one get (ETCD_REQUEST_TIMEOUT), or zero. New applies it.
+	RequestTimeout time.Duration
+	// Require lists what had to be configured, from ETCD_REQUIRE, ETCD_PROFILE and options like RequireTLS.
+	Require []string
+	// TLSReloadInterval is how often certificate files are checked for changes (ETCD_TLS_RELOAD_INTERVAL). Zero means for every connection.
+	TLSReloadInterval time.Duration
+	// Via is what the endpoints are: "direct", "gateway" or "grpc-proxy" (ETCD_VIA).
+	Via string
+	// Proxy is the proxy (ETCD_PROXY) or SSH jump host (ETCD_SSH_JUMP_HOST) connections go through, if any. ETCD_PROXY can contain a password.
+	Proxy string
+	// Sources says for each variable that was set where its value came from, like "env", "file", "command" or "keyring".
+	Sources map[string]string
+	// Settings are the variables Config was built from, for passing to ApplySettings or Export.
+	Settings Settings
+}
+
+// FromEnv reads the configuration like Apply(Defaults()), and returns it with the settings that don't fit in a clientv3.Config.
+func FromEnv(opts ...Option) (Config, error) {
+	o := newOptions(opts)
+	s, err := ReadSettings(opts...)
+	if err != nil {
+		return Config{}, err
+	}
+	c := Config{Settings: s, Sources: map[string]string{}}
+	c.Config, err = ApplySettings(Defaults(), s, opts...)
+	if err != nil {
+		return c, err
+	}
+	c.Namespace = namespaceFromSettings(s)
+	// ApplySettings already validated these.
+	c.ConnectTimeout, _ = connectTimeout(s)
+	c.RequestTimeout, _ = requestTimeout(s)
+	c.TLSReloadInterval, _ = time.ParseDuration(s["ETCD_TLS_RELOAD_INTERVAL"])
+	required, _ := parseRequire(o, s)
+	for r := range required {
+		c.Require = append(c.Require, r)
+	}
+	sort.Strings(c.Require)
+	c.Via = s["ETCD_VIA"]
+	if c.Via == "" {
+		c.Via = "direct"
+		if s["ETCD_GATEWAY_ENDPOINT"] != "" {
+			c.Via = "gateway"
+		}
+	}
+	c.Proxy = s["ETCD_PROXY"]
+	if c.Proxy == "" {
+		c.Proxy = s["ETCD_SSH_JUMP_HOST"]
+	}
+	for k, v := range s {
+		if v != "" {
+			c.Sources[k], _ = variableSource(o, k)
+		}
+	}
+	return c, nil
+}
+
+// ToClientV3 returns the clientv3.Config, for passing to clientv3.New. Remember to apply Namespace and RequestTimeout, or use New.
+func (c Config) ToClientV3() clientv3.Config {
+	return c.Config
+}
+
+// New creates a client from c, confined to c.Namespace and with c.RequestTimeout. Unlike Connect, it doesn't wait for etcd to answer.
+func (c Config) New() (*clientv3.Client, error) {
+	cli, err := clientv3.New(c.Config)
+	if err != nil {
+		return nil, err
+	}
+	wrapNamespace(cli, c.Namespace)
+	wrapRequestTimeout(cli, c.RequestTimeout)
+	return cli, nil
+}
@@ -0,0 +1,80 @@
+package clientconfig
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"sort"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// fingerprintKey keys the hash of Fingerprint, so a fingerprint that ends up in a log can't be used to guess a weak password offline.
+var fingerprintKey = func() []byte {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return b
+}()
+
+// Fingerprint returns a hash of the effective settings of c, including the password, CAs and the current client certificate, to cheaply tell whether two configs would connect the same way.
+// Fingerprints are keyed with a random key, so they're only comparable within a process. DialOptions can't be compared, so only their number counts: settings that only end up in DialOptions (like ETCD_AUTH_TOKEN, which is reloaded by the client itself) aren't covered.
+// CAs are compared by their subjects (apart from the system roots), so a CA that's replaced by one with the same name doesn't change it. The order of the endpoints doesn't matter (so ETCD_ENDPOINT_SHUFFLE doesn't change it), and neither do the certificates of ETCD_DEV_TLS, which are new every time.
+func Fingerprint(c clientv3.Config) string {
+	h := hmac.New(sha256.New, fingerprintKey)
+	d := c
+	d.Endpoints = append([]string(nil), c.Endpoints...)
+	sort.Strings(d.Endpoints)
+	devTLS := c.TLS != nil && c.TLS.RootCAs != nil && isDevTLSPool(c.TLS.RootCAs)
+	if devTLS {
+		d.TLS = c.TLS.Clone()
+		d.TLS.Certificates = nil
+		d.TLS.GetClientCertificate = nil
+	}
+	writeField(h, DumpRedacted(d))
+	writeField(h, c.Password)
+	if t := c.TLS; t != nil {
+		writeField(h, fmt.Sprintf("%t %q %d %d %v", t.InsecureSkipVerify, t.ServerName, t.MinVersion, t.MaxVersion, t.CipherSuites))
+		if devTLS {
+			writeField(h, "dev-tls")
+			return hex.EncodeToString(h.Sum(nil))
+		}
+		if t.RootCAs != nil {
+			// A CertPool can't be turned back into certificates, so the CAs are compared by subject.
+			for _, s := range t.RootCAs.Subjects() {
+				writeField(h, string(s))
+			}
+		}
+		if crt, err := currentClientCertificate(t); err == nil && crt != nil {
+			for _, der := range crt.Certificate {
+				writeField(h, string(der))
+			}
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeField writes s length-prefixed, so fields can't run into each other.
+func writeField(h hash.Hash, s string) {
+	fmt.Fprintf(h, "%d:%s", len(s), s)
+}
+
+// Changed reads the environment and files again and returns whether the resulting config (like ApplyWithOptions(Defaults(), opts...)) has a different Fingerprint than prev.
+// It doesn't do anything ApplyWithOptions does besides reading the configuration: it doesn't probe for ETCD_ENDPOINT_HEALTH_CHECK, start ETCD_DEV_AUTOSTART or write ETCD_DEV_TLS certificates, and it uses the endpoints of the last ETCD_DISCOVERY_SRV lookup. So if ETCD_ENDPOINT_HEALTH_CHECK dropped endpoints from prev, that's reported as a change.
+func Changed(prev string, opts ...Option) (bool, error) {
+	s, err := ReadSettings(opts...)
+	if err != nil {
+		return false, err
+	}
+	c, err := ApplySettings(Defaults(), s, append(opts[:len(opts):len(opts)], withDryRun(nil), func(o *options) {
+		o.fingerprinting = true
+	})...)
+	if err != nil {
+		return false, err
+	}
+	return Fingerprint(c) != prev, nil
+}
@@ -0,0 +1,96 @@
+package clientconfig
+
+import "strings"
+
+// FlagSet is implemented by *flag.FlagSet and by *pflag.FlagSet from github.com/spf13/pflag.
+type FlagSet interface {
+	StringVar(p *string, name, value, usage string)
+	BoolVar(p *bool, name string, value bool, usage string)
+}
+
+// flagVariables are the flags RegisterFlags defines and the variables they set. Like etcdctl, certificates and keys are passed as filenames.
+var flagVariables = []struct {
+	name     string
+	variable string
+	usage    string
+	// hides are variables that can't be combined with this one, which are ignored when the flag is given.
+	hides []string
+}{
+	{"etcd-endpoints", "ETCD_ENDPOINTS", "Comma separated list of etcd endpoints", []string{"ETCD_GATEWAY_ENDPOINT", "ETCD_DISCOVERY_SRV", "ETCD_DISCOVERY_SRV_NAME"}},
+	{"etcd-discovery-srv", "ETCD_DISCOVERY_SRV", "Domain to discover the etcd endpoints from with SRV records", []string{"ETCD_ENDPOINTS", "ETCD_GATEWAY_ENDPOINT"}},
+	{"etcd-gateway-endpoint", "ETCD_GATEWAY_ENDPOINT", "Address of an etcd gateway", []string{"ETCD_ENDPOINTS", "ETCD_DISCOVERY_SRV", "ETCD_DISCOVERY_SRV_NAME"}},
+	{"etcd-username", "ETCD_USERNAME", "Username for etcd authentication", []string{"ETCD_USERNAME_AND_PASSWORD"}},
+	{"etcd-password-file", "ETCD_PASSWORD_FILE", "File with the password for etcd authentication", []string{"ETCD_USERNAME_AND_PASSWORD"}},
+	{"etcd-cacert", "ETCD_SERVER_CA_FILE", "File with the CA certificate(s) to verify etcd with", nil},
+	{"etcd-cert", "ETCD_CLIENT_CERT_FILE", "File with the client certificate for etcd", []string{"ETCD_CLIENT_CERT_AND_KEY"}},
+	{"etcd-key", "ETCD_CLIENT_KEY_FILE", "File with the client key for etcd", []string{"ETCD_CLIENT_CERT_AND_KEY"}},
+	{"etcd-namespace", "ETCD_NAMESPACE", "Key prefix to confine the client to", nil},
+	{"etcd-dial-timeout", "ETCD_DIAL_TIMEOUT", "Timeout for establishing a connection to etcd", nil},
+}
+
+// Flags are the command line flags defined by RegisterFlags. Pass them to WithFlags after parsing.
+type Flags struct {
+	values       map[string]*string
+	insecureSkip bool
+}
+
+// RegisterFlags defines --etcd-endpoints, --etcd-username, --etcd-password-file, --etcd-cacert, --etcd-cert, --etcd-key, --etcd-insecure-skip-tls-verify and a few others on fs, for CLI tools that want flags next to the environment variables.
+// Flags that are given take precedence over the variables they set (and their _FILE and other variants).
+func RegisterFlags(fs FlagSet) *Flags {
+	f := &Flags{values: map[string]*string{}}
+	for _, fv := range flagVariables {
+		p := new(string)
+		fs.StringVar(p, fv.name, "", fv.usage+" (overrides "+fv.variable+")")
+		f.values[fv.variable] = p
+	}
+	fs.BoolVar(&f.insecureSkip, "etcd-insecure-skip-tls-verify", false, "Don't verify etcd's certificate (overrides ETCD_INSECURE_SKIP_VERIFY)")
+	return f
+}
+
+// WithFlags makes the flags registered by RegisterFlags take precedence over the environment.
+func WithFlags(f *Flags) Option {
+	return func(o *options) {
+		o.flags = f
+	}
+}
+
+// set returns the variables set by flags, and the variables they hide.
+func (f *Flags) set() (map[string]string, map[string]bool) {
+	ret := map[string]string{}
+	hidden := map[string]bool{}
+	for _, fv := range flagVariables {
+		if p := f.values[fv.variable]; *p != "" {
+			ret[fv.variable] = *p
+			hidden[strings.TrimSuffix(fv.variable, "_FILE")] = true
+			for _, h := range fv.hides {
+				hidden[h] = true
+			}
+		}
+	}
+	if f.insecureSkip {
+		ret["ETCD_INSECURE_SKIP_VERIFY"] = "true"
+		hidden["ETCD_INSECURE_SKIP_VERIFY"] = true
+	}
+	return ret, hidden
+}
+
+// flagEnvironment returns the values of flags, hiding all other ways to set the variables they cover.
+type flagEnvironment struct {
+	Environment
+	flags *Flags
+}
+
+func (e flagEnvironment) Getenv(key string) string {
+	set, hidden := e.flags.set()
+	if v, ok := set[key]; ok {
+		return v
+	}
+	base := key
+	for _, suffix := range []string{"_FILE", "_B64", "_COMMAND", "_SOURCE", "_KEYRING"} {
+		base = strings.TrimSuffix(base, suffix)
+	}
+	if hidden[base] {
+		return ""
+	}
+	return e.Environment.Getenv(key)
+}
@@ -0,0 +1,38 @@
+package clientconfig
+
+import (
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// WithFS reads the files named by _FILE variables (and ETCD_CREDENTIALS_DIR and the systemd credentials) from fsys instead of the filesystem, like an embed.FS with test fixtures or an fstest.MapFS. Variables are still read from the environment.
+// fs.FS paths can't start with a slash, so a leading / is removed: ETCD_SERVER_CA_FILE=/run/secrets/ca.crt reads run/secrets/ca.crt from fsys.
+func WithFS(fsys fs.FS) Option {
+	return func(o *options) {
+		o.fsys = fsys
+	}
+}
+
+// fsEnvironment reads files from an fs.FS.
+type fsEnvironment struct {
+	Environment
+	fsys fs.FS
+}
+
+func (e fsEnvironment) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(e.fsys, fsPath(name))
+}
+
+func (e fsEnvironment) Stat(name string) (os.FileInfo, error) {
+	return fs.Stat(e.fsys, fsPath(name))
+}
+
+// fsPath turns a path from a variable into one fs.FS accepts.
+func fsPath(name string) string {
+	name = strings.TrimLeft(name, "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
@@ -0,0 +1,111 @@
+package clientconfig
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWithFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"run/secrets/password":   {Data: []byte("from-fsys\n")},
+		"run/secrets/ca.crt":     {Data: []byte("ca")},
+		"fixtures/username":      {Data: []byte("alice")},
+		"creds/endpoints":        {Data: []byte("http://etcd1:2379")},
+		"creds/username":         {Data: []byte("bob")},
+		"creds/password":         {Data: []byte("hunter2")},
+		"systemd/etcd.password":  {Data: []byte("from-systemd")},
+		"systemd/etcd.server-ca": {Data: []byte("systemd-ca")},
+	}
+
+	tests := []struct {
+		name    string
+		vars    map[string]string
+		want    Settings
+		wantErr string
+	}{
+		{
+			name: "absolute paths lose their leading slash",
+			vars: map[string]string{"ETCD_PASSWORD_FILE": "/run/secrets/password", "ETCD_SERVER_CA_FILE": "/run/secrets/ca.crt"},
+			want: Settings{"ETCD_PASSWORD": "from-fsys", "ETCD_SERVER_CA": "ca"},
+		},
+		{
+			name: "relative paths",
+			vars: map[string]string{"ETCD_USERNAME_FILE": "fixtures/username"},
+			want: Settings{"ETCD_USERNAME": "alice"},
+		},
+		{
+			name: "variables are still read from the environment",
+			vars: map[string]string{"ETCD_ENDPOINTS": "http://etcd2:2379", "ETCD_USERNAME_FILE": "fixtures/username"},
+			want: Settings{"ETCD_ENDPOINTS": "http://etcd2:2379", "ETCD_USERNAME": "alice"},
+		},
+		{
+			name: "ETCD_CREDENTIALS_DIR",
+			vars: map[string]string{"ETCD_CREDENTIALS_DIR": "/creds"},
+			want: Settings{"ETCD_CREDENTIALS_DIR": "/creds", "ETCD_ENDPOINTS": "http://etcd1:2379", "ETCD_USERNAME": "bob", "ETCD_PASSWORD": "hunter2"},
+		},
+		{
+			name: "ETCD_CREDENTIALS_DIR doesn't override variables",
+			vars: map[string]string{"ETCD_CREDENTIALS_DIR": "/creds", "ETCD_USERNAME_AND_PASSWORD": "carol:secret"},
+			want: Settings{"ETCD_CREDENTIALS_DIR": "/creds", "ETCD_ENDPOINTS": "http://etcd1:2379", "ETCD_USERNAME_AND_PASSWORD": "carol:secret"},
+		},
+		{
+			name: "systemd credentials",
+			vars: map[string]string{"CREDENTIALS_DIRECTORY": "/systemd", "ETCD_USERNAME": "dave"},
+			want: Settings{"ETCD_USERNAME": "dave", "ETCD_PASSWORD": "from-systemd", "ETCD_SERVER_CA": "systemd-ca"},
+		},
+		{
+			name: "ETCD_CREDENTIALS_DIR wins over systemd credentials",
+			vars: map[string]string{"ETCD_CREDENTIALS_DIR": "/creds", "CREDENTIALS_DIRECTORY": "/systemd"},
+			want: Settings{"ETCD_CREDENTIALS_DIR": "/creds", "ETCD_ENDPOINTS": "http://etcd1:2379", "ETCD_USERNAME": "bob", "ETCD_PASSWORD": "hunter2", "ETCD_SERVER_CA": "systemd-ca"},
+		},
+		{
+			name:    "files outside of fsys aren't read",
+			vars:    map[string]string{"ETCD_SERVER_CA_FILE": "/etc/hostname"},
+			wantErr: `"/etc/hostname"`,
+		},
+		{
+			name:    "missing file",
+			vars:    map[string]string{"ETCD_PASSWORD_FILE": "/run/secrets/nonexistent"},
+			wantErr: "ETCD_PASSWORD_FILE",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ReadSettings(WithFS(fsys), WithEnvironment(lookuperEnvironment{MapLookuper(tc.vars)}))
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("ReadSettings() = %v, %v; want an error containing %q", got, err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadSettings: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ReadSettings() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFSPath(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "/run/secrets/ca.crt", want: "run/secrets/ca.crt"},
+		{in: "//run/secrets/ca.crt", want: "run/secrets/ca.crt"},
+		{in: "fixtures/ca.crt", want: "fixtures/ca.crt"},
+		{in: "/", want: "."},
+		{in: "", want: "."},
+	}
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			if got := fsPath(tc.in); got != tc.want {
+				t.Errorf("fsPath(%q) = %q, want %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
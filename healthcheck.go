@@ -0,0 +1,103 @@
+package clientconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// healthCheckTimeout is how long ETCD_ENDPOINT_HEALTH_CHECK waits for an endpoint, unless the DialTimeout is shorter.
+const healthCheckTimeout = 2 * time.Second
+
+// applyEndpointHealthCheck implements ETCD_ENDPOINT_HEALTH_CHECK: it drops the endpoints of c that don't accept a connection (and a TLS handshake if they use TLS) through dial, which can be nil.
+// It fails if none of them do.
+func applyEndpointHealthCheck(ctx context.Context, o *options, c *clientv3.Config, settings Settings, dial dialFunc) error {
+	v := settings["ETCD_ENDPOINT_HEALTH_CHECK"]
+	if v == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return errorf(CodeInvalidValue, "ETCD_ENDPOINT_HEALTH_CHECK", "failed to parse ETCD_ENDPOINT_HEALTH_CHECK as bool (%q)", v)
+	}
+	if !b || len(c.Endpoints) == 0 {
+		return nil
+	}
+	if o.dryRun {
+		o.skip("ETCD_ENDPOINT_HEALTH_CHECK: the endpoints weren't probed")
+		return nil
+	}
+	if dial == nil {
+		dial = baseDial
+	}
+	timeout := healthCheckTimeout
+	if c.DialTimeout > 0 && c.DialTimeout < timeout {
+		timeout = c.DialTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	errs := make([]error, len(c.Endpoints))
+	var wg sync.WaitGroup
+	for i, ep := range c.Endpoints {
+		wg.Add(1)
+		go func(i int, ep string) {
+			defer wg.Done()
+			errs[i] = checkEndpointHealth(ctx, c.TLS, ep, dial)
+		}(i, ep)
+	}
+	wg.Wait()
+	var healthy []string
+	for i, ep := range c.Endpoints {
+		if errs[i] != nil {
+			o.warnf("ETCD_ENDPOINT_HEALTH_CHECK: dropping unreachable endpoint %q: %v", ep, errs[i])
+			continue
+		}
+		healthy = append(healthy, ep)
+	}
+	if len(healthy) == 0 {
+		return errorf(CodeUnreachable, "ETCD_ENDPOINTS", "none of the endpoints are reachable (ETCD_ENDPOINT_HEALTH_CHECK is set): %q: %v", c.Endpoints[0], errs[0])
+	}
+	c.Endpoints = healthy
+	return nil
+}
+
+// checkEndpointHealth connects to ep, and does a TLS handshake with tc if ep uses TLS.
+func checkEndpointHealth(ctx context.Context, tc *tls.Config, ep string, dial dialFunc) error {
+	addr, secure := ep, false
+	if i := strings.Index(addr, "://"); i != -1 {
+		switch addr[:i] {
+		case "unix", "unixs":
+			addr = "unix://" + addr[i+3:]
+		default:
+			addr = addr[i+3:]
+		}
+		secure = ep[:i] == "https" || ep[:i] == "unixs"
+	} else {
+		secure = tc != nil
+	}
+	conn, err := dial(ctx, addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if !secure {
+		return nil
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(dl)
+	}
+	cfg := &tls.Config{}
+	if tc != nil {
+		cfg = tc.Clone()
+	}
+	if network, address := splitDialTarget(addr); cfg.ServerName == "" && network == "tcp" {
+		cfg.ServerName, _, _ = net.SplitHostPort(address)
+	}
+	return tls.Client(conn, cfg).HandshakeContext(ctx)
+}
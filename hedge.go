@@ -0,0 +1,129 @@
+package clientconfig
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// HedgedKV is a clientv3.KV that sends serializable reads to a second endpoint if the first hasn't answered within the hedge delay. The first response wins.
+// This reduces tail latency on clusters with one slow member. Everything else (writes, linearizable reads, transactions) goes through a regular client.
+type HedgedKV struct {
+	clientv3.KV
+
+	client  *clientv3.Client
+	members []*clientv3.Client
+	delay   time.Duration
+	next    uint32
+}
+
+// NewHedgedKV creates a client for c and an additional client per endpoint, which are used for hedged reads. You must call Close when you're done.
+func NewHedgedKV(c clientv3.Config, delay time.Duration) (*HedgedKV, error) {
+	cli, err := clientv3.New(c)
+	if err != nil {
+		return nil, err
+	}
+	h := &HedgedKV{
+		KV:     cli.KV,
+		client: cli,
+		delay:  delay,
+	}
+	for _, ep := range c.Endpoints {
+		mc := c
+		mc.Endpoints = []string{ep}
+		// Auto syncing would spread this client over all members again.
+		mc.AutoSyncInterval = 0
+		m, err := clientv3.New(mc)
+		if err != nil {
+			h.Close()
+			return nil, err
+		}
+		h.members = append(h.members, m)
+	}
+	return h, nil
+}
+
+// Client returns the regular client used for all non-hedged requests.
+func (h *HedgedKV) Client() *clientv3.Client {
+	return h.client
+}
+
+// Get is like clientv3.KV.Get, but hedged if clientv3.WithSerializable() is passed.
+func (h *HedgedKV) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	resp, err := h.Do(ctx, clientv3.OpGet(key, opts...))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Get(), nil
+}
+
+// Do is like clientv3.KV.Do, but hedged for serializable Get operations.
+func (h *HedgedKV) Do(ctx context.Context, op clientv3.Op) (clientv3.OpResponse, error) {
+	if !op.IsGet() || !op.IsSerializable() || len(h.members) < 2 {
+		return h.KV.Do(ctx, op)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type result struct {
+		resp clientv3.OpResponse
+		err  error
+	}
+	ch := make(chan result, 2)
+	first := int(atomic.AddUint32(&h.next, 1)) % len(h.members)
+	send := func(i int) {
+		go func() {
+			resp, err := h.members[i].Do(ctx, op)
+			ch <- result{resp, err}
+		}()
+	}
+	send(first)
+	t := time.NewTimer(h.delay)
+	defer t.Stop()
+	inflight := 1
+	hedged := false
+	var lastErr error
+	for {
+		select {
+		case <-t.C:
+			if !hedged {
+				hedged = true
+				inflight++
+				send((first + 1) % len(h.members))
+			}
+		case r := <-ch:
+			inflight--
+			if r.err == nil {
+				return r.resp, nil
+			}
+			lastErr = r.err
+			if !hedged {
+				// Don't wait for the delay if the first endpoint already failed.
+				hedged = true
+				inflight++
+				send((first + 1) % len(h.members))
+			} else if inflight == 0 {
+				return clientv3.OpResponse{}, lastErr
+			}
+		case <-ctx.Done():
+			if lastErr != nil {
+				return clientv3.OpResponse{}, lastErr
+			}
+			return clientv3.OpResponse{}, ctx.Err()
+		}
+	}
+}
+
+// Close closes all clients and returns the first error.
+func (h *HedgedKV) Close() error {
+	err := h.client.Close()
+	for _, m := range h.members {
+		if merr := m.Close(); err == nil {
+			err = merr
+		}
+	}
+	return err
+}
+
+var _ clientv3.KV = &HedgedKV{}
@@ -0,0 +1,99 @@
+package clientconfig
+
+import (
+	"context"
+	"net"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"google.golang.org/grpc"
+)
+
+// Hooks are called on events in the life of a configuration and the clients built from it, for example to export metrics (see the metrics module). All of them are optional and may be called concurrently.
+type Hooks struct {
+	// OnConfigLoad is called after reading and applying the configuration, with the error if that failed.
+	OnConfigLoad func(err error)
+	// OnTLSReload is called when rotated TLS files were picked up, with kind "client-cert" or "ca" and the error if the new files couldn't be used (and the previous ones are still in use).
+	OnTLSReload func(kind string, err error)
+	// OnDial is called for every connection attempt to an endpoint, including reconnects. Setting it installs a custom dialer, which means gRPC's own proxy support (HTTPS_PROXY) no longer applies; use ETCD_PROXY=env instead.
+	OnDial func(addr string, err error)
+	// OnAuthFailure is called when etcd rejects the credentials or token of a call, like a wrong password or a user without permission.
+	OnAuthFailure func(method string, err error)
+}
+
+// WithHooks installs Hooks. Multiple WithHooks options are all called.
+func WithHooks(h Hooks) Option {
+	return func(o *options) {
+		o.hooks = append(o.hooks, h)
+	}
+}
+
+func (o *options) configLoaded(err error) {
+	for _, h := range o.hooks {
+		if h.OnConfigLoad != nil {
+			h.OnConfigLoad(err)
+		}
+	}
+}
+
+func (o *options) tlsReloaded(kind string, err error) {
+	for _, h := range o.hooks {
+		if h.OnTLSReload != nil {
+			h.OnTLSReload(kind, err)
+		}
+	}
+}
+
+// hasDialHook returns whether any of the hooks wants to know about dials.
+func (o *options) hasDialHook() bool {
+	for _, h := range o.hooks {
+		if h.OnDial != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// dialHook wraps next to call the OnDial hooks.
+func (o *options) dialHook(next dialFunc) dialFunc {
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		conn, err := next(ctx, addr)
+		for _, h := range o.hooks {
+			if h.OnDial != nil {
+				h.OnDial(addr, err)
+			}
+		}
+		return conn, err
+	}
+}
+
+// authFailure calls the OnAuthFailure hooks if err means etcd rejected our credentials.
+func (o *options) authFailure(method string, err error) {
+	if err == nil {
+		return
+	}
+	switch rpctypes.Error(err) {
+	case rpctypes.ErrAuthFailed, rpctypes.ErrPermissionDenied, rpctypes.ErrUserEmpty:
+		// Not ErrInvalidAuthToken: expired tokens are refreshed by the client.
+	default:
+		return
+	}
+	for _, h := range o.hooks {
+		if h.OnAuthFailure != nil {
+			h.OnAuthFailure(method, err)
+		}
+	}
+}
+
+// addAuthFailureHooks installs interceptors that report rejected credentials to the hooks.
+func (i *interceptors) addAuthFailureHooks(o *options) {
+	i.unary = append(i.unary, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		o.authFailure(method, err)
+		return err
+	})
+	i.stream = append(i.stream, func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		s, err := streamer(ctx, desc, cc, method, opts...)
+		o.authFailure(method, err)
+		return s, err
+	})
+}
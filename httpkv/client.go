@@ -0,0 +1,252 @@
+// Package httpkv talks to etcd's grpc-gateway (the /v3 HTTP/JSON API) instead of gRPC.
+//
+// This is a fallback for environments where egress proxies break gRPC or HTTP/2. It only supports a small subset of the KV API, but uses the same endpoints, credentials and TLS settings as a regular client:
+//
+//	c, err := clientconfig.Get()
+//	if err != nil {
+//		return err
+//	}
+//	kv := httpkv.New(c)
+//	v, found, err := kv.Get(ctx, "/my/key")
+package httpkv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Client is a minimal KV client for the grpc-gateway.
+type Client struct {
+	endpoints []string
+	hc        *http.Client
+	username  string
+	password  string
+
+	mtx   sync.Mutex
+	token string
+}
+
+// KeyValue is a key and its value as returned by List.
+type KeyValue struct {
+	Key         string
+	Value       []byte
+	ModRevision int64
+}
+
+// New creates a Client. Endpoints without a scheme get https:// if TLS is configured and http:// otherwise.
+func New(c clientv3.Config) *Client {
+	scheme := "http://"
+	if c.TLS != nil {
+		scheme = "https://"
+	}
+	ret := &Client{
+		hc: &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				DialContext: (&net.Dialer{
+					Timeout:   c.DialTimeout,
+					KeepAlive: c.DialKeepAliveTime,
+				}).DialContext,
+				TLSClientConfig:     c.TLS,
+				TLSHandshakeTimeout: c.DialTimeout,
+			},
+		},
+		username: c.Username,
+		password: c.Password,
+	}
+	for _, ep := range c.Endpoints {
+		if !strings.Contains(ep, "://") {
+			ep = scheme + ep
+		}
+		ret.endpoints = append(ret.endpoints, strings.TrimSuffix(ep, "/"))
+	}
+	return ret
+}
+
+// Get returns the value of a single key. found is false if the key doesn't exist.
+func (c *Client) Get(ctx context.Context, key string) (value []byte, found bool, err error) {
+	var resp rangeResponse
+	if err := c.call(ctx, "/v3/kv/range", rangeRequest{Key: []byte(key)}, &resp); err != nil {
+		return nil, false, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, false, nil
+	}
+	return resp.Kvs[0].Value, true, nil
+}
+
+// List returns all keys with the given prefix.
+func (c *Client) List(ctx context.Context, prefix string) ([]KeyValue, error) {
+	var resp rangeResponse
+	if err := c.call(ctx, "/v3/kv/range", rangeRequest{Key: []byte(prefix), RangeEnd: []byte(clientv3.GetPrefixRangeEnd(prefix))}, &resp); err != nil {
+		return nil, err
+	}
+	ret := make([]KeyValue, len(resp.Kvs))
+	for i, kv := range resp.Kvs {
+		ret[i] = KeyValue{Key: string(kv.Key), Value: kv.Value, ModRevision: kv.ModRevision}
+	}
+	return ret, nil
+}
+
+// Put sets the value of a key.
+func (c *Client) Put(ctx context.Context, key string, value []byte) error {
+	return c.call(ctx, "/v3/kv/put", putRequest{Key: []byte(key), Value: value}, nil)
+}
+
+// Delete removes a key and returns how many keys were deleted.
+func (c *Client) Delete(ctx context.Context, key string) (int64, error) {
+	var resp deleteRangeResponse
+	if err := c.call(ctx, "/v3/kv/deleterange", deleteRangeRequest{Key: []byte(key)}, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Deleted, nil
+}
+
+// call sends the request to the endpoints in order until one of them answers. It (re)authenticates if needed.
+func (c *Client) call(ctx context.Context, path string, req, resp interface{}) error {
+	if len(c.endpoints) == 0 {
+		return errors.New("httpkv: no endpoints configured")
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	var lastErr error
+	for _, ep := range c.endpoints {
+		for attempt := 0; attempt < 2; attempt++ {
+			token, err := c.getToken(ctx, ep, attempt > 0)
+			if err != nil {
+				lastErr = err
+				break
+			}
+			err = c.post(ctx, ep+path, token, body, resp)
+			var ge *gatewayError
+			if errors.As(err, &ge) && ge.isInvalidToken() && c.username != "" {
+				lastErr = err
+				continue
+			}
+			if err == nil || errors.As(err, &ge) {
+				return err
+			}
+			lastErr = err
+			break
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	return lastErr
+}
+
+func (c *Client) getToken(ctx context.Context, ep string, refresh bool) (string, error) {
+	if c.username == "" {
+		return "", nil
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.token != "" && !refresh {
+		return c.token, nil
+	}
+	body, err := json.Marshal(authenticateRequest{Name: c.username, Password: c.password})
+	if err != nil {
+		return "", err
+	}
+	var resp authenticateResponse
+	if err := c.post(ctx, ep+"/v3/auth/authenticate", "", body, &resp); err != nil {
+		return "", fmt.Errorf("httpkv: failed to authenticate: %v", err)
+	}
+	c.token = resp.Token
+	return c.token, nil
+}
+
+func (c *Client) post(ctx context.Context, url, token string, body []byte, resp interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	hr, err := c.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer hr.Body.Close()
+	b, err := ioutil.ReadAll(hr.Body)
+	if err != nil {
+		return err
+	}
+	if hr.StatusCode != http.StatusOK {
+		ge := &gatewayError{StatusCode: hr.StatusCode}
+		if json.Unmarshal(b, ge) != nil || ge.Message == "" {
+			ge.Message = strings.TrimSpace(string(b))
+		}
+		return ge
+	}
+	if resp == nil {
+		return nil
+	}
+	return json.Unmarshal(b, resp)
+}
+
+type gatewayError struct {
+	StatusCode int    `json:"-"`
+	Code       int    `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *gatewayError) Error() string {
+	return fmt.Sprintf("httpkv: etcd returned HTTP %d: %s", e.StatusCode, e.Message)
+}
+
+func (e *gatewayError) isInvalidToken() bool {
+	return strings.Contains(e.Message, "invalid auth token") || strings.Contains(e.Message, "user name is empty")
+}
+
+// The types below mirror the JSON mapping of etcdserverpb. Bytes are base64 encoded and int64s are strings.
+
+type rangeRequest struct {
+	Key      []byte `json:"key"`
+	RangeEnd []byte `json:"range_end,omitempty"`
+}
+
+type rangeResponse struct {
+	Kvs []struct {
+		Key         []byte `json:"key"`
+		Value       []byte `json:"value"`
+		ModRevision int64  `json:"mod_revision,string"`
+	} `json:"kvs"`
+}
+
+type putRequest struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+type deleteRangeRequest struct {
+	Key []byte `json:"key"`
+}
+
+type deleteRangeResponse struct {
+	Deleted int64 `json:"deleted,string"`
+}
+
+type authenticateRequest struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+type authenticateResponse struct {
+	Token string `json:"token"`
+}
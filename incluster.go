@@ -0,0 +1,64 @@
+package clientconfig
+
+import (
+	"path/filepath"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	// inClusterSecretDir is where InClusterDefaults looks for a mounted Secret, in the format of ETCD_CREDENTIALS_DIR.
+	inClusterSecretDir = "/var/run/secrets/etcd"
+	// inClusterNamespaceFile is the namespace of the pod, in every pod with a service account token mounted.
+	inClusterNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+	// inClusterService is the Service InClusterDefaults connects to in the namespace of the pod.
+	inClusterService = "etcd-client"
+)
+
+// InClusterDefaults is like ApplyWithOptions(Defaults(), opts...), with defaults for pods in Kubernetes, like client-go's rest.InClusterConfig.
+// A Secret mounted at /var/run/secrets/etcd/ is used like ETCD_CREDENTIALS_DIR, and without an endpoints file in it the endpoint is etcd-client.<namespace>.svc:2379 in the namespace of the pod. Variables that are set win over both.
+// Outside Kubernetes this is just ApplyWithOptions(Defaults(), opts...).
+func InClusterDefaults(opts ...Option) (clientv3.Config, error) {
+	probe := newOptions(opts)
+	env := credentialsDirEnvironment{Environment: probe.base}
+	var ic []Option
+	for _, f := range credentialsDirFiles {
+		if env.exists(filepath.Join(inClusterSecretDir, f.name)) {
+			ic = append(ic, func(o *options) {
+				o.inClusterDir = inClusterSecretDir
+			})
+			break
+		}
+	}
+	if b, err := probe.base.ReadFile(inClusterNamespaceFile); err == nil {
+		if ns := strings.TrimSpace(string(b)); ns != "" {
+			ep := inClusterService + "." + ns + ".svc:2379"
+			ic = append(ic, func(o *options) {
+				o.inClusterEndpoint = ep
+			})
+		}
+	}
+	return ApplyWithOptions(Defaults(), append(opts[:len(opts):len(opts)], ic...)...)
+}
+
+// inClusterDirEnvironment sets ETCD_CREDENTIALS_DIR to the Secret found by InClusterDefaults, unless it's set.
+type inClusterDirEnvironment struct {
+	Environment
+	dir string
+}
+
+func (e inClusterDirEnvironment) Getenv(key string) string {
+	if v := e.Environment.Getenv(key); v != "" || key != "ETCD_CREDENTIALS_DIR" {
+		return v
+	}
+	return e.dir
+}
+
+// applyInClusterEndpoint sets the endpoint found by InClusterDefaults, unless the endpoints are configured some other way.
+func applyInClusterEndpoint(o *options, settings Settings) {
+	if o.inClusterEndpoint == "" || settings["ETCD_ENDPOINTS"] != "" || settings["ETCD_GATEWAY_ENDPOINT"] != "" || settings["ETCD_DISCOVERY_SRV"] != "" {
+		return
+	}
+	settings["ETCD_ENDPOINTS"] = o.inClusterEndpoint
+}
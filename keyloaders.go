@@ -0,0 +1,86 @@
+package clientconfig
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var (
+	keyLoadersMtx sync.Mutex
+	keyLoaders    = map[string]func(uri string, password []byte, cert *x509.Certificate) (crypto.Signer, error){}
+)
+
+// RegisterKeyLoader makes ETCD_CLIENT_KEY accept URIs with the given scheme (like "pkcs11:token=etcd;object=client"), for keys that never leave a hardware token or TPM.
+// load gets the whole URI, ETCD_CLIENT_KEY_PASSWORD (e.g. the PIN) and the client certificate, and returns a signer for the certificate's public key.
+// RegisterKeyLoader is meant to be called from init functions and panics if the scheme is already taken.
+func RegisterKeyLoader(scheme string, load func(uri string, password []byte, cert *x509.Certificate) (crypto.Signer, error)) {
+	keyLoadersMtx.Lock()
+	defer keyLoadersMtx.Unlock()
+	if scheme == "" || strings.ContainsAny(scheme, ":/ ") {
+		panic(fmt.Sprintf("clientconfig: invalid key loader scheme %q", scheme))
+	}
+	if _, found := keyLoaders[scheme]; found {
+		panic(fmt.Sprintf("clientconfig: key loader for %q registered twice", scheme))
+	}
+	keyLoaders[scheme] = load
+}
+
+// keyURIScheme returns the scheme of key if it's a URI rather than PEM.
+func keyURIScheme(key []byte) (string, bool) {
+	s := strings.TrimSpace(string(key))
+	if strings.Contains(s, "-----BEGIN") {
+		return "", false
+	}
+	scheme, _, ok := strings.Cut(s, ":")
+	if !ok || scheme == "" || strings.ContainsAny(scheme, "/ \n") {
+		return "", false
+	}
+	return scheme, true
+}
+
+// loadKeyPair builds a certificate whose private key is provided by the loader registered for scheme.
+func loadKeyPair(scheme string, certPEM, key, password []byte) (tls.Certificate, error) {
+	keyLoadersMtx.Lock()
+	load, found := keyLoaders[scheme]
+	keyLoadersMtx.Unlock()
+	if !found {
+		if scheme == "pkcs11" {
+			return tls.Certificate{}, errors.New("ETCD_CLIENT_KEY is a PKCS#11 URI; import github.com/Jille/etcd-client-from-env/pkcs11 to support it")
+		}
+		return tls.Certificate{}, fmt.Errorf("ETCD_CLIENT_KEY isn't a PEM key and no key loader is registered for %q", scheme)
+	}
+	var crt tls.Certificate
+	for rest := certPEM; ; {
+		var b *pem.Block
+		b, rest = pem.Decode(rest)
+		if b == nil {
+			break
+		}
+		if b.Type == "CERTIFICATE" {
+			crt.Certificate = append(crt.Certificate, b.Bytes)
+		}
+	}
+	if len(crt.Certificate) == 0 {
+		return tls.Certificate{}, errors.New("failed to find any PEM data in certificate input")
+	}
+	leaf, err := x509.ParseCertificate(crt.Certificate[0])
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	signer, err := load(strings.TrimSpace(string(key)), password, leaf)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if pub, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool }); !ok || !pub.Equal(leaf.PublicKey) {
+		return tls.Certificate{}, errors.New("the key from ETCD_CLIENT_KEY doesn't match the certificate")
+	}
+	crt.PrivateKey = signer
+	crt.Leaf = leaf
+	return crt, nil
+}
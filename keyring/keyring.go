@@ -0,0 +1,49 @@
+// Package keyring reads passwords from the OS keyring: the macOS Keychain, the Windows Credential Manager or the Secret Service (GNOME Keyring, KWallet) on Linux.
+//
+// Importing it makes ETCD_PASSWORD_KEYRING (and ETCD_CLIENT_KEY_PASSWORD_KEYRING) work, and registers the keyring resolver for the _SOURCE variables:
+//
+//	ETCD_PASSWORD_KEYRING=etcd-prod/alice
+//	ETCD_CLIENT_KEY_SOURCE=keyring://etcd-prod/client-key
+//
+// The value is the service and the account, separated by the last slash. Store a password with:
+//
+//	security add-generic-password -s etcd-prod -a alice -w                 # macOS
+//	secret-tool store --label=etcd-prod service etcd-prod username alice   # Linux
+//	cmdkey /generic:etcd-prod:alice /user:alice /pass                      # Windows
+package keyring
+
+import (
+	"fmt"
+	"strings"
+
+	clientconfig "github.com/Jille/etcd-client-from-env"
+	gokeyring "github.com/zalando/go-keyring"
+)
+
+func init() {
+	clientconfig.RegisterResolver("keyring", resolver{})
+}
+
+// resolver implements clientconfig.Resolver and clientconfig.LocalConfigSource.
+type resolver struct{}
+
+func (resolver) Resolve(scheme, ref string) ([]byte, error) {
+	i := strings.LastIndex(ref, "/")
+	if i <= 0 || i == len(ref)-1 {
+		return nil, fmt.Errorf("%q should be service/account", ref)
+	}
+	service, account := ref[:i], ref[i+1:]
+	pw, err := gokeyring.Get(service, account)
+	if err != nil {
+		if err == gokeyring.ErrNotFound {
+			return nil, fmt.Errorf("no password for account %q of service %q in the keyring", account, service)
+		}
+		return nil, fmt.Errorf("failed to read the keyring: %v", err)
+	}
+	return []byte(pw), nil
+}
+
+// IsLocal implements clientconfig.LocalConfigSource: the keyring is on this machine, so it can be used with offline resolution.
+func (resolver) IsLocal() bool {
+	return true
+}
@@ -0,0 +1,124 @@
+// Package koanfprovider implements a koanf.Provider backed by clientconfig's resolution.
+//
+// It returns the raw settings (after _FILE handling) under the "etcd" key, with lowercased names without the ETCD_ prefix:
+//
+//	k := koanf.New(".")
+//	p := koanfprovider.Provider()
+//	if err := k.Load(p, nil); err != nil {
+//		return err
+//	}
+//	k.String("etcd.endpoints")
+//
+// It doesn't import koanf itself, so depending on it doesn't pull koanf into your module graph unless you use koanf anyway.
+package koanfprovider
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	clientconfig "github.com/Jille/etcd-client-from-env"
+)
+
+// EtcdProvider implements koanf.Provider.
+type EtcdProvider struct {
+	// PollInterval is how often Watch re-reads the settings. Defaults to 10 seconds.
+	PollInterval time.Duration
+
+	opts []clientconfig.Option
+
+	mtx    sync.Mutex
+	closed bool
+	// stop is closed by Close to end all Watch goroutines.
+	stop chan struct{}
+}
+
+// Provider returns a provider. The options are passed to clientconfig.ReadSettings.
+func Provider(opts ...clientconfig.Option) *EtcdProvider {
+	return &EtcdProvider{opts: opts}
+}
+
+// ReadBytes is not supported.
+func (p *EtcdProvider) ReadBytes() ([]byte, error) {
+	return nil, errors.New("koanfprovider does not support ReadBytes")
+}
+
+// Read returns the settings as a nested map.
+func (p *EtcdProvider) Read() (map[string]interface{}, error) {
+	s, err := clientconfig.ReadSettings(p.opts...)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]interface{}{}
+	for k, v := range s {
+		m[strings.ToLower(strings.TrimPrefix(k, "ETCD_"))] = v
+	}
+	return map[string]interface{}{"etcd": m}, nil
+}
+
+// Watch polls the settings and calls cb when they change (with a nil event), or with an error if they can't be read. Polling continues until Close is called.
+func (p *EtcdProvider) Watch(cb func(event interface{}, err error)) error {
+	p.mtx.Lock()
+	if p.closed {
+		p.mtx.Unlock()
+		return errors.New("koanfprovider can't Watch after Close")
+	}
+	if p.stop == nil {
+		p.stop = make(chan struct{})
+	}
+	stop := p.stop
+	p.mtx.Unlock()
+	last, err := clientconfig.ReadSettings(p.opts...)
+	if err != nil {
+		return err
+	}
+	interval := p.PollInterval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	t := time.NewTicker(interval)
+	go func() {
+		defer t.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+			}
+			s, err := clientconfig.ReadSettings(p.opts...)
+			if err != nil {
+				cb(nil, err)
+				continue
+			}
+			if !reflect.DeepEqual(s, last) {
+				last = s
+				cb(nil, nil)
+			}
+		}
+	}()
+	return nil
+}
+
+// Close stops all Watches. The provider can't be watched anymore afterwards, but Read still works.
+func (p *EtcdProvider) Close() error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if !p.closed {
+		p.closed = true
+		if p.stop != nil {
+			close(p.stop)
+		}
+	}
+	return nil
+}
+
+// Settings converts the "etcd" section of a koanf instance (as returned by koanf.Koanf.StringMap("etcd")) back into clientconfig.Settings, so you can pass it to clientconfig.ApplySettings.
+func Settings(m map[string]string) clientconfig.Settings {
+	s := clientconfig.Settings{}
+	for k, v := range m {
+		s["ETCD_"+strings.ToUpper(k)] = v
+	}
+	return s
+}
@@ -0,0 +1,24 @@
+package clientconfig
+
+import (
+	"fmt"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/balancer"
+)
+
+// lbPolicies are the load balancing policies gRPC always has. Other registered balancers are accepted too.
+var lbPolicies = []string{"round_robin", "pick_first"}
+
+// applyLBPolicy makes the client use the gRPC load balancing policy name instead of round_robin.
+func applyLBPolicy(c *clientv3.Config, name string) error {
+	name = strings.TrimSpace(name)
+	if balancer.Get(name) == nil {
+		return errorf(CodeInvalidValue, "ETCD_LB_POLICY", "unknown load balancing policy %q in ETCD_LB_POLICY (should be %s, or a registered gRPC balancer)", name, strings.Join(lbPolicies, " or "))
+	}
+	// clientv3's resolver hands gRPC a service config with round_robin, which beats a default service config unless service configs from the resolver are disabled.
+	appendDialOptions(c, grpc.WithDisableServiceConfig(), grpc.WithDefaultServiceConfig(fmt.Sprintf(`{"loadBalancingPolicy": %q}`, name)))
+	return nil
+}
@@ -0,0 +1,3 @@
+module github.com/Jille/etcd-client-from-env/legacy
+
+go 1.17
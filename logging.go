@@ -0,0 +1,57 @@
+package clientconfig
+
+import (
+	"go.etcd.io/etcd/client/pkg/v3/logutil"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// logLevels are the values of ETCD_LOG_LEVEL, as understood by zapcore.Level.
+var logLevels = []string{"debug", "info", "warn", "error", "dpanic", "panic", "fatal"}
+
+// ApplyWithLogger is like ApplyWithOptions with WithLogger(lg).
+func ApplyWithLogger(c clientv3.Config, lg *zap.Logger, opts ...Option) (clientv3.Config, error) {
+	return ApplyWithOptions(c, append(opts, WithLogger(lg))...)
+}
+
+// WithLogger sets clientv3.Config.Logger, so the client logs through the application's logger. ETCD_LOG_LEVEL and ETCD_LOG_FORMAT don't apply to it.
+func WithLogger(lg *zap.Logger) Option {
+	return func(o *options) {
+		o.logger = lg
+	}
+}
+
+// applyLogConfig sets c.LogConfig from ETCD_LOG_LEVEL and ETCD_LOG_FORMAT, starting from the client's default configuration.
+func applyLogConfig(o *options, c *clientv3.Config, settings Settings) error {
+	level, format := settings["ETCD_LOG_LEVEL"], settings["ETCD_LOG_FORMAT"]
+	if level == "" && format == "" {
+		return nil
+	}
+	if c.Logger != nil {
+		o.warnf("ignoring ETCD_LOG_LEVEL and ETCD_LOG_FORMAT, because the config already has a Logger")
+		return nil
+	}
+	var cfg zap.Config
+	if c.LogConfig != nil {
+		cfg = *c.LogConfig
+	} else {
+		cfg = logutil.DefaultZapLoggerConfig
+	}
+	if level != "" {
+		var l zapcore.Level
+		if err := l.UnmarshalText([]byte(level)); err != nil {
+			return errorf(CodeInvalidValue, "ETCD_LOG_LEVEL", "invalid ETCD_LOG_LEVEL %q (should be one of %v)", level, logLevels)
+		}
+		cfg.Level = zap.NewAtomicLevelAt(l)
+	}
+	switch format {
+	case "":
+	case "json", "console":
+		cfg.Encoding = format
+	default:
+		return errorf(CodeInvalidValue, "ETCD_LOG_FORMAT", "invalid ETCD_LOG_FORMAT %q (should be json or console)", format)
+	}
+	c.LogConfig = &cfg
+	return nil
+}
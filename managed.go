@@ -0,0 +1,136 @@
+package clientconfig
+
+import (
+	"context"
+	"maps"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// ManagedClient is a client that follows changes to its configuration: on SIGHUP or a call to Reload it reads the environment and files again and, if anything changed, connects a new client and switches over to it (see CredentialRotator).
+// It implements clientv3.KV, and its Watch keeps going across reloads, so long-running daemons can hold on to it instead of a *clientv3.Client.
+type ManagedClient struct {
+	rotator *CredentialRotator
+	o       *options
+	opts    []Option
+	stop    func()
+
+	// mtx serializes reloads.
+	mtx      sync.Mutex
+	settings Settings
+}
+
+// NewManagedClient connects like Connect and starts listening for SIGHUP. Call Close when you're done.
+func NewManagedClient(ctx context.Context, opts ...Option) (*ManagedClient, error) {
+	settings, err := ReadSettingsContext(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	cli, err := connectSettings(ctx, settings, opts)
+	if err != nil {
+		return nil, err
+	}
+	m := &ManagedClient{
+		rotator:  &CredentialRotator{cli: cli, rotated: make(chan struct{})},
+		o:        newOptions(opts),
+		opts:     opts,
+		settings: settings,
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	done := make(chan struct{})
+	var once sync.Once
+	m.stop = func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-ch:
+				if err := m.Reload(context.Background()); err != nil {
+					m.o.warnf("reloading after SIGHUP failed, keeping the current client: %v", err)
+				}
+			}
+		}
+	}()
+	return m, nil
+}
+
+// Reload reads the configuration again. If it changed, a new client is connected (and must answer like in Connect) before it replaces the current one, which is closed 30 seconds later. If that fails, the current client stays in use.
+func (m *ManagedClient) Reload(ctx context.Context) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	settings, err := ReadSettingsContext(ctx, m.opts...)
+	if err != nil {
+		return err
+	}
+	if maps.Equal(settings, m.settings) {
+		return nil
+	}
+	cli, err := connectSettings(ctx, settings, m.opts)
+	if err != nil {
+		return err
+	}
+	if err := m.rotator.swap(cli); err != nil {
+		return err
+	}
+	m.settings = settings
+	return nil
+}
+
+// Client returns the current client. Don't hold on to it: it is closed some time after the next reload.
+func (m *ManagedClient) Client() *clientv3.Client {
+	return m.rotator.Client()
+}
+
+// Get is clientv3.KV.Get on the current client.
+func (m *ManagedClient) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	return m.Client().Get(ctx, key, opts...)
+}
+
+// Put is clientv3.KV.Put on the current client.
+func (m *ManagedClient) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	return m.Client().Put(ctx, key, val, opts...)
+}
+
+// Delete is clientv3.KV.Delete on the current client.
+func (m *ManagedClient) Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	return m.Client().Delete(ctx, key, opts...)
+}
+
+// Compact is clientv3.KV.Compact on the current client.
+func (m *ManagedClient) Compact(ctx context.Context, rev int64, opts ...clientv3.CompactOption) (*clientv3.CompactResponse, error) {
+	return m.Client().Compact(ctx, rev, opts...)
+}
+
+// Do is clientv3.KV.Do on the current client.
+func (m *ManagedClient) Do(ctx context.Context, op clientv3.Op) (clientv3.OpResponse, error) {
+	return m.Client().Do(ctx, op)
+}
+
+// Txn is clientv3.KV.Txn on the current client.
+func (m *ManagedClient) Txn(ctx context.Context) clientv3.Txn {
+	return m.Client().Txn(ctx)
+}
+
+// Watch is like clientv3.Watcher.Watch, but resumes on the new client after a reload. See CredentialRotator.Watch.
+func (m *ManagedClient) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	return m.rotator.Watch(ctx, key, opts...)
+}
+
+// Close stops listening for SIGHUP and closes the current client.
+func (m *ManagedClient) Close() error {
+	m.stop()
+	return m.rotator.Close()
+}
+
+var _ clientv3.KV = &ManagedClient{}
@@ -0,0 +1,35 @@
+package clientconfig
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// parseMetadata parses ETCD_GRPC_METADATA ("key1=val1,key2=val2") into a list of alternating keys and values.
+func parseMetadata(v string) ([]string, error) {
+	var kv []string
+	for _, p := range strings.Split(v, ",") {
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+		sp := strings.SplitN(p, "=", 2)
+		if len(sp) != 2 || strings.TrimSpace(sp[0]) == "" {
+			return nil, errorf(CodeInvalidValue, "ETCD_GRPC_METADATA", "invalid ETCD_GRPC_METADATA: %q should be key=value", p)
+		}
+		kv = append(kv, strings.ToLower(strings.TrimSpace(sp[0])), sp[1])
+	}
+	return kv, nil
+}
+
+// addMetadata installs interceptors that attach the given metadata to every call.
+func (i *interceptors) addMetadata(kv []string) {
+	i.unary = append(i.unary, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(metadata.AppendToOutgoingContext(ctx, kv...), method, req, reply, cc, opts...)
+	})
+	i.stream = append(i.stream, func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(metadata.AppendToOutgoingContext(ctx, kv...), desc, cc, method, opts...)
+	})
+}
@@ -0,0 +1,71 @@
+// Package metrics provides Prometheus collectors for the configuration built by clientconfig.
+package metrics
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+
+	clientconfig "github.com/Jille/etcd-client-from-env"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var certExpiryDesc = prometheus.NewDesc(
+	"etcd_client_certificate_expiry_timestamp_seconds",
+	"Unix timestamp at which the certificate configured for the etcd client expires.",
+	[]string{"kind", "subject", "serial"}, nil,
+)
+
+// CertExpiryCollector exports the expiry time of the client certificate (ETCD_CLIENT_CERT) and the CA(s) (ETCD_SERVER_CA).
+// The settings are re-read on every scrape, so the metrics reflect rotated files.
+type CertExpiryCollector struct {
+	opts []clientconfig.Option
+}
+
+// NewCertExpiryCollector creates a CertExpiryCollector. The options are passed to clientconfig.ReadSettings.
+func NewCertExpiryCollector(opts ...clientconfig.Option) *CertExpiryCollector {
+	return &CertExpiryCollector{opts: opts}
+}
+
+// Describe implements prometheus.Collector.
+func (c *CertExpiryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- certExpiryDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *CertExpiryCollector) Collect(ch chan<- prometheus.Metric) {
+	settings, err := clientconfig.ReadSettings(c.opts...)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(certExpiryDesc, err)
+		return
+	}
+	for kind, k := range map[string]string{"client": "ETCD_CLIENT_CERT", "ca": "ETCD_SERVER_CA"} {
+		certs, err := parseCertificates(settings[k])
+		if err != nil {
+			ch <- prometheus.NewInvalidMetric(certExpiryDesc, err)
+			continue
+		}
+		for _, crt := range certs {
+			ch <- prometheus.MustNewConstMetric(certExpiryDesc, prometheus.GaugeValue, float64(crt.NotAfter.Unix()), kind, crt.Subject.String(), crt.SerialNumber.String())
+		}
+	}
+}
+
+func parseCertificates(v string) ([]*x509.Certificate, error) {
+	var ret []*x509.Certificate
+	rest := []byte(v)
+	for {
+		var b *pem.Block
+		b, rest = pem.Decode(rest)
+		if b == nil {
+			return ret, nil
+		}
+		if b.Type != "CERTIFICATE" {
+			continue
+		}
+		crt, err := x509.ParseCertificate(b.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, crt)
+	}
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"errors"
+
+	clientconfig "github.com/Jille/etcd-client-from-env"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ClientCollector counts configuration loads, TLS reloads, connection attempts and authentication failures of the clients built with its Hooks.
+// Register it with a prometheus.Registerer and pass clientconfig.WithHooks(c.Hooks()) to Connect (or any other function that takes Options).
+type ClientCollector struct {
+	configLoads  *prometheus.CounterVec
+	tlsReloads   *prometheus.CounterVec
+	dials        *prometheus.CounterVec
+	authFailures *prometheus.CounterVec
+}
+
+// NewClientCollector creates a ClientCollector.
+func NewClientCollector() *ClientCollector {
+	return &ClientCollector{
+		configLoads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "etcd_client_config_loads_total",
+			Help: "Number of times the etcd client configuration was loaded, by result (ok or the error code).",
+		}, []string{"result"}),
+		tlsReloads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "etcd_client_tls_reloads_total",
+			Help: "Number of times rotated TLS files were picked up, by kind (client-cert or ca) and result (ok or error).",
+		}, []string{"kind", "result"}),
+		dials: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "etcd_client_dials_total",
+			Help: "Number of connection attempts to etcd endpoints, including reconnects, by result (ok or error).",
+		}, []string{"endpoint", "result"}),
+		authFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "etcd_client_auth_failures_total",
+			Help: "Number of calls etcd rejected because of the credentials or permissions, by gRPC method.",
+		}, []string{"method"}),
+	}
+}
+
+// Hooks returns the clientconfig.Hooks that feed the counters.
+func (c *ClientCollector) Hooks() clientconfig.Hooks {
+	return clientconfig.Hooks{
+		OnConfigLoad: func(err error) {
+			c.configLoads.WithLabelValues(errorResult(err)).Inc()
+		},
+		OnTLSReload: func(kind string, err error) {
+			c.tlsReloads.WithLabelValues(kind, result(err)).Inc()
+		},
+		OnDial: func(addr string, err error) {
+			c.dials.WithLabelValues(addr, result(err)).Inc()
+		},
+		OnAuthFailure: func(method string, err error) {
+			c.authFailures.WithLabelValues(method).Inc()
+		},
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *ClientCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.configLoads.Describe(ch)
+	c.tlsReloads.Describe(ch)
+	c.dials.Describe(ch)
+	c.authFailures.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *ClientCollector) Collect(ch chan<- prometheus.Metric) {
+	c.configLoads.Collect(ch)
+	c.tlsReloads.Collect(ch)
+	c.dials.Collect(ch)
+	c.authFailures.Collect(ch)
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// errorResult is like result, but uses the code of clientconfig errors, like ETCDCFG-0001.
+func errorResult(err error) string {
+	var e *clientconfig.Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return result(err)
+}
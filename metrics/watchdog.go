@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"sync"
+
+	clientconfig "github.com/Jille/etcd-client-from-env"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	endpointUpDesc = prometheus.NewDesc(
+		"etcd_client_endpoint_up",
+		"Whether the endpoint answered the last Status call of the watchdog.",
+		[]string{"endpoint"}, nil,
+	)
+	endpointRTTDesc = prometheus.NewDesc(
+		"etcd_client_endpoint_status_rtt_seconds",
+		"Round trip time of the last Status call of the watchdog.",
+		[]string{"endpoint"}, nil,
+	)
+	alarmsDesc = prometheus.NewDesc(
+		"etcd_client_cluster_alarms",
+		"Number of members that have raised each alarm, as seen by the watchdog.",
+		[]string{"alarm"}, nil,
+	)
+)
+
+// WatchdogCollector exports the results of a clientconfig.Watchdog. Pass its Observe method as WatchdogOptions.OnReport.
+type WatchdogCollector struct {
+	mtx  sync.Mutex
+	last *clientconfig.WatchdogReport
+}
+
+// NewWatchdogCollector creates a WatchdogCollector.
+func NewWatchdogCollector() *WatchdogCollector {
+	return &WatchdogCollector{}
+}
+
+// Observe records a report from the watchdog.
+func (c *WatchdogCollector) Observe(r clientconfig.WatchdogReport) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.last = &r
+}
+
+// Describe implements prometheus.Collector.
+func (c *WatchdogCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- endpointUpDesc
+	ch <- endpointRTTDesc
+	ch <- alarmsDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *WatchdogCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mtx.Lock()
+	r := c.last
+	c.mtx.Unlock()
+	if r == nil {
+		return
+	}
+	for _, e := range r.Endpoints {
+		up := 1.0
+		if e.Err != nil {
+			up = 0
+		}
+		ch <- prometheus.MustNewConstMetric(endpointUpDesc, prometheus.GaugeValue, up, e.Endpoint)
+		if e.Err == nil {
+			ch <- prometheus.MustNewConstMetric(endpointRTTDesc, prometheus.GaugeValue, e.RTT.Seconds(), e.Endpoint)
+		}
+	}
+	alarms := map[string]int{}
+	for _, a := range r.Alarms {
+		alarms[a.Alarm]++
+	}
+	for a, n := range alarms {
+		ch <- prometheus.MustNewConstMetric(alarmsDesc, prometheus.GaugeValue, float64(n), a)
+	}
+}
@@ -0,0 +1,39 @@
+package clientconfig
+
+import (
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/namespace"
+)
+
+// Wrap confines cli's KV, Watcher and Lease to the key prefix in ETCD_NAMESPACE (or the one set at build time), so multiple applications can share a cluster, and gives KV calls without a deadline the ETCD_REQUEST_TIMEOUT. It does nothing if neither is set.
+// Connect and Dial already do this; use Wrap if you create the client yourself.
+func Wrap(cli *clientv3.Client, opts ...Option) error {
+	settings, err := ReadSettings(opts...)
+	if err != nil {
+		return err
+	}
+	rt, err := requestTimeout(settings)
+	if err != nil {
+		return err
+	}
+	wrapNamespace(cli, namespaceFromSettings(settings))
+	wrapRequestTimeout(cli, rt)
+	return nil
+}
+
+// namespaceFromSettings returns ETCD_NAMESPACE, or the namespace set at build time.
+func namespaceFromSettings(s Settings) string {
+	if v := s["ETCD_NAMESPACE"]; v != "" {
+		return v
+	}
+	return defaultNamespace
+}
+
+func wrapNamespace(cli *clientv3.Client, ns string) {
+	if ns == "" {
+		return
+	}
+	cli.KV = namespace.NewKV(cli.KV, ns)
+	cli.Watcher = namespace.NewWatcher(cli.Watcher, ns)
+	cli.Lease = namespace.NewLease(cli.Lease, ns)
+}
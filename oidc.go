@@ -0,0 +1,94 @@
+package clientconfig
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenSource provides the bearer tokens for WithTokenSource. Token is called for every call, so it should cache tokens until they're about to expire.
+// The oidc module adapts an oauth2.TokenSource.
+type TokenSource interface {
+	Token() (token string, expiry time.Time, err error)
+}
+
+// WithTokenSource sends a token from ts with every call, like ETCD_AUTH_TOKEN but for tokens that expire.
+func WithTokenSource(ts TokenSource) Option {
+	return func(o *options) {
+		o.tokenSource = ts
+	}
+}
+
+// tokenSourceCredentials implements credentials.PerRPCCredentials for a TokenSource.
+type tokenSourceCredentials struct {
+	ts TokenSource
+}
+
+func (t tokenSourceCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	tok, _, err := t.ts.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a token: %v", err)
+	}
+	return map[string]string{"authorization": "Bearer " + tok}, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials. Like for tokenCredentials, ApplySettings checks the endpoints instead.
+func (t tokenSourceCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// OIDCConfig is the configuration of ETCD_OIDC_ISSUER, for RegisterOIDC.
+type OIDCConfig struct {
+	// Issuer is the URL of the OIDC provider, without a trailing slash.
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+var (
+	oidcMtx            sync.Mutex
+	oidcNewTokenSource func(cfg OIDCConfig) TokenSource
+)
+
+// RegisterOIDC makes ETCD_OIDC_ISSUER work: newTokenSource gets the validated configuration and returns where the tokens come from. The token endpoint should be discovered on first use, so building the config doesn't need the network.
+// It's called by the oidc module, which keeps golang.org/x/oauth2 out of this one. RegisterOIDC is meant to be called from init functions and panics if it's called twice.
+func RegisterOIDC(newTokenSource func(cfg OIDCConfig) TokenSource) {
+	oidcMtx.Lock()
+	defer oidcMtx.Unlock()
+	if oidcNewTokenSource != nil {
+		panic("clientconfig: RegisterOIDC called twice")
+	}
+	oidcNewTokenSource = newTokenSource
+}
+
+func newOIDCTokenSource(settings Settings) (TokenSource, error) {
+	issuer := strings.TrimSuffix(settings["ETCD_OIDC_ISSUER"], "/")
+	if !strings.HasPrefix(issuer, "https://") {
+		return nil, errorf(CodeInvalidValue, "ETCD_OIDC_ISSUER", "ETCD_OIDC_ISSUER should be an https:// URL (%q)", issuer)
+	}
+	if settings["ETCD_OIDC_CLIENT_ID"] == "" || settings["ETCD_OIDC_CLIENT_SECRET"] == "" {
+		return nil, errorf(CodeConflictingVariables, "ETCD_OIDC_ISSUER", "ETCD_OIDC_ISSUER needs both ETCD_OIDC_CLIENT_ID and ETCD_OIDC_CLIENT_SECRET")
+	}
+	cfg := OIDCConfig{
+		Issuer:       issuer,
+		ClientID:     settings["ETCD_OIDC_CLIENT_ID"],
+		ClientSecret: settings["ETCD_OIDC_CLIENT_SECRET"],
+	}
+	if v := settings["ETCD_OIDC_SCOPES"]; v != "" {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				cfg.Scopes = append(cfg.Scopes, s)
+			}
+		}
+	}
+	oidcMtx.Lock()
+	newTokenSource := oidcNewTokenSource
+	oidcMtx.Unlock()
+	if newTokenSource == nil {
+		return nil, errorf(CodeInvalidValue, "ETCD_OIDC_ISSUER", "ETCD_OIDC_ISSUER is set; import github.com/Jille/etcd-client-from-env/oidc to support it")
+	}
+	return newTokenSource(cfg), nil
+}
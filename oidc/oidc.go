@@ -0,0 +1,102 @@
+// Package oidc implements ETCD_OIDC_ISSUER: importing it makes clients get bearer tokens from an OIDC provider with the client credentials flow.
+//
+//	import _ "github.com/Jille/etcd-client-from-env/oidc"
+//
+// It lives in its own module so golang.org/x/oauth2 only ends up in programs that need it. TokenSource adapts any other oauth2.TokenSource for clientconfig.WithTokenSource.
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	clientconfig "github.com/Jille/etcd-client-from-env"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+func init() {
+	clientconfig.RegisterOIDC(func(cfg clientconfig.OIDCConfig) clientconfig.TokenSource {
+		return TokenSource(&issuerTokenSource{
+			issuer: cfg.Issuer,
+			cfg: clientcredentials.Config{
+				ClientID:     cfg.ClientID,
+				ClientSecret: cfg.ClientSecret,
+				Scopes:       cfg.Scopes,
+			},
+		})
+	})
+}
+
+// TokenSource adapts ts for clientconfig.WithTokenSource. ts should cache its tokens (see oauth2.ReuseTokenSource); it's asked for every call.
+func TokenSource(ts oauth2.TokenSource) clientconfig.TokenSource {
+	return tokenSource{ts}
+}
+
+type tokenSource struct {
+	ts oauth2.TokenSource
+}
+
+func (t tokenSource) Token() (string, time.Time, error) {
+	tok, err := t.ts.Token()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return tok.AccessToken, tok.Expiry, nil
+}
+
+// issuerTokenSource gets tokens from an OIDC issuer with the client credentials flow. The token endpoint is discovered on first use, so building the config doesn't need the network.
+type issuerTokenSource struct {
+	issuer string
+	cfg    clientcredentials.Config
+
+	mtx sync.Mutex
+	ts  oauth2.TokenSource
+}
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// Token implements oauth2.TokenSource.
+func (s *issuerTokenSource) Token() (*oauth2.Token, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.ts == nil {
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
+		cfg := s.cfg
+		var err error
+		if cfg.TokenURL, err = discoverTokenEndpoint(ctx, s.issuer); err != nil {
+			return nil, err
+		}
+		s.ts = cfg.TokenSource(ctx)
+	}
+	return s.ts.Token()
+}
+
+// discoverTokenEndpoint reads the token_endpoint from the issuer's OpenID configuration.
+func discoverTokenEndpoint(ctx context.Context, issuer string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to discover the OIDC configuration of %s: %v", issuer, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to discover the OIDC configuration of %s: %s", issuer, resp.Status)
+	}
+	var doc struct {
+		TokenEndpoint string `json:"token_endpoint"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return "", fmt.Errorf("failed to parse the OIDC configuration of %s: %v", issuer, err)
+	}
+	if doc.TokenEndpoint == "" {
+		return "", fmt.Errorf("the OIDC configuration of %s has no token_endpoint", issuer)
+	}
+	return doc.TokenEndpoint, nil
+}
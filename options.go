@@ -0,0 +1,238 @@
+package clientconfig
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"strconv"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// Option changes the behavior of ApplyWithOptions.
+type Option func(*options)
+
+type options struct {
+	ctx                context.Context
+	unaryInterceptors  []grpc.UnaryClientInterceptor
+	streamInterceptors []grpc.StreamClientInterceptor
+	warn               func(string)
+	offline            bool
+	env                Environment
+	lenient            bool
+	etcdctlCompat      bool
+	prefix             string
+	cluster            string
+	strict             bool
+	flags              *Flags
+	noExpand           bool
+	logger             *zap.Logger
+	dialOptions        []grpc.DialOption
+	hooks              []Hooks
+	tokenSource        TokenSource
+	require            map[string]bool
+	fsys               fs.FS
+	keepNewlines       bool
+	// dryRun makes ApplySettings only compute the config, without probing or starting anything. What it skips is passed to skipped, if set.
+	dryRun  bool
+	skipped func(string)
+	// fingerprinting is dry-run mode for Changed, which also doesn't generate ETCD_DEV_TLS certificates and reuses the last SRV lookup.
+	fingerprinting bool
+	// devEtcdStarted is told about the etcd ETCD_DEV_AUTOSTART started for this config, so Dial's cleanup can stop it.
+	devEtcdStarted func(p *devEtcdProcess)
+	// inClusterDir and inClusterEndpoint are the defaults InClusterDefaults found.
+	inClusterDir      string
+	inClusterEndpoint string
+	// base is env before applying prefix and cluster.
+	base Environment
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{env: osEnvironment{}}
+	for _, f := range opts {
+		f(o)
+	}
+	if o.fsys != nil {
+		o.env = fsEnvironment{o.env, o.fsys}
+	}
+	o.base = o.env
+	if o.prefix != "" {
+		o.env = prefixedEnvironment{o.env, o.prefix}
+	}
+	if o.cluster != "" {
+		o.env = clusterEnvironment{o.env, o.cluster}
+	}
+	if o.flags != nil {
+		o.env = flagEnvironment{o.env, o.flags}
+	}
+	if o.inClusterDir != "" {
+		o.env = inClusterDirEnvironment{o.env, o.inClusterDir}
+	}
+	o.env = newCredentialsDirEnvironment(o.env, o.base, o)
+	if b, err := strconv.ParseBool(o.env.Getenv("ETCD_EXPAND_VARIABLES")); err == nil && !b {
+		o.noExpand = true
+	}
+	if !o.noExpand {
+		o.env = expandingEnvironment{o.env, o.base}
+	}
+	return o
+}
+
+// warnf reports a warning about the configuration.
+func (o *options) warnf(format string, args ...interface{}) {
+	if o.warn != nil {
+		o.warn(fmt.Sprintf(format, args...))
+		return
+	}
+	warnf(format, args...)
+}
+
+// skip reports that a check or action was skipped in dry-run mode.
+func (o *options) skip(what string) {
+	if o.skipped != nil {
+		o.skipped(what)
+	}
+}
+
+// withDryRun makes ApplySettings skip everything with side effects, like connecting to the endpoints. skipped is called with what was skipped.
+func withDryRun(skipped func(string)) Option {
+	return func(o *options) {
+		o.dryRun = true
+		o.skipped = skipped
+	}
+}
+
+// violation returns err, or reports it as a warning and returns nil in lenient mode.
+func (o *options) violation(err error) error {
+	if err != nil && o.lenient {
+		o.warnf("%v", err)
+		return nil
+	}
+	return err
+}
+
+// warnf logs a warning about the configuration.
+func warnf(format string, args ...interface{}) {
+	log.Printf("clientconfig: "+format, args...)
+}
+
+// WithContext sets clientv3.Config.Context, which binds the lifetime of the client (including its internal goroutines and watches) to ctx.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithEnvironment reads variables and files from env instead of the process environment and filesystem.
+func WithEnvironment(env Environment) Option {
+	return func(o *options) {
+		o.env = env
+	}
+}
+
+// WithKeepNewlines keeps the trailing newline in the files of ETCD_USERNAME_FILE, ETCD_PASSWORD_FILE, ETCD_AUTH_TOKEN_FILE and the like, for passwords that really end in a newline.
+func WithKeepNewlines() Option {
+	return func(o *options) {
+		o.keepNewlines = true
+	}
+}
+
+// WithEtcdctlCompat also reads the variables etcdctl uses (ETCDCTL_ENDPOINTS, ETCDCTL_CACERT, ETCDCTL_CERT, ETCDCTL_KEY, ETCDCTL_USER, ETCDCTL_PASSWORD, ETCDCTL_INSECURE_SKIP_TLS_VERIFY, ETCDCTL_DISCOVERY_SRV and ETCDCTL_DISCOVERY_SRV_NAME). The ETCD_* variables take precedence.
+func WithEtcdctlCompat() Option {
+	return func(o *options) {
+		o.etcdctlCompat = true
+	}
+}
+
+// WithPrefix reads all variables with the given prefix, like MYAPP_ETCD_ENDPOINTS for WithPrefix("MYAPP_"), so a process can be configured for multiple clusters.
+func WithPrefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// WithLenientValidation turns consistency checks of the configuration (like endpoints mixing TLS and plaintext) into warnings, for migrations where you can't fix everything at once.
+func WithLenientValidation() Option {
+	return func(o *options) {
+		o.lenient = true
+	}
+}
+
+// WithoutExpansion disables expanding $VAR and ${VAR} in values, like ETCD_EXPAND_VARIABLES=false.
+func WithoutExpansion() Option {
+	return func(o *options) {
+		o.noExpand = true
+	}
+}
+
+// WithStrict makes unknown ETCD_* variables an error, like ETCD_STRICT=1, so typos like ETCD_ENPOINTS don't go unnoticed.
+func WithStrict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// WithOfflineResolution forbids config sources that might need the network, like ETCD_OFFLINE_RESOLUTION=1. Using them becomes an error.
+func WithOfflineResolution() Option {
+	return func(o *options) {
+		o.offline = true
+	}
+}
+
+// WithoutSideEffects makes ApplySettings only resolve the configuration, for tools that hand it to something else: ETCD_ENDPOINT_HEALTH_CHECK doesn't probe the endpoints and ETCD_DEV_AUTOSTART doesn't start an etcd (one that's already running is still used) and ETCD_DEV_TLS doesn't generate certificates (only a CA that's already in ETCD_DEV_TLS_DIR is used, without a client certificate).
+func WithoutSideEffects() Option {
+	return withDryRun(nil)
+}
+
+// WithUnaryInterceptors adds unary client interceptors to the generated DialOptions.
+//
+// The order of interceptors is: clientv3's own retry interceptor, then the ones installed by this package, then the ones passed with this option in the given order.
+func WithUnaryInterceptors(interceptors ...grpc.UnaryClientInterceptor) Option {
+	return func(o *options) {
+		o.unaryInterceptors = append(o.unaryInterceptors, interceptors...)
+	}
+}
+
+// WithStreamInterceptors adds stream client interceptors to the generated DialOptions. They're ordered like WithUnaryInterceptors.
+func WithStreamInterceptors(interceptors ...grpc.StreamClientInterceptor) Option {
+	return func(o *options) {
+		o.streamInterceptors = append(o.streamInterceptors, interceptors...)
+	}
+}
+
+// WithDialOptions adds DialOptions to the generated config, after the ones this package installs, so they can't be clobbered by later versions of it. Use WithUnaryInterceptors and WithStreamInterceptors for interceptors, or they'll replace the ones installed by this package.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) {
+		o.dialOptions = append(o.dialOptions, opts...)
+	}
+}
+
+// interceptors collects the interceptors for a config, so they can be installed as a single chain.
+type interceptors struct {
+	unary  []grpc.UnaryClientInterceptor
+	stream []grpc.StreamClientInterceptor
+}
+
+// dialOptions returns the DialOptions installing our own interceptors followed by the caller's.
+// We use the Chain variants because clientv3 uses grpc.WithUnaryInterceptor itself, which would override ours.
+func (i interceptors) dialOptions(o *options) []grpc.DialOption {
+	var ret []grpc.DialOption
+	if u := append(i.unary, o.unaryInterceptors...); len(u) > 0 {
+		ret = append(ret, grpc.WithChainUnaryInterceptor(u...))
+	}
+	if s := append(i.stream, o.streamInterceptors...); len(s) > 0 {
+		ret = append(ret, grpc.WithChainStreamInterceptor(s...))
+	}
+	return ret
+}
+
+// appendDialOptions adds DialOptions to c without modifying the caller's slice.
+func appendDialOptions(c *clientv3.Config, opts ...grpc.DialOption) {
+	if len(opts) == 0 {
+		return
+	}
+	c.DialOptions = append(append([]grpc.DialOption{}, c.DialOptions...), opts...)
+}
@@ -0,0 +1,36 @@
+// Package otel traces and measures the etcd calls of clients configured by clientconfig with OpenTelemetry.
+//
+// Importing it registers the ETCD_OTEL variable: ETCD_OTEL=true instruments every client built from the environment, without code changes beyond the import.
+package otel
+
+import (
+	"fmt"
+	"strconv"
+
+	clientconfig "github.com/Jille/etcd-client-from-env"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+)
+
+func init() {
+	clientconfig.RegisterVariable("ETCD_OTEL", func(c *clientv3.Config, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a bool", value)
+		}
+		if b {
+			c.DialOptions = append(append([]grpc.DialOption{}, c.DialOptions...), dialOption())
+		}
+		return nil
+	})
+}
+
+// WithOpenTelemetry instruments every RPC of the client with otelgrpc, using the global TracerProvider and MeterProvider unless opts say otherwise. Don't combine it with ETCD_OTEL=true, or every call is recorded twice.
+func WithOpenTelemetry(opts ...otelgrpc.Option) clientconfig.Option {
+	return clientconfig.WithDialOptions(dialOption(opts...))
+}
+
+func dialOption(opts ...otelgrpc.Option) grpc.DialOption {
+	return grpc.WithStatsHandler(otelgrpc.NewClientHandler(opts...))
+}
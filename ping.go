@@ -0,0 +1,87 @@
+package clientconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// ClusterInfo is the result of Ping.
+type ClusterInfo struct {
+	// ClusterID is the ID of the cluster, as reported by the first endpoint that answered.
+	ClusterID uint64
+	// Leader is the member ID of the leader, and LeaderEndpoint the endpoint that is the leader, if it's one of ours.
+	Leader         uint64
+	LeaderEndpoint string
+	// Endpoints has the results for every endpoint, in the order of c.Endpoints.
+	Endpoints []EndpointStatus
+}
+
+// EndpointStatus is the result of a Status call against a single endpoint. Only Endpoint, RTT and Err are set if it failed.
+type EndpointStatus struct {
+	Endpoint  string
+	Version   string
+	MemberID  uint64
+	IsLearner bool
+	// DBSize is the size of the backend database in bytes.
+	DBSize int64
+	RTT    time.Duration
+	Err    error
+}
+
+// Ping creates a client for c, runs Status against every endpoint concurrently and closes the client again. It's meant for startup probes and readiness checks that use exactly the config the application would.
+// It returns an error only if the client couldn't be created (for example because the credentials were rejected) or no endpoint answered; problems with individual endpoints are in EndpointStatus.Err.
+func Ping(ctx context.Context, c clientv3.Config) (ClusterInfo, error) {
+	if len(c.Endpoints) == 0 {
+		return ClusterInfo{}, errors.New("no endpoints configured")
+	}
+	c.Context = ctx
+	cli, err := clientv3.New(c)
+	if err != nil {
+		return ClusterInfo{}, err
+	}
+	defer cli.Close()
+	info := ClusterInfo{Endpoints: make([]EndpointStatus, len(c.Endpoints))}
+	resps := make([]*clientv3.StatusResponse, len(c.Endpoints))
+	var wg sync.WaitGroup
+	for i, ep := range c.Endpoints {
+		wg.Add(1)
+		go func(i int, ep string) {
+			defer wg.Done()
+			start := time.Now()
+			resps[i], info.Endpoints[i].Err = cli.Status(ctx, ep)
+			info.Endpoints[i].Endpoint = ep
+			info.Endpoints[i].RTT = time.Since(start)
+		}(i, ep)
+	}
+	wg.Wait()
+	var lastErr error
+	answered := false
+	for i, s := range info.Endpoints {
+		if s.Err != nil {
+			lastErr = fmt.Errorf("%s: %v", s.Endpoint, s.Err)
+			continue
+		}
+		r := resps[i]
+		info.Endpoints[i].Version = r.Version
+		info.Endpoints[i].MemberID = r.Header.MemberId
+		info.Endpoints[i].IsLearner = r.IsLearner
+		info.Endpoints[i].DBSize = r.DbSize
+		if !answered {
+			answered = true
+			info.ClusterID = r.Header.ClusterId
+			info.Leader = r.Leader
+		}
+		if r.Leader != 0 && r.Leader == r.Header.MemberId {
+			info.LeaderEndpoint = s.Endpoint
+		}
+	}
+	if !answered {
+		return info, lastErr
+	}
+	return info, nil
+}
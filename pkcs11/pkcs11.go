@@ -0,0 +1,138 @@
+// Package pkcs11 lets ETCD_CLIENT_KEY be a PKCS#11 URI (RFC 7512), so the private key of the client certificate stays in an HSM, smart card or TPM and is only used to sign the TLS handshake.
+//
+// Importing it registers the pkcs11 key loader (see clientconfig.RegisterKeyLoader):
+//
+//	ETCD_CLIENT_CERT_FILE=/etc/etcd/client.crt
+//	ETCD_CLIENT_KEY=pkcs11:token=etcd;object=client?module-path=/usr/lib/softhsm/libsofthsm2.so
+//	ETCD_CLIENT_KEY_PASSWORD_FILE=/run/secrets/etcd-pin
+//
+// The token is selected with the token, serial or slot-id attribute and the key with object (its label), id or both. The module-path attribute is required. The PIN comes from the pin-value or pin-source attribute, or otherwise from ETCD_CLIENT_KEY_PASSWORD.
+//
+// It uses cgo to load the PKCS#11 module. Tokens stay logged in for the lifetime of the process, so rotated certificates for the same key don't need a new login.
+package pkcs11
+
+import (
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	clientconfig "github.com/Jille/etcd-client-from-env"
+	"github.com/ThalesIgnite/crypto11"
+)
+
+func init() {
+	clientconfig.RegisterKeyLoader("pkcs11", load)
+}
+
+var (
+	contextsMtx sync.Mutex
+	// contexts are the tokens we've logged into, keyed by crypto11.Config.
+	contexts = map[crypto11.Config]*crypto11.Context{}
+)
+
+func load(uri string, password []byte, cert *x509.Certificate) (crypto.Signer, error) {
+	attrs, err := parseURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	cfg := crypto11.Config{
+		Path:        attrs["module-path"],
+		TokenLabel:  attrs["token"],
+		TokenSerial: attrs["serial"],
+	}
+	if cfg.Path == "" {
+		return nil, errors.New("the PKCS#11 URI in ETCD_CLIENT_KEY needs a module-path attribute, like ?module-path=/usr/lib/softhsm/libsofthsm2.so")
+	}
+	if v, ok := attrs["slot-id"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid slot-id %q in the PKCS#11 URI", v)
+		}
+		cfg.SlotNumber = &n
+	}
+	switch {
+	case attrs["pin-value"] != "":
+		cfg.Pin = attrs["pin-value"]
+	case attrs["pin-source"] != "":
+		b, err := ioutil.ReadFile(strings.TrimPrefix(attrs["pin-source"], "file:"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the pin-source of the PKCS#11 URI: %v", err)
+		}
+		cfg.Pin = strings.TrimRight(string(b), "\r\n")
+	default:
+		cfg.Pin = string(password)
+	}
+	id, label := []byte(attrs["id"]), []byte(attrs["object"])
+	if len(id) == 0 && len(label) == 0 {
+		return nil, errors.New("the PKCS#11 URI in ETCD_CLIENT_KEY needs an object or id attribute to select the key")
+	}
+	if len(id) == 0 {
+		id = nil
+	}
+	if len(label) == 0 {
+		label = nil
+	}
+	ctx, err := getContext(cfg)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ctx.FindKeyPair(id, label)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the key in the PKCS#11 token: %v", err)
+	}
+	if signer == nil {
+		return nil, errors.New("the PKCS#11 token doesn't have the key in ETCD_CLIENT_KEY")
+	}
+	return signer, nil
+}
+
+func getContext(cfg crypto11.Config) (*crypto11.Context, error) {
+	contextsMtx.Lock()
+	defer contextsMtx.Unlock()
+	if ctx, ok := contexts[cfg]; ok {
+		return ctx, nil
+	}
+	// Configure keeps a pointer to the config, so give it its own copy.
+	c := cfg
+	ctx, err := crypto11.Configure(&c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the PKCS#11 token: %v", err)
+	}
+	contexts[cfg] = ctx
+	return ctx, nil
+}
+
+// parseURI returns the path and query attributes of a PKCS#11 URI, percent-decoded.
+func parseURI(uri string) (map[string]string, error) {
+	rest, ok := strings.CutPrefix(uri, "pkcs11:")
+	if !ok {
+		return nil, fmt.Errorf("%q isn't a PKCS#11 URI", uri)
+	}
+	path, query, _ := strings.Cut(rest, "?")
+	attrs := map[string]string{}
+	for _, part := range []struct {
+		s, sep string
+	}{{path, ";"}, {query, "&"}} {
+		if part.s == "" {
+			continue
+		}
+		for _, a := range strings.Split(part.s, part.sep) {
+			k, v, ok := strings.Cut(a, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid attribute %q in the PKCS#11 URI", a)
+			}
+			dv, err := url.PathUnescape(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for %s in the PKCS#11 URI: %v", k, err)
+			}
+			attrs[k] = dv
+		}
+	}
+	return attrs, nil
+}
@@ -0,0 +1,107 @@
+package clientconfig
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// profiles are the bundles of tuning selectable with ETCD_PROFILE.
+var profiles = map[string]func(c *clientv3.Config){
+	// development is for a local etcd on a workstation: default to localhost and fail fast.
+	"development": func(c *clientv3.Config) {
+		if len(c.Endpoints) == 0 {
+			c.Endpoints = []string{"localhost:2379"}
+		}
+		c.DialTimeout = 2 * time.Second
+		c.DialKeepAliveTime = 10 * time.Second
+		c.DialKeepAliveTimeout = 3 * time.Second
+	},
+	// production is for services: be patient with a busy cluster and refuse outdated ones. It also requires TLS, see profileRequirements.
+	"production": func(c *clientv3.Config) {
+		c.DialTimeout = 30 * time.Second
+		c.DialKeepAliveTime = 30 * time.Second
+		c.DialKeepAliveTimeout = 10 * time.Second
+		c.RejectOldCluster = true
+	},
+	// local is for etcd on the same host: fail fast.
+	"local": func(c *clientv3.Config) {
+		c.DialTimeout = 2 * time.Second
+		c.DialKeepAliveTime = 10 * time.Second
+		c.DialKeepAliveTimeout = 3 * time.Second
+	},
+	// lan is for etcd in the same datacenter.
+	"lan": func(c *clientv3.Config) {
+		c.DialTimeout = 5 * time.Second
+		c.DialKeepAliveTime = 30 * time.Second
+		c.DialKeepAliveTimeout = 10 * time.Second
+	},
+	// wan is for etcd in another region: be patient, compress and back off further between reconnects.
+	"wan": func(c *clientv3.Config) {
+		c.DialTimeout = 30 * time.Second
+		c.DialKeepAliveTime = time.Minute
+		c.DialKeepAliveTimeout = 20 * time.Second
+		appendDialOptions(c,
+			grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)),
+			grpc.WithConnectParams(grpc.ConnectParams{
+				Backoff: backoff.Config{
+					BaseDelay:  2 * time.Second,
+					Multiplier: 1.6,
+					Jitter:     0.2,
+					MaxDelay:   2 * time.Minute,
+				},
+				MinConnectTimeout: 20 * time.Second,
+			}),
+		)
+	},
+	// small is for constrained devices: cap message sizes and use small buffers and flow control windows.
+	"small": func(c *clientv3.Config) {
+		c.MaxCallSendMsgSize = 1536 * 1024 // etcd's default request size limit
+		c.MaxCallRecvMsgSize = 4 * 1024 * 1024
+		appendDialOptions(c,
+			grpc.WithReadBufferSize(8*1024),
+			grpc.WithWriteBufferSize(8*1024),
+			// Setting the window sizes disables growing them dynamically.
+			grpc.WithInitialWindowSize(64*1024),
+			grpc.WithInitialConnWindowSize(64*1024),
+		)
+	},
+}
+
+// profileRequirements are the requirements (see ETCD_REQUIRE) a profile adds when it's selected with ETCD_PROFILE.
+var profileRequirements = map[string][]string{
+	"production": {"tls"},
+}
+
+// DefaultsFor returns Defaults with the given comma separated list of profiles (see ETCD_PROFILE) applied, like "production" or "development".
+// The requirements of a profile, like the TLS production insists on, can't be expressed in a clientv3.Config, so pass RequireTLS to Apply as well, or select the profile with ETCD_PROFILE instead.
+func DefaultsFor(profile string) (clientv3.Config, error) {
+	c := Defaults()
+	if err := applyProfiles(&c, profile); err != nil {
+		return clientv3.Config{}, err
+	}
+	return c, nil
+}
+
+// applyProfiles applies the comma separated list of profiles to c, in order.
+func applyProfiles(c *clientv3.Config, names string) error {
+	for _, n := range strings.Split(names, ",") {
+		n = strings.TrimSpace(n)
+		p, ok := profiles[n]
+		if !ok {
+			var known []string
+			for k := range profiles {
+				known = append(known, k)
+			}
+			sort.Strings(known)
+			return errorf(CodeInvalidValue, "ETCD_PROFILE", "unknown profile %q in ETCD_PROFILE (known: %s)", n, strings.Join(known, ", "))
+		}
+		p(c)
+	}
+	return nil
+}
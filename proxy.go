@@ -0,0 +1,151 @@
+package clientconfig
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+)
+
+// newProxyDialer returns a dialer that connects through the proxy in ETCD_PROXY.
+// v is a socks5://, socks5h:// or http:// URL, or "env" to use HTTPS_PROXY (or ALL_PROXY) and NO_PROXY like gRPC's default dialer does.
+func newProxyDialer(o *options, v string) (dialFunc, error) {
+	if v == "env" {
+		return envProxyDialer(o)
+	}
+	return proxyURLDialer(v)
+}
+
+func proxyURLDialer(v string) (dialFunc, error) {
+	u, err := url.Parse(v)
+	if err != nil || u.Host == "" {
+		return nil, errorf(CodeInvalidValue, "ETCD_PROXY", "failed to parse ETCD_PROXY as a URL like socks5://host:1080 or http://host:3128")
+	}
+	switch u.Scheme {
+	case "socks5", "socks5h":
+		var auth *proxy.Auth
+		if u.User != nil {
+			p, _ := u.User.Password()
+			auth = &proxy.Auth{User: u.User.Username(), Password: p}
+		}
+		d, err := proxy.SOCKS5("tcp", u.Host, auth, &net.Dialer{})
+		if err != nil {
+			return nil, errorf(CodeInvalidValue, "ETCD_PROXY", "invalid ETCD_PROXY: %v", err)
+		}
+		cd := d.(proxy.ContextDialer)
+		return func(ctx context.Context, addr string) (net.Conn, error) {
+			if network, _ := splitDialTarget(addr); network != "tcp" {
+				return baseDial(ctx, addr)
+			}
+			return cd.DialContext(ctx, "tcp", addr)
+		}, nil
+	case "http":
+		return func(ctx context.Context, addr string) (net.Conn, error) {
+			if network, _ := splitDialTarget(addr); network != "tcp" {
+				return baseDial(ctx, addr)
+			}
+			return dialConnect(ctx, u, addr)
+		}, nil
+	default:
+		return nil, errorf(CodeInvalidValue, "ETCD_PROXY", "unsupported ETCD_PROXY scheme %q (should be socks5, socks5h or http)", u.Scheme)
+	}
+}
+
+// envProxyDialer picks the proxy per address from HTTPS_PROXY, ALL_PROXY and NO_PROXY (or their lowercase versions).
+func envProxyDialer(o *options) (dialFunc, error) {
+	get := func(k string) string {
+		if v := o.base.Getenv(k); v != "" {
+			return v
+		}
+		return o.base.Getenv(strings.ToLower(k))
+	}
+	cfg := httpproxy.Config{
+		HTTPSProxy: get("HTTPS_PROXY"),
+		NoProxy:    get("NO_PROXY"),
+	}
+	if cfg.HTTPSProxy == "" {
+		cfg.HTTPSProxy = get("ALL_PROXY")
+	}
+	if cfg.HTTPSProxy == "" {
+		return nil, errorf(CodeInvalidValue, "ETCD_PROXY", "ETCD_PROXY is env, but neither HTTPS_PROXY nor ALL_PROXY is set")
+	}
+	if !strings.Contains(cfg.HTTPSProxy, "://") {
+		// Like net/http, a bare host:port is taken to be an HTTP proxy.
+		cfg.HTTPSProxy = "http://" + cfg.HTTPSProxy
+	}
+	viaProxy, err := proxyURLDialer(cfg.HTTPSProxy)
+	if err != nil {
+		return nil, err
+	}
+	proxyFor := cfg.ProxyFunc()
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		if network, _ := splitDialTarget(addr); network == "tcp" {
+			if pu, err := proxyFor(&url.URL{Scheme: "https", Host: addr}); err != nil {
+				return nil, err
+			} else if pu != nil {
+				return viaProxy(ctx, addr)
+			}
+		}
+		return baseDial(ctx, addr)
+	}, nil
+}
+
+// dialConnect connects to addr through the HTTP proxy at u with a CONNECT request.
+func dialConnect(ctx context.Context, u *url.URL, addr string) (net.Conn, error) {
+	conn, err := baseDial(ctx, u.Host)
+	if err != nil {
+		return nil, err
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(dl)
+	}
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if u.User != nil {
+		p, _ := u.User.Password()
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(u.User.Username()+":"+p)))
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send CONNECT to proxy %s: %v", u.Host, err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read CONNECT response from proxy %s: %v", u.Host, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		// Like net/http's Transport, we only look at the body when the tunnel wasn't established, as reading it could consume the tunneled stream.
+		resp.Body.Close()
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s refused to connect to %s: %s", u.Host, addr, resp.Status)
+	}
+	conn.SetDeadline(time.Time{})
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn returns what the bufio.Reader already read from Conn before reading from Conn itself.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
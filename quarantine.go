@@ -0,0 +1,308 @@
+package clientconfig
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// QuarantineOptions configures StartQuarantine.
+type QuarantineOptions struct {
+	// Threshold is the failure score at which an endpoint gets quarantined. Defaults to 3.
+	Threshold float64
+	// HalfLife is how quickly old failures are forgotten. Defaults to a minute.
+	HalfLife time.Duration
+	// CheckInterval is how often healthy endpoints are checked. Defaults to 10 seconds.
+	CheckInterval time.Duration
+	// ProbeInterval is the initial delay before a quarantined endpoint is probed again. It doubles (up to MaxProbeInterval) every time the probe fails and is jittered. Defaults to 30 seconds.
+	ProbeInterval time.Duration
+	// MaxProbeInterval defaults to 10 minutes.
+	MaxProbeInterval time.Duration
+	// Timeout for each check. Defaults to 5 seconds.
+	Timeout time.Duration
+	// OnChange is called with the endpoint that was quarantined or released.
+	OnChange func(endpoint string, quarantined bool)
+}
+
+type endpointState struct {
+	score       float64
+	updated     time.Time
+	quarantined bool
+	failedProbe int
+	nextProbe   time.Time
+}
+
+// Quarantine tracks failures per endpoint and temporarily removes endpoints that keep failing from the client. Create it with StartQuarantine.
+type Quarantine struct {
+	cli    *clientv3.Client
+	opts   QuarantineOptions
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mtx       sync.Mutex
+	endpoints map[string]*endpointState
+	changes   []quarantineChange
+}
+
+type quarantineChange struct {
+	endpoint    string
+	quarantined bool
+}
+
+// StartQuarantine starts a goroutine that checks the endpoints of cli and calls cli.SetEndpoints without the ones that keep failing.
+// Quarantined endpoints are probed again with exponential backoff and jitter, and restored once they answer. The last healthy endpoint is never removed.
+//
+// Endpoints restored by auto syncing are removed again on the next check.
+func StartQuarantine(cli *clientv3.Client, opts QuarantineOptions) *Quarantine {
+	if opts.Threshold <= 0 {
+		opts.Threshold = 3
+	}
+	if opts.HalfLife <= 0 {
+		opts.HalfLife = time.Minute
+	}
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = 10 * time.Second
+	}
+	if opts.ProbeInterval <= 0 {
+		opts.ProbeInterval = 30 * time.Second
+	}
+	if opts.MaxProbeInterval <= 0 {
+		opts.MaxProbeInterval = 10 * time.Minute
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Quarantine{
+		cli:       cli,
+		opts:      opts,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		endpoints: map[string]*endpointState{},
+	}
+	go q.run(ctx)
+	return q
+}
+
+// Stop stops checking. Quarantined endpoints are not restored.
+func (q *Quarantine) Stop() {
+	q.cancel()
+	<-q.done
+}
+
+// Quarantined returns the endpoints that are currently quarantined.
+func (q *Quarantine) Quarantined() []string {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	var ret []string
+	for ep, s := range q.endpoints {
+		if s.quarantined {
+			ret = append(ret, ep)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// ReportFailure lets the application report a failed request against an endpoint, in addition to the periodic checks. If that quarantines the endpoint, it's removed from the client right away.
+func (q *Quarantine) ReportFailure(endpoint string) {
+	q.mtx.Lock()
+	q.prune()
+	q.fail(endpoint, time.Now())
+	q.apply(nil)
+	q.mtx.Unlock()
+	q.flushChanges()
+}
+
+func (q *Quarantine) run(ctx context.Context) {
+	defer close(q.done)
+	t := time.NewTicker(q.opts.CheckInterval)
+	defer t.Stop()
+	for {
+		q.check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (q *Quarantine) check(ctx context.Context) {
+	now := time.Now()
+	q.mtx.Lock()
+	q.prune()
+	var targets []string
+	for ep, s := range q.endpoints {
+		if !s.quarantined || !now.Before(s.nextProbe) {
+			targets = append(targets, ep)
+		}
+	}
+	q.mtx.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, q.opts.Timeout)
+	defer cancel()
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, ep := range targets {
+		wg.Add(1)
+		go func(i int, ep string) {
+			defer wg.Done()
+			_, errs[i] = q.cli.Status(ctx, ep)
+		}(i, ep)
+	}
+	wg.Wait()
+	if ctx.Err() == context.Canceled {
+		return
+	}
+
+	q.mtx.Lock()
+	defer q.flushChanges()
+	defer q.mtx.Unlock()
+	now = time.Now()
+	var released []string
+	for i, ep := range targets {
+		s, ok := q.endpoints[ep]
+		if !ok {
+			// Removed by ReportFailure's pruning in the meantime.
+			continue
+		}
+		if errs[i] == nil {
+			if s.quarantined {
+				s.quarantined = false
+				s.score = 0
+				s.failedProbe = 0
+				released = append(released, ep)
+				q.notify(ep, false)
+			}
+			continue
+		}
+		if s.quarantined {
+			s.failedProbe++
+			s.nextProbe = now.Add(q.probeDelay(s.failedProbe))
+			continue
+		}
+		q.fail(ep, now)
+	}
+	q.apply(released)
+}
+
+// apply calls SetEndpoints on the client without the quarantined endpoints, and with the released ones. q.mtx must be held.
+func (q *Quarantine) apply(released []string) {
+	current := append([]string{}, q.cli.Endpoints()...)
+	active := released
+	for _, ep := range current {
+		if s, ok := q.endpoints[ep]; !ok || !s.quarantined {
+			active = append(active, ep)
+		}
+	}
+	if len(active) == 0 {
+		return
+	}
+	active = uniqueStrings(active)
+	sort.Strings(current)
+	if !equalStrings(active, current) {
+		q.cli.SetEndpoints(active...)
+	}
+}
+
+// prune starts tracking the current endpoints of the client, and forgets the ones that were removed from it (by SetEndpoints or auto syncing) and aren't quarantined. q.mtx must be held.
+func (q *Quarantine) prune() {
+	current := map[string]bool{}
+	for _, ep := range q.cli.Endpoints() {
+		current[ep] = true
+		if _, ok := q.endpoints[ep]; !ok {
+			q.endpoints[ep] = &endpointState{updated: time.Now()}
+		}
+	}
+	for ep, s := range q.endpoints {
+		if !current[ep] && !s.quarantined {
+			delete(q.endpoints, ep)
+		}
+	}
+}
+
+// fail records a failure of ep and quarantines it if needed. q.mtx must be held.
+func (q *Quarantine) fail(ep string, now time.Time) {
+	s, ok := q.endpoints[ep]
+	if !ok || s.quarantined {
+		return
+	}
+	s.score = s.score*math.Pow(0.5, float64(now.Sub(s.updated))/float64(q.opts.HalfLife)) + 1
+	s.updated = now
+	if s.score < q.opts.Threshold {
+		return
+	}
+	healthy := 0
+	for _, o := range q.endpoints {
+		if !o.quarantined {
+			healthy++
+		}
+	}
+	if healthy <= 1 {
+		return
+	}
+	s.quarantined = true
+	s.failedProbe = 0
+	s.nextProbe = now.Add(q.probeDelay(0))
+	q.notify(ep, true)
+}
+
+func (q *Quarantine) probeDelay(failures int) time.Duration {
+	d := q.opts.ProbeInterval
+	for i := 0; i < failures && d < q.opts.MaxProbeInterval; i++ {
+		d *= 2
+	}
+	if d > q.opts.MaxProbeInterval {
+		d = q.opts.MaxProbeInterval
+	}
+	// Jitter by ±20% so clients don't probe in lockstep.
+	return time.Duration(float64(d) * (0.8 + 0.4*rand.Float64()))
+}
+
+// notify queues a call to OnChange. q.mtx must be held.
+func (q *Quarantine) notify(ep string, quarantined bool) {
+	if q.opts.OnChange != nil {
+		q.changes = append(q.changes, quarantineChange{ep, quarantined})
+	}
+}
+
+// flushChanges calls OnChange for the queued changes. q.mtx must not be held, so the callback can call Quarantined.
+func (q *Quarantine) flushChanges() {
+	q.mtx.Lock()
+	changes := q.changes
+	q.changes = nil
+	q.mtx.Unlock()
+	for _, c := range changes {
+		q.opts.OnChange(c.endpoint, c.quarantined)
+	}
+}
+
+// uniqueStrings sorts a and removes duplicates.
+func uniqueStrings(a []string) []string {
+	sort.Strings(a)
+	var ret []string
+	for _, s := range a {
+		if len(ret) == 0 || s != ret[len(ret)-1] {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
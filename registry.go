@@ -0,0 +1,141 @@
+package clientconfig
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Registry shares clients between the subsystems of an application. ConnectNamed returns the same client for every name whose configuration resolves to the same settings, and closes it once all of its users have released it. Create it with NewRegistry.
+type Registry struct {
+	opts []Option
+
+	mtx     sync.Mutex
+	clients map[[sha256.Size]byte]*registryClient
+	closed  bool
+}
+
+type registryClient struct {
+	ready chan struct{}
+	cli   *clientv3.Client
+	err   error
+	// refs is the number of users that haven't released the client yet.
+	refs int
+}
+
+// NewRegistry returns an empty Registry. The options are used for every ConnectNamed call.
+func NewRegistry(opts ...Option) *Registry {
+	return &Registry{
+		opts:    opts,
+		clients: map[[sha256.Size]byte]*registryClient{},
+	}
+}
+
+// ConnectNamed reads the configuration of the named cluster (see WithCluster, or "" for the plain ETCD_* variables) and returns a client for it like Connect, sharing an existing client if one has the same resolved settings.
+// Call release when you're done with the client instead of closing it; the client is closed when its last user releases it. Failures aren't cached, so the next call tries again.
+func (r *Registry) ConnectNamed(ctx context.Context, name string) (cli *clientv3.Client, release func(), err error) {
+	opts := r.opts
+	if name != "" {
+		opts = append(opts[:len(opts):len(opts)], WithCluster(name))
+	}
+	settings, err := ReadSettingsContext(ctx, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	key := settingsHash(settings)
+	r.mtx.Lock()
+	if r.closed {
+		r.mtx.Unlock()
+		return nil, nil, errors.New("Registry is closed")
+	}
+	rc, ok := r.clients[key]
+	if !ok {
+		rc = &registryClient{ready: make(chan struct{})}
+		r.clients[key] = rc
+	}
+	rc.refs++
+	r.mtx.Unlock()
+	if !ok {
+		cli, err := connectSettings(ctx, settings, opts)
+		r.mtx.Lock()
+		rc.cli, rc.err = cli, err
+		if rc.err != nil {
+			if r.clients[key] == rc {
+				delete(r.clients, key)
+			}
+		} else if r.closed {
+			rc.cli.Close()
+			rc.cli, rc.err = nil, errors.New("Registry is closed")
+		}
+		r.mtx.Unlock()
+		close(rc.ready)
+	} else {
+		select {
+		case <-rc.ready:
+		case <-ctx.Done():
+			r.release(key, rc)
+			return nil, nil, ctx.Err()
+		}
+	}
+	if rc.err != nil {
+		return nil, nil, rc.err
+	}
+	var once sync.Once
+	return rc.cli, func() { once.Do(func() { r.release(key, rc) }) }, nil
+}
+
+// release drops a reference to rc and closes its client if it was the last one. The client is set under mtx, so this is safe while it's still connecting.
+// Once the registry is closed, Close has taken over its clients and release only drops the reference.
+func (r *Registry) release(key [sha256.Size]byte, rc *registryClient) {
+	r.mtx.Lock()
+	rc.refs--
+	last := rc.refs == 0 && rc.cli != nil && !r.closed && r.clients[key] == rc
+	if last {
+		delete(r.clients, key)
+	}
+	r.mtx.Unlock()
+	if last {
+		rc.cli.Close()
+	}
+}
+
+// Close closes all clients, even if they haven't been released, and makes further ConnectNamed calls fail. It returns the first error.
+func (r *Registry) Close() error {
+	r.mtx.Lock()
+	r.closed = true
+	clients := r.clients
+	r.clients = map[[sha256.Size]byte]*registryClient{}
+	r.mtx.Unlock()
+	var err error
+	for _, rc := range clients {
+		<-rc.ready
+		if rc.cli == nil {
+			continue
+		}
+		if cerr := rc.cli.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// settingsHash identifies the resolved settings, without keeping the secrets in them around.
+func settingsHash(s Settings) [sha256.Size]byte {
+	keys := make([]string, 0, len(s))
+	for k := range s {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%q=%q\n", k, s[k])
+	}
+	var ret [sha256.Size]byte
+	h.Sum(ret[:0])
+	return ret
+}
@@ -0,0 +1,35 @@
+package clientconfig
+
+import (
+	"context"
+	"os"
+	"os/signal"
+)
+
+// Reload resolves the configuration again with GetContext and rotates to a client using it.
+// If you customized the defaults, resolve the config yourself and call Rotate instead.
+func (r *CredentialRotator) Reload(ctx context.Context, opts ...Option) error {
+	c, err := ApplyContext(ctx, Defaults(), opts...)
+	if err != nil {
+		return err
+	}
+	return r.Rotate(c)
+}
+
+// ReloadOnSignal calls Reload every time one of sigs (typically syscall.SIGHUP) is received, until ctx is cancelled. Errors are passed to onError, which may be nil.
+// On Windows services, use HandleServiceControl instead.
+func ReloadOnSignal(ctx context.Context, r *CredentialRotator, onError func(error), sigs ...os.Signal) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	defer signal.Stop(ch)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+			if err := r.Reload(ctx); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package clientconfig
+
+import (
+	"context"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+// HandleServiceControl reloads r if req is a svc.ParamChange or one of the given custom control codes (128-255), mirroring SIGHUP on Unix.
+// Call it from the loop of your svc.Handler and include svc.AcceptParamChange in the accepted commands. It returns whether it handled req.
+func HandleServiceControl(ctx context.Context, r *CredentialRotator, req svc.ChangeRequest, codes ...svc.Cmd) (bool, error) {
+	handled := req.Cmd == svc.ParamChange
+	for _, c := range codes {
+		if req.Cmd == c {
+			handled = true
+		}
+	}
+	if !handled {
+		return false, nil
+	}
+	return true, r.Reload(ctx)
+}
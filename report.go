@@ -0,0 +1,205 @@
+package clientconfig
+
+import (
+	"sort"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// variableFields are the clientv3.Config fields each built-in variable affects, for ApplyWithReport.
+var variableFields = map[string][]string{
+	"ETCD_ENDPOINTS":               {"Endpoints"},
+	"ETCD_ENDPOINT_SHUFFLE":        {"Endpoints"},
+	"ETCD_ENDPOINT_HEALTH_CHECK":   {"Endpoints"},
+	"ETCD_ENDPOINT_PREFER":         {"Endpoints"},
+	"ETCD_USERNAME":                {"Username"},
+	"ETCD_PASSWORD":                {"Password"},
+	"ETCD_USERNAME_AND_PASSWORD":   {"Username", "Password"},
+	"ETCD_INSECURE_SKIP_VERIFY":    {"TLS.InsecureSkipVerify"},
+	"ETCD_SERVER_CA":               {"TLS.RootCAs"},
+	"ETCD_CLIENT_CERT":             {"TLS.Certificates"},
+	"ETCD_CLIENT_KEY":              {"TLS.Certificates"},
+	"ETCD_CLIENT_KEY_PASSWORD":     {"TLS.Certificates"},
+	"ETCD_CLIENT_CERT_AND_KEY":     {"TLS.Certificates"},
+	"ETCD_GATEWAY_ENDPOINT":        {"Endpoints", "AutoSyncInterval"},
+	"ETCD_GATEWAY_SERVER_NAME":     {"TLS.ServerName"},
+	"ETCD_VIA":                     {"AutoSyncInterval", "DialKeepAliveTime", "DialKeepAliveTimeout"},
+	"ETCD_DIAL_OPTIONS":            {"DialOptions"},
+	"ETCD_LB_POLICY":               {"DialOptions"},
+	"ETCD_CALL_OPTIONS":            {"DialOptions"},
+	"ETCD_GRPC_METADATA":           {"DialOptions"},
+	"ETCD_DNS_REFRESH_INTERVAL":    {"DialOptions"},
+	"ETCD_PROXY":                   {"DialOptions"},
+	"ETCD_SSH_JUMP_HOST":           {"DialOptions"},
+	"ETCD_SSH_USER":                {"DialOptions"},
+	"ETCD_SSH_KEY":                 {"DialOptions"},
+	"ETCD_SSH_KNOWN_HOSTS":         {"DialOptions"},
+	"ETCD_PROFILE":                 {"DialTimeout", "DialKeepAliveTime", "DialKeepAliveTimeout", "MaxCallSendMsgSize", "MaxCallRecvMsgSize"},
+	"ETCD_DEV_TLS":                 {"TLS"},
+	"ETCD_DEV_AUTOSTART":           {"Endpoints", "AutoSyncInterval"},
+	"ETCD_DEBUG_RPC":               {"DialOptions"},
+	"ETCD_DISCOVERY_SRV":           {"Endpoints"},
+	"ETCD_DISCOVERY_SRV_NAME":      {"Endpoints"},
+	"ETCD_DIAL_TIMEOUT":            {"DialTimeout"},
+	"ETCD_DIAL_KEEPALIVE_TIME":     {"DialKeepAliveTime"},
+	"ETCD_DIAL_KEEPALIVE_TIMEOUT":  {"DialKeepAliveTimeout"},
+	"ETCD_AUTO_SYNC_INTERVAL":      {"AutoSyncInterval"},
+	"ETCD_DISABLE_AUTO_SYNC":       {"AutoSyncInterval"},
+	"ETCD_MAX_CALL_SEND_MSG_SIZE":  {"MaxCallSendMsgSize"},
+	"ETCD_MAX_CALL_RECV_MSG_SIZE":  {"MaxCallRecvMsgSize"},
+	"ETCD_REJECT_OLD_CLUSTER":      {"RejectOldCluster"},
+	"ETCD_PERMIT_WITHOUT_STREAM":   {"PermitWithoutStream"},
+	"ETCD_BACKOFF_WAIT_BETWEEN":    {"BackoffWaitBetween"},
+	"ETCD_BACKOFF_JITTER_FRACTION": {"BackoffJitterFraction"},
+	"ETCD_MAX_UNARY_RETRIES":       {"MaxUnaryRetries"},
+	"ETCD_USER_AGENT":              {"DialOptions"},
+	"ETCD_AUTH_TOKEN":              {"DialOptions"},
+	"ETCD_OIDC_ISSUER":             {"DialOptions"},
+	"ETCD_OIDC_CLIENT_ID":          {"DialOptions"},
+	"ETCD_OIDC_CLIENT_SECRET":      {"DialOptions"},
+	"ETCD_OIDC_SCOPES":             {"DialOptions"},
+	"ETCD_LOG_LEVEL":               {"LogConfig"},
+	"ETCD_LOG_FORMAT":              {"LogConfig"},
+	"ETCD_SERVER_CA_APPEND_SYSTEM": {"TLS.RootCAs"},
+	"ETCD_TLS_SERVER_NAME":         {"TLS.ServerName"},
+	"ETCD_TLS_MIN_VERSION":         {"TLS.MinVersion"},
+	"ETCD_TLS_MAX_VERSION":         {"TLS.MaxVersion"},
+	"ETCD_TLS_CIPHER_SUITES":       {"TLS.CipherSuites"},
+}
+
+// etcdctlSources are the ETCDCTL_* variables that WithEtcdctlCompat reads each setting from.
+var etcdctlSources = map[string]string{
+	"ETCD_ENDPOINTS":             "ETCDCTL_ENDPOINTS",
+	"ETCD_DISCOVERY_SRV":         "ETCDCTL_DISCOVERY_SRV",
+	"ETCD_DISCOVERY_SRV_NAME":    "ETCDCTL_DISCOVERY_SRV_NAME",
+	"ETCD_SERVER_CA":             "ETCDCTL_CACERT",
+	"ETCD_CLIENT_CERT":           "ETCDCTL_CERT",
+	"ETCD_CLIENT_KEY":            "ETCDCTL_KEY",
+	"ETCD_USERNAME":              "ETCDCTL_USER",
+	"ETCD_USERNAME_AND_PASSWORD": "ETCDCTL_USER",
+	"ETCD_PASSWORD":              "ETCDCTL_PASSWORD",
+	"ETCD_INSECURE_SKIP_VERIFY":  "ETCDCTL_INSECURE_SKIP_TLS_VERIFY",
+}
+
+// VariableReport describes how one variable was applied.
+type VariableReport struct {
+	Name string
+	// Source is where the value came from: "env", "file", "base64", "command", "keyring", "resolver", "alias", "etcdctl" or "config source" (which includes ETCD_CONFIG_JSON and ETCD_CONFIG_YAML). It's empty if the variable wasn't set.
+	Source string
+	// From is the file (for "file") or the variable (for "alias", "etcdctl", "keyring" and "resolver") the value was read from.
+	From string
+	// Value is the value with secrets redacted and PEM shortened to its first line.
+	Value string
+	// Fields are the clientv3.Config fields the variable affects. Variables that don't go into the config (like ETCD_CONNECT_TIMEOUT) have none.
+	Fields []string
+}
+
+// ApplyReport is returned by ApplyWithReport.
+type ApplyReport struct {
+	// Variables has an entry for every variable we know, also the ones that weren't set.
+	Variables []VariableReport
+	// Warnings that were logged.
+	Warnings []string
+}
+
+// ApplyWithReport is like ApplyWithOptions, but also returns which variables were applied, where they came from and which fields they affected, for logging at startup.
+// The report is also returned if the configuration is invalid.
+func ApplyWithReport(c clientv3.Config, opts ...Option) (clientv3.Config, *ApplyReport, error) {
+	r := &ApplyReport{}
+	o := newOptions(opts)
+	warn := o.warnf
+	opts = append(opts[:len(opts):len(opts)], func(o *options) {
+		o.warn = func(w string) {
+			r.Warnings = append(r.Warnings, w)
+			warn("%s", w)
+		}
+	})
+	s, err := ReadSettings(opts...)
+	if err != nil {
+		return c, r, err
+	}
+	for _, v := range EnvVars() {
+		if v.AliasOf != "" {
+			continue
+		}
+		vr := VariableReport{Name: v.Name}
+		if val := s[v.Name]; val != "" {
+			vr.Source, vr.From = variableSource(o, v.Name)
+			vr.Value = displayValue(v.Name, val)
+			vr.Fields = variableFields[v.Name]
+		}
+		r.Variables = append(r.Variables, vr)
+	}
+	c, err = ApplySettings(c, s, opts...)
+	return c, r, err
+}
+
+// variableSource returns where the value of k came from, assuming it's set.
+func variableSource(o *options, k string) (string, string) {
+	if fn := o.env.Getenv(k + "_FILE"); fn != "" {
+		return "file", fn
+	}
+	if base64Variables[k] && o.env.Getenv(k+"_B64") != "" {
+		return "base64", k + "_B64"
+	}
+	if commandVariables[k] && o.env.Getenv(k+"_COMMAND") != "" {
+		return "command", k + "_COMMAND"
+	}
+	if keyringVariables[k] && o.env.Getenv(k+"_KEYRING") != "" {
+		return "keyring", k + "_KEYRING"
+	}
+	if resolvable(k) && o.env.Getenv(k+"_SOURCE") != "" {
+		return "resolver", k + "_SOURCE"
+	}
+	if o.env.Getenv(k) != "" {
+		return "env", ""
+	}
+	for _, a := range aliasesFor(k) {
+		if o.env.Getenv(a) != "" || o.env.Getenv(a+"_FILE") != "" {
+			return "alias", a
+		}
+	}
+	if o.etcdctlCompat {
+		if e := etcdctlSources[k]; e != "" && o.env.Getenv(e) != "" {
+			return "etcdctl", e
+		}
+	}
+	return "config source", ""
+}
+
+// displayValue redacts secrets and shortens PEM to its first line.
+func displayValue(k, v string) string {
+	if secretVariables[k] {
+		return "<redacted>"
+	}
+	if strings.Contains(v, "\n") {
+		return "<" + strings.Split(strings.TrimSpace(v), "\n")[0] + " ...>"
+	}
+	return v
+}
+
+// String returns a human readable summary of the variables that were set.
+func (r *ApplyReport) String() string {
+	var sb strings.Builder
+	vars := append([]VariableReport(nil), r.Variables...)
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	for _, v := range vars {
+		if v.Source == "" {
+			continue
+		}
+		sb.WriteString(v.Name + "=" + v.Value + " (" + v.Source)
+		if v.From != "" {
+			sb.WriteString(" " + v.From)
+		}
+		sb.WriteString(")")
+		if len(v.Fields) > 0 {
+			sb.WriteString(" -> " + strings.Join(v.Fields, ", "))
+		}
+		sb.WriteString("\n")
+	}
+	for _, w := range r.Warnings {
+		sb.WriteString("warning: " + w + "\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,90 @@
+package clientconfig
+
+import (
+	"slices"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// requirements are the values of ETCD_REQUIRE, and what the Require options set.
+var requirements = []string{"endpoints", "tls", "auth"}
+
+// RequireEndpoints makes Apply fail if the endpoints weren't configured, rather than falling back to the defaults, like ETCD_REQUIRE=endpoints.
+func RequireEndpoints() Option {
+	return require("endpoints")
+}
+
+// RequireTLS makes Apply fail if any endpoint would be used without TLS, like ETCD_REQUIRE=tls.
+func RequireTLS() Option {
+	return require("tls")
+}
+
+// RequireAuth makes Apply fail if no credentials (a username and password, client certificate or token) are configured, like ETCD_REQUIRE=auth.
+func RequireAuth() Option {
+	return require("auth")
+}
+
+func require(what string) Option {
+	return func(o *options) {
+		if o.require == nil {
+			o.require = map[string]bool{}
+		}
+		o.require[what] = true
+	}
+}
+
+// parseRequire returns the requirements from the options, ETCD_PROFILE and ETCD_REQUIRE.
+func parseRequire(o *options, settings Settings) (map[string]bool, error) {
+	ret := map[string]bool{}
+	for k := range o.require {
+		ret[k] = true
+	}
+	for _, p := range strings.Split(settings["ETCD_PROFILE"], ",") {
+		for _, r := range profileRequirements[strings.TrimSpace(p)] {
+			ret[r] = true
+		}
+	}
+	for _, r := range strings.Split(settings["ETCD_REQUIRE"], ",") {
+		r = strings.ToLower(strings.TrimSpace(r))
+		if r == "" {
+			continue
+		}
+		if !slices.Contains(requirements, r) {
+			return nil, errorf(CodeInvalidValue, "ETCD_REQUIRE", "invalid requirement %q in ETCD_REQUIRE (should be %s)", r, strings.Join(requirements, ", "))
+		}
+		ret[r] = true
+	}
+	return ret, nil
+}
+
+// checkEndpointsConfigured returns an error if c has no endpoints, or only the defaults it was given.
+func checkEndpointsConfigured(c clientv3.Config, settings Settings, defaults []string) error {
+	// ETCD_ENDPOINT_SHUFFLE might have reordered the defaults.
+	eps, defaults := slices.Clone(c.Endpoints), slices.Clone(defaults)
+	slices.Sort(eps)
+	slices.Sort(defaults)
+	if len(c.Endpoints) > 0 && (settings["ETCD_ENDPOINTS"] != "" || settings["ETCD_GATEWAY_ENDPOINT"] != "" || settings["ETCD_DISCOVERY_SRV"] != "" || !slices.Equal(eps, defaults)) {
+		return nil
+	}
+	return errorf(CodeNotConfigured, "ETCD_ENDPOINTS", "endpoints are required (by ETCD_REQUIRE or RequireEndpoints), but none are configured: set ETCD_ENDPOINTS (or ETCD_GATEWAY_ENDPOINT or ETCD_DISCOVERY_SRV)")
+}
+
+// checkTLSUsed returns an error if any endpoint of c doesn't use TLS. why explains who requires it.
+func checkTLSUsed(c clientv3.Config, why string) error {
+	if plain, _ := splitSchemes(c); len(plain) > 0 {
+		return errorf(CodeTLSRequired, "", "%s requires TLS, but endpoint %q doesn't use it (set ETCD_SERVER_CA or use https://)", why, plain[0])
+	}
+	return nil
+}
+
+// checkAuthConfigured returns an error if c has no credentials. tokenVar is the variable that supplies a token, if any.
+func checkAuthConfigured(c clientv3.Config, tokenVar string) error {
+	if c.Username != "" && c.Password != "" || tokenVar != "" {
+		return nil
+	}
+	if c.TLS != nil && (len(c.TLS.Certificates) > 0 || c.TLS.GetClientCertificate != nil) {
+		return nil
+	}
+	return errorf(CodeAuthRequired, "ETCD_USERNAME", "credentials are required (by ETCD_REQUIRE or RequireAuth), but none are configured: set ETCD_USERNAME and ETCD_PASSWORD, a client certificate or ETCD_AUTH_TOKEN")
+}
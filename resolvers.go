@@ -0,0 +1,100 @@
+package clientconfig
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Resolver fetches values from somewhere like a secret manager or keyring, for the _SOURCE variants of the variables: ETCD_PASSWORD_SOURCE=scheme://ref calls Resolve(scheme, ref) and uses the result as ETCD_PASSWORD.
+type Resolver interface {
+	Resolve(scheme, ref string) ([]byte, error)
+}
+
+// ContextResolver can optionally be implemented by a Resolver to give up when the context passed to ReadSettingsContext (or GetContext etc) is done.
+type ContextResolver interface {
+	ResolveContext(ctx context.Context, scheme, ref string) ([]byte, error)
+}
+
+// ResolverFunc is a Resolver implemented by a function.
+type ResolverFunc func(scheme, ref string) ([]byte, error)
+
+// Resolve implements Resolver.
+func (f ResolverFunc) Resolve(scheme, ref string) ([]byte, error) {
+	return f(scheme, ref)
+}
+
+var (
+	resolversMtx sync.Mutex
+	resolvers    = map[string]Resolver{}
+)
+
+// RegisterResolver makes r responsible for the given URI scheme (without "://") in _SOURCE variables.
+// Resolvers are called when the settings are read. They're assumed to need the network, and are only allowed with offline resolution if they implement LocalConfigSource.
+// RegisterResolver is meant to be called from init functions and panics if the scheme is already taken.
+func RegisterResolver(scheme string, r Resolver) {
+	resolversMtx.Lock()
+	defer resolversMtx.Unlock()
+	if scheme == "" || strings.ContainsAny(scheme, ":/ ") {
+		panic(fmt.Sprintf("clientconfig: invalid resolver scheme %q", scheme))
+	}
+	if _, found := resolvers[scheme]; found {
+		panic(fmt.Sprintf("clientconfig: resolver for %q registered twice", scheme))
+	}
+	resolvers[scheme] = r
+}
+
+// resolverHints are the packages that register the resolvers our own variables need.
+var resolverHints = map[string]string{
+	"keyring": "github.com/Jille/etcd-client-from-env/keyring",
+}
+
+// resolvable returns whether k can be set with k_SOURCE. ETCD_OFFLINE_RESOLUTION can't, because it decides which resolvers may be used.
+func resolvable(k string) bool {
+	return k != "ETCD_OFFLINE_RESOLUTION"
+}
+
+// resolveSource returns the value of k from the resolver for uri, which was given in the variable from (like ETCD_PASSWORD_SOURCE).
+func resolveSource(ctx context.Context, o *options, k, from, uri string) (string, error) {
+	scheme, ref, ok := strings.Cut(uri, "://")
+	if !ok {
+		return "", errorf(CodeInvalidValue, k, "%s should look like scheme://reference (%q)", from, uri)
+	}
+	resolversMtx.Lock()
+	r, found := resolvers[scheme]
+	var known []string
+	for s := range resolvers {
+		known = append(known, s)
+	}
+	resolversMtx.Unlock()
+	if !found {
+		sort.Strings(known)
+		if hint := resolverHints[scheme]; hint != "" {
+			return "", errorf(CodeUnknownName, k, "%s needs the %q resolver; import %s to register it", from, scheme, hint)
+		}
+		return "", errorf(CodeUnknownName, k, "unknown scheme %q in %s (registered: %s)", scheme, from, strings.Join(known, ", "))
+	}
+	if l, ok := r.(LocalConfigSource); !ok || !l.IsLocal() {
+		if offline, err := offlineResolution(ctx, o); err != nil {
+			return "", err
+		} else if offline {
+			return "", errorf(CodeNetworkForbidden, k, "%s might use the network, which is forbidden by offline resolution", from)
+		}
+	}
+	var b []byte
+	var err error
+	if cr, ok := r.(ContextResolver); ok {
+		b, err = cr.ResolveContext(ctx, scheme, ref)
+	} else {
+		b, err = r.Resolve(scheme, ref)
+	}
+	if err != nil {
+		return "", errorf(CodeRegisteredFailure, k, "failed to resolve %s: %v", from, err)
+	}
+	if len(b) == 0 {
+		return "", errorf(CodeRegisteredFailure, k, "%s resolved to an empty value", from)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,162 @@
+package clientconfig
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// CredentialRotator owns a client and replaces it when the credentials or certificates change, without creating gaps in watches started through it.
+type CredentialRotator struct {
+	// CloseDelay is how long the previous client is kept open after a rotation, so in-flight requests can finish. Defaults to 30 seconds.
+	CloseDelay time.Duration
+
+	mtx     sync.Mutex
+	cli     *clientv3.Client
+	rotated chan struct{}
+	closed  bool
+}
+
+// NewCredentialRotator creates a client from c.
+func NewCredentialRotator(c clientv3.Config) (*CredentialRotator, error) {
+	cli, err := clientv3.New(c)
+	if err != nil {
+		return nil, err
+	}
+	return &CredentialRotator{
+		cli:     cli,
+		rotated: make(chan struct{}),
+	}, nil
+}
+
+// Client returns the current client. Don't hold on to it: it is closed some time after the next rotation.
+func (r *CredentialRotator) Client() *clientv3.Client {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.cli
+}
+
+func (r *CredentialRotator) current() (*clientv3.Client, chan struct{}) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.cli, r.rotated
+}
+
+// Rotate dials a new client with c (typically the same endpoints with new credentials or certificates) and switches over to it.
+// Watches started with Watch move to the new client and resume after the last revision they delivered. The old client is closed after CloseDelay.
+func (r *CredentialRotator) Rotate(c clientv3.Config) error {
+	cli, err := clientv3.New(c)
+	if err != nil {
+		return err
+	}
+	return r.swap(cli)
+}
+
+// swap switches over to cli and closes the old client after CloseDelay.
+func (r *CredentialRotator) swap(cli *clientv3.Client) error {
+	r.mtx.Lock()
+	if r.closed {
+		r.mtx.Unlock()
+		return cli.Close()
+	}
+	old := r.cli
+	r.cli = cli
+	close(r.rotated)
+	r.rotated = make(chan struct{})
+	r.mtx.Unlock()
+	delay := r.CloseDelay
+	if delay <= 0 {
+		delay = 30 * time.Second
+	}
+	time.AfterFunc(delay, func() {
+		old.Close()
+	})
+	return nil
+}
+
+// Watch is like clientv3.Watcher.Watch, but keeps going across rotations: it resubscribes on the new client from the revision after the last one it delivered, so consumers don't miss events.
+// It always requests clientv3.WithCreatedNotify() to learn the starting revision, so the first response is a created notification without events.
+func (r *CredentialRotator) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	out := make(chan clientv3.WatchResponse)
+	opts = append(opts[:len(opts):len(opts)], clientv3.WithCreatedNotify())
+	go r.pumpWatch(ctx, key, opts, out)
+	return out
+}
+
+func (r *CredentialRotator) pumpWatch(ctx context.Context, key string, opts []clientv3.OpOption, out chan<- clientv3.WatchResponse) {
+	defer close(out)
+	// rev is the last revision of which all events have been delivered.
+	var rev int64
+	startRev := clientv3.OpGet(key, opts...).Rev()
+	if startRev > 0 {
+		rev = startRev - 1
+	}
+	created := false
+	for {
+		cli, rotated := r.current()
+		wopts := opts
+		if rev > 0 {
+			wopts = append(opts[:len(opts):len(opts)], clientv3.WithRev(rev+1))
+		}
+		wctx, cancel := context.WithCancel(ctx)
+		wch := cli.Watch(wctx, key, wopts...)
+		resubscribe := false
+		for !resubscribe {
+			select {
+			case <-ctx.Done():
+				cancel()
+				return
+			case <-rotated:
+				resubscribe = true
+			case wr, ok := <-wch:
+				if !ok {
+					select {
+					case <-rotated:
+						// The old client was closed underneath us.
+						resubscribe = true
+						continue
+					default:
+					}
+					cancel()
+					return
+				}
+				if wr.Created {
+					if created {
+						// Our own resubscription; the consumer already got their created notification.
+						continue
+					}
+					created = true
+					if startRev == 0 {
+						// The watch starts after the current revision.
+						rev = wr.Header.Revision
+					}
+				} else if n := len(wr.Events); n > 0 {
+					rev = wr.Events[n-1].Kv.ModRevision
+				} else if wr.IsProgressNotify() && wr.Header.Revision > rev {
+					rev = wr.Header.Revision
+				}
+				select {
+				case out <- wr:
+				case <-ctx.Done():
+					cancel()
+					return
+				}
+				if wr.Canceled {
+					cancel()
+					return
+				}
+			}
+		}
+		cancel()
+	}
+}
+
+// Close closes the current client. Watches end.
+func (r *CredentialRotator) Close() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.closed = true
+	return r.cli.Close()
+}
@@ -0,0 +1,115 @@
+package clientconfig
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// checkSchemes returns an error if c mixes TLS and plaintext endpoints, or has TLS settings that none of the endpoints use.
+// etcd picks the transport per endpoint, so either mistake only shows up as failures on some endpoints at runtime.
+func checkSchemes(c clientv3.Config) error {
+	plain, secure := splitSchemes(c)
+	if len(plain) > 0 && len(secure) > 0 {
+		return errorf(CodeInconsistentTLS, "ETCD_ENDPOINTS", "endpoints mix TLS (%s) and plaintext (%s); use the same scheme for all of them", strings.Join(secure, ", "), strings.Join(plain, ", "))
+	}
+	if c.TLS != nil && len(plain) > 0 {
+		return errorf(CodeInconsistentTLS, "ETCD_ENDPOINTS", "TLS is configured, but it isn't used by endpoints %s; use https:// or no scheme", strings.Join(plain, ", "))
+	}
+	return nil
+}
+
+// splitSchemes returns the endpoints of c that etcd will connect to without and with TLS.
+func splitSchemes(c clientv3.Config) (plain, secure []string) {
+	for _, ep := range c.Endpoints {
+		switch {
+		case strings.HasPrefix(ep, "http://"), strings.HasPrefix(ep, "unix://"):
+			plain = append(plain, ep)
+		case strings.HasPrefix(ep, "https://"), strings.HasPrefix(ep, "unixs://"):
+			secure = append(secure, ep)
+		case strings.HasPrefix(ep, "unix:"):
+			plain = append(plain, ep)
+		case c.TLS != nil:
+			secure = append(secure, ep)
+		default:
+			plain = append(plain, ep)
+		}
+	}
+	return plain, secure
+}
+
+// parseEndpoints splits the comma separated endpoints in variable k, trimming whitespace and skipping empty entries, and checks that each is a URL or host:port.
+func parseEndpoints(k, v string) ([]string, error) {
+	var ret []string
+	for _, ep := range strings.Split(v, ",") {
+		ep = strings.TrimSpace(ep)
+		if ep == "" {
+			continue
+		}
+		if err := validateEndpoint(ep); err != nil {
+			return nil, errorf(CodeInvalidValue, k, "invalid endpoint %q in %s: %v", ep, k, err)
+		}
+		ret = append(ret, ep)
+	}
+	if len(ret) == 0 {
+		return nil, errorf(CodeInvalidValue, k, "%s doesn't contain any endpoints", k)
+	}
+	return ret, nil
+}
+
+func validateEndpoint(ep string) error {
+	if strings.HasPrefix(ep, "unix:") && !strings.HasPrefix(ep, "unix://") {
+		return nil
+	}
+	if strings.Contains(ep, "://") {
+		u, err := url.Parse(ep)
+		if err != nil {
+			return err
+		}
+		switch u.Scheme {
+		case "http", "https":
+			if u.Host == "" {
+				return errors.New("missing host")
+			}
+			if u.Path != "" && u.Path != "/" {
+				return errors.New("endpoints can't have a path")
+			}
+			return nil
+		case "unix", "unixs":
+			return nil
+		}
+		return fmt.Errorf("unknown scheme %q (should be http, https, unix or unixs)", u.Scheme)
+	}
+	host, port, err := net.SplitHostPort(ep)
+	if err != nil {
+		return errors.New("should be a URL like https://host:2379 or host:port")
+	}
+	if host == "" {
+		return errors.New("missing host")
+	}
+	if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+	return nil
+}
+
+// inferSchemes prefixes endpoints without a scheme with https:// if TLS is configured and http:// otherwise. etcd would do the same, but this makes it visible in the config (and its error messages).
+func inferSchemes(c *clientv3.Config) {
+	scheme := "http://"
+	if c.TLS != nil {
+		scheme = "https://"
+	}
+	eps := make([]string, len(c.Endpoints))
+	for i, ep := range c.Endpoints {
+		if !strings.Contains(ep, "://") && !strings.HasPrefix(ep, "unix:") {
+			ep = scheme + ep
+		}
+		eps[i] = ep
+	}
+	c.Endpoints = eps
+}
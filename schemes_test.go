@@ -0,0 +1,53 @@
+package clientconfig
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEndpoints(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr string
+	}{
+		{in: "http://localhost:2379", want: []string{"http://localhost:2379"}},
+		{in: "https://etcd1:2379,https://etcd2:2379", want: []string{"https://etcd1:2379", "https://etcd2:2379"}},
+		{in: " https://etcd1:2379 , https://etcd2:2379 ", want: []string{"https://etcd1:2379", "https://etcd2:2379"}},
+		{in: "http://etcd1:2379,,http://etcd2:2379,", want: []string{"http://etcd1:2379", "http://etcd2:2379"}},
+		{in: "https://etcd1:2379/", want: []string{"https://etcd1:2379/"}},
+		{in: "etcd1:2379,10.0.0.1:2379", want: []string{"etcd1:2379", "10.0.0.1:2379"}},
+		{in: "[::1]:2379,https://[fe80::1]:2379", want: []string{"[::1]:2379", "https://[fe80::1]:2379"}},
+		{in: "unix:///run/etcd.sock,unixs://etcd.sock", want: []string{"unix:///run/etcd.sock", "unixs://etcd.sock"}},
+		{in: "unix:etcd.sock:2379", want: []string{"unix:etcd.sock:2379"}},
+		{in: "", wantErr: "ETCD_ENDPOINTS doesn't contain any endpoints"},
+		{in: " , ", wantErr: "ETCD_ENDPOINTS doesn't contain any endpoints"},
+		{in: "http://etcd1:2379,etcd2", wantErr: `invalid endpoint "etcd2" in ETCD_ENDPOINTS: should be a URL like https://host:2379 or host:port`},
+		{in: "grpc://etcd1:2379", wantErr: `unknown scheme "grpc"`},
+		{in: "http:///v3", wantErr: "missing host"},
+		{in: ":2379", wantErr: "missing host"},
+		{in: "https://etcd1:2379/v3", wantErr: "endpoints can't have a path"},
+		{in: "etcd1:http", wantErr: `invalid port "http"`},
+		{in: "etcd1:0", wantErr: `invalid port "0"`},
+		{in: "etcd1:65536", wantErr: `invalid port "65536"`},
+		{in: "http://etcd1:2379/%zz", wantErr: `invalid endpoint "http://etcd1:2379/%zz"`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := parseEndpoints("ETCD_ENDPOINTS", tc.in)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("parseEndpoints(%q) = %q, %v; want an error containing %q", tc.in, got, err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseEndpoints(%q): %v", tc.in, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("parseEndpoints(%q) = %q, want %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
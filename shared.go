@@ -0,0 +1,50 @@
+package clientconfig
+
+import (
+	"context"
+	"sync"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+var (
+	sharedConfigOnce sync.Once
+	sharedConfig     clientv3.Config
+	sharedConfigErr  error
+
+	sharedClientMtx sync.Mutex
+	sharedClient    *clientv3.Client
+)
+
+// MustGet is like Get, but panics if the configuration is invalid. It's meant for wiring in main().
+func MustGet() clientv3.Config {
+	c, err := Get()
+	if err != nil {
+		panic("clientconfig: " + err.Error())
+	}
+	return c
+}
+
+// GetShared is like Get, but only reads the configuration once per process and returns the same result every time after that. Don't modify the TLS config or slices in it.
+func GetShared() (clientv3.Config, error) {
+	sharedConfigOnce.Do(func() {
+		sharedConfig, sharedConfigErr = Get()
+	})
+	return sharedConfig, sharedConfigErr
+}
+
+// ConnectShared is like Connect, but returns the same client to every caller in the process, so multiple libraries in one binary share a single connection to etcd.
+// If connecting fails, the next call tries again. ctx only bounds connecting. Never close the returned client.
+func ConnectShared(ctx context.Context) (*clientv3.Client, error) {
+	sharedClientMtx.Lock()
+	defer sharedClientMtx.Unlock()
+	if sharedClient != nil {
+		return sharedClient, nil
+	}
+	cli, err := Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sharedClient = cli
+	return cli, nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package sidecar
+
+import (
+	"errors"
+	"net"
+)
+
+func peerUID(c *net.UnixConn) (int, error) {
+	return 0, errors.New("peer credentials are only supported on Linux")
+}
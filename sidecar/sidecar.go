@@ -0,0 +1,184 @@
+// Package sidecar lets one process resolve the configuration and serve it to other local processes over a unix socket.
+//
+// This way a whole host can share one integration point with the secret manager. The server checks the peer credentials of every connection (Linux only) and keeps the configuration refreshed:
+//
+//	s := &sidecar.Server{AllowedUIDs: []int{1000, 1001}}
+//	log.Fatal(s.ListenAndServe("/run/etcd-config.sock"))
+//
+// Clients fetch the configuration with Get:
+//
+//	c, err := sidecar.Get(ctx, "/run/etcd-config.sock")
+package sidecar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	clientconfig "github.com/Jille/etcd-client-from-env"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Server serves the settings read by clientconfig.ReadSettings.
+type Server struct {
+	// AllowedUIDs are the user ids that may fetch the configuration. If empty, only the user running the server is allowed.
+	AllowedUIDs []int
+	// RefreshInterval is how often the settings are re-read. Defaults to a minute.
+	RefreshInterval time.Duration
+	// Options are passed to clientconfig.ReadSettings.
+	Options []clientconfig.Option
+
+	mtx      sync.Mutex
+	settings clientconfig.Settings
+	err      error
+}
+
+type response struct {
+	Settings clientconfig.Settings `json:"settings,omitempty"`
+	Error    string                `json:"error,omitempty"`
+}
+
+// ListenAndServe listens on the unix socket at path (replacing a stale socket) and calls Serve.
+func (s *Server) ListenAndServe(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(path, 0666); err != nil {
+		l.Close()
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l and answers each with the current settings. It returns an error if the settings can't be read initially.
+func (s *Server) Serve(l net.Listener) error {
+	defer l.Close()
+	s.refresh()
+	if err := s.lastError(); err != nil {
+		return err
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go s.refreshLoop(stop)
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) refresh() {
+	settings, err := clientconfig.ReadSettings(s.Options...)
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if err != nil {
+		// Keep serving the last known good settings.
+		s.err = err
+		return
+	}
+	s.settings = settings
+	s.err = nil
+}
+
+func (s *Server) lastError() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.err
+}
+
+func (s *Server) refreshLoop(stop chan struct{}) {
+	interval := s.RefreshInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+			s.refresh()
+			if err := s.lastError(); err != nil {
+				log.Printf("sidecar: failed to refresh etcd settings (serving the previous ones): %v", err)
+			}
+		}
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	var resp response
+	if err := s.checkPeer(conn); err != nil {
+		resp.Error = err.Error()
+	} else {
+		s.mtx.Lock()
+		resp.Settings = s.settings
+		s.mtx.Unlock()
+	}
+	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	json.NewEncoder(conn).Encode(resp)
+}
+
+func (s *Server) checkPeer(conn net.Conn) error {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return errors.New("sidecar: only unix sockets are supported")
+	}
+	uid, err := peerUID(uc)
+	if err != nil {
+		return fmt.Errorf("sidecar: failed to get peer credentials: %v", err)
+	}
+	allowed := s.AllowedUIDs
+	if len(allowed) == 0 {
+		allowed = []int{os.Getuid()}
+	}
+	for _, a := range allowed {
+		if a == uid {
+			return nil
+		}
+	}
+	return fmt.Errorf("sidecar: uid %d is not allowed to fetch the configuration", uid)
+}
+
+// Fetch retrieves the settings from the server listening on the unix socket at path.
+func Fetch(ctx context.Context, path string) (clientconfig.Settings, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", path)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if dl, ok := ctx.Deadline(); ok {
+		conn.SetReadDeadline(dl)
+	}
+	var resp response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("sidecar: failed to read response: %v", err)
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	return resp.Settings, nil
+}
+
+// Get fetches the settings and applies them to clientconfig.Defaults().
+func Get(ctx context.Context, path string, opts ...clientconfig.Option) (clientv3.Config, error) {
+	settings, err := Fetch(ctx, path)
+	if err != nil {
+		return clientv3.Config{}, err
+	}
+	return clientconfig.ApplySettings(clientconfig.Defaults(), settings, opts...)
+}
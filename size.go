@@ -0,0 +1,43 @@
+package clientconfig
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// sizeSuffixes are the units parseSize understands, longest first so "MiB" isn't taken for "B".
+var sizeSuffixes = []struct {
+	suffix string
+	factor int64
+}{
+	{"KiB", 1 << 10},
+	{"MiB", 1 << 20},
+	{"GiB", 1 << 30},
+	{"KB", 1000},
+	{"MB", 1000 * 1000},
+	{"GB", 1000 * 1000 * 1000},
+	{"K", 1 << 10},
+	{"M", 1 << 20},
+	{"G", 1 << 30},
+	{"B", 1},
+}
+
+// parseSize parses a positive number of bytes like 4194304, 4MiB or 10MB for variable k. A bare K, M or G means KiB, MiB or GiB.
+func parseSize(k, v string) (int, error) {
+	n, factor := strings.TrimSpace(v), int64(1)
+	for _, s := range sizeSuffixes {
+		if strings.HasSuffix(strings.ToUpper(n), strings.ToUpper(s.suffix)) {
+			n, factor = strings.TrimSpace(n[:len(n)-len(s.suffix)]), s.factor
+			break
+		}
+	}
+	i, err := strconv.ParseInt(n, 10, 64)
+	if err != nil || i <= 0 {
+		return 0, errorf(CodeInvalidValue, k, "failed to parse %s as a positive size like 4194304 or 10MiB (%q)", k, v)
+	}
+	if i > math.MaxInt32/factor {
+		return 0, errorf(CodeInvalidValue, k, "%s must be less than 2GiB (%q)", k, v)
+	}
+	return int(i * factor), nil
+}
@@ -0,0 +1,56 @@
+package clientconfig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int
+		wantErr string
+	}{
+		{in: "4194304", want: 4194304},
+		{in: " 4194304 ", want: 4194304},
+		{in: "1B", want: 1},
+		{in: "4KiB", want: 4 << 10},
+		{in: "4MiB", want: 4 << 20},
+		{in: "1GiB", want: 1 << 30},
+		{in: "10KB", want: 10 * 1000},
+		{in: "10MB", want: 10 * 1000 * 1000},
+		{in: "2GB", want: 2 * 1000 * 1000 * 1000},
+		{in: "4K", want: 4 << 10},
+		{in: "4M", want: 4 << 20},
+		{in: "1G", want: 1 << 30},
+		{in: "4mib", want: 4 << 20},
+		{in: "4 MiB", want: 4 << 20},
+		{in: "2147483647", want: 2147483647},
+		{in: "2147483648", wantErr: "less than 2GiB"},
+		{in: "2GiB", wantErr: "less than 2GiB"},
+		{in: "3GB", wantErr: "less than 2GiB"},
+		{in: "0", wantErr: "positive size"},
+		{in: "-1MiB", wantErr: "positive size"},
+		{in: "1.5MiB", wantErr: "positive size"},
+		{in: "MiB", wantErr: "positive size"},
+		{in: "4TiB", wantErr: "positive size"},
+		{in: "", wantErr: "positive size"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := parseSize("ETCD_MAX_CALL_SEND_MSG_SIZE", tc.in)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("parseSize(%q) = %d, %v; want an error containing %q", tc.in, got, err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSize(%q): %v", tc.in, err)
+			}
+			if got != tc.want {
+				t.Errorf("parseSize(%q) = %d, want %d", tc.in, got, tc.want)
+			}
+		})
+	}
+}
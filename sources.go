@@ -0,0 +1,157 @@
+package clientconfig
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ConfigSource provides values for our variables from somewhere other than the environment, like an internal config service.
+type ConfigSource interface {
+	// Load returns values keyed by variable name (like "ETCD_ENDPOINTS"). Unknown names are ignored.
+	Load(ctx context.Context) (map[string]string, error)
+}
+
+// ConfigSourceWatcher can optionally be implemented by a ConfigSource that knows when its values change.
+type ConfigSourceWatcher interface {
+	// Watch calls onChange whenever the values returned by Load might have changed, until ctx is cancelled.
+	Watch(ctx context.Context, onChange func()) error
+}
+
+// LocalConfigSource can be implemented by a ConfigSource that doesn't need the network, like one reading local files. Only local sources can be used with ETCD_OFFLINE_RESOLUTION.
+type LocalConfigSource interface {
+	IsLocal() bool
+}
+
+var (
+	configSourcesMtx sync.Mutex
+	configSources    = map[string]func() (ConfigSource, error){}
+)
+
+// RegisterConfigSource makes a ConfigSource available under the given name. Operators enable it by listing the name in ETCD_CONFIG_SOURCES.
+// Values from the environment take precedence over sources, and sources listed earlier take precedence over later ones.
+// RegisterConfigSource is meant to be called from init functions and panics if the name is already taken.
+func RegisterConfigSource(name string, factory func() (ConfigSource, error)) {
+	configSourcesMtx.Lock()
+	defer configSourcesMtx.Unlock()
+	if strings.ContainsAny(name, ", ") {
+		panic(fmt.Sprintf("clientconfig: invalid config source name %q", name))
+	}
+	if _, found := configSources[name]; found {
+		panic(fmt.Sprintf("clientconfig: config source %q registered twice", name))
+	}
+	configSources[name] = factory
+}
+
+// enabledConfigSources returns the sources listed in ETCD_CONFIG_SOURCES.
+func enabledConfigSources(ctx context.Context, o *options) ([]ConfigSource, error) {
+	names, err := readVariable(ctx, o, "ETCD_CONFIG_SOURCES")
+	if err != nil || names == "" {
+		return nil, err
+	}
+	offline, err := offlineResolution(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+	configSourcesMtx.Lock()
+	defer configSourcesMtx.Unlock()
+	var ret []ConfigSource
+	for _, n := range strings.Split(names, ",") {
+		n = strings.TrimSpace(n)
+		if n == "" {
+			continue
+		}
+		f, found := configSources[n]
+		if !found {
+			var known []string
+			for k := range configSources {
+				known = append(known, k)
+			}
+			sort.Strings(known)
+			return nil, errorf(CodeUnknownName, "ETCD_CONFIG_SOURCES", "unknown config source %q in ETCD_CONFIG_SOURCES (registered: %s)", n, strings.Join(known, ", "))
+		}
+		s, err := f()
+		if err != nil {
+			return nil, errorf(CodeRegisteredFailure, "ETCD_CONFIG_SOURCES", "failed to create config source %q: %v", n, err)
+		}
+		if l, ok := s.(LocalConfigSource); offline && (!ok || !l.IsLocal()) {
+			return nil, errorf(CodeNetworkForbidden, "ETCD_CONFIG_SOURCES", "config source %q might use the network, which is forbidden by offline resolution", n)
+		}
+		ret = append(ret, s)
+	}
+	return ret, nil
+}
+
+// loadConfigSources fills in the variables missing from settings from the enabled sources.
+func loadConfigSources(ctx context.Context, o *options, settings Settings) error {
+	sources, err := enabledConfigSources(ctx, o)
+	if err != nil {
+		return err
+	}
+	known := map[string]bool{}
+	for _, k := range allVariables() {
+		known[k] = true
+	}
+	for _, s := range sources {
+		vals, err := s.Load(ctx)
+		if err != nil {
+			return errorf(CodeRegisteredFailure, "ETCD_CONFIG_SOURCES", "failed to load config source: %v", err)
+		}
+		for k, v := range vals {
+			if known[k] && v != "" && settings[k] == "" {
+				settings[k] = v
+			}
+		}
+	}
+	return nil
+}
+
+// WatchConfigSources calls onChange whenever one of the sources enabled in ETCD_CONFIG_SOURCES reports a change, so you can call ReadSettings again.
+// It blocks until ctx is cancelled or one of the watches ends. Sources that don't implement ConfigSourceWatcher are not watched.
+func WatchConfigSources(ctx context.Context, onChange func(), opts ...Option) error {
+	sources, err := enabledConfigSources(ctx, newOptions(opts))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errCh := make(chan error, len(sources))
+	watching := 0
+	for _, s := range sources {
+		w, ok := s.(ConfigSourceWatcher)
+		if !ok {
+			continue
+		}
+		watching++
+		go func() {
+			errCh <- w.Watch(ctx, onChange)
+		}()
+	}
+	if watching == 0 {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if err := <-errCh; err != nil && ctx.Err() == nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// offlineResolution returns whether sources that need the network are forbidden, by WithOfflineResolution or ETCD_OFFLINE_RESOLUTION.
+func offlineResolution(ctx context.Context, o *options) (bool, error) {
+	if o.offline {
+		return true, nil
+	}
+	v, err := readVariable(ctx, o, "ETCD_OFFLINE_RESOLUTION")
+	if err != nil || v == "" {
+		return false, err
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, errorf(CodeInvalidValue, "ETCD_OFFLINE_RESOLUTION", "failed to parse ETCD_OFFLINE_RESOLUTION as bool (%q)", v)
+	}
+	return b, nil
+}
@@ -0,0 +1,94 @@
+// Package spiffe uses an X.509 SVID from the SPIFFE Workload API (like a SPIRE agent) as the etcd client certificate.
+//
+// Importing it registers two variables:
+//
+//   - ETCD_SPIFFE_SOCKET: The address of the Workload API, like unix:///run/spire/sockets/agent.sock (a bare path works too). The SVID is fetched when the configuration is applied and rotated certificates are used for new connections.
+//   - ETCD_SPIFFE_TRUST_DOMAIN: Optionally, verify the server against the trust bundle from the Workload API instead of ETCD_SERVER_CA, and only accept servers with a SPIFFE ID in this trust domain (like example.org).
+//
+// The connection to the Workload API stays open for the lifetime of the process, and is shared by all configurations with the same socket.
+package spiffe
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	clientconfig "github.com/Jille/etcd-client-from-env"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/spiffetls/tlsconfig"
+	"github.com/spiffe/go-spiffe/v2/workloadapi"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func init() {
+	clientconfig.RegisterVariables([]string{"ETCD_SPIFFE_SOCKET", "ETCD_SPIFFE_TRUST_DOMAIN"}, apply)
+}
+
+// fetchTimeout bounds waiting for the first SVID.
+const fetchTimeout = 30 * time.Second
+
+var (
+	sourcesMtx sync.Mutex
+	sources    = map[string]*workloadapi.X509Source{}
+)
+
+func apply(c *clientv3.Config, values map[string]string) error {
+	addr := values["ETCD_SPIFFE_SOCKET"]
+	if addr == "" {
+		return errors.New("ETCD_SPIFFE_TRUST_DOMAIN can only be used together with ETCD_SPIFFE_SOCKET")
+	}
+	if strings.HasPrefix(addr, "/") {
+		addr = "unix://" + addr
+	}
+	if c.TLS != nil && (len(c.TLS.Certificates) > 0 || c.TLS.GetClientCertificate != nil) {
+		return errors.New("a client certificate is already configured (by ETCD_CLIENT_CERT?)")
+	}
+	var td spiffeid.TrustDomain
+	if v := values["ETCD_SPIFFE_TRUST_DOMAIN"]; v != "" {
+		var err error
+		td, err = spiffeid.TrustDomainFromString(v)
+		if err != nil {
+			return fmt.Errorf("invalid ETCD_SPIFFE_TRUST_DOMAIN %q: %v", v, err)
+		}
+		if c.TLS != nil && c.TLS.RootCAs != nil {
+			return errors.New("ETCD_SPIFFE_TRUST_DOMAIN verifies the server with the SPIFFE trust bundle, so it can't be combined with ETCD_SERVER_CA")
+		}
+	}
+	src, err := getSource(addr)
+	if err != nil {
+		return err
+	}
+	if c.TLS == nil {
+		c.TLS = &tls.Config{}
+	} else {
+		c.TLS = c.TLS.Clone()
+	}
+	if td.IsZero() {
+		c.TLS.GetClientCertificate = tlsconfig.GetClientCertificate(src)
+	} else {
+		tlsconfig.HookMTLSClientConfig(c.TLS, src, src, tlsconfig.AuthorizeMemberOf(td))
+	}
+	return nil
+}
+
+// getSource returns the X509Source for the Workload API at addr, connecting to it if needed.
+func getSource(addr string) (*workloadapi.X509Source, error) {
+	sourcesMtx.Lock()
+	defer sourcesMtx.Unlock()
+	if src, ok := sources[addr]; ok {
+		return src, nil
+	}
+	// The context only bounds waiting for the first SVID; the source keeps watching for updates after that.
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+	src, err := workloadapi.NewX509Source(ctx, workloadapi.WithClientOptions(workloadapi.WithAddr(addr)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get an X.509 SVID from %s: %v", addr, err)
+	}
+	sources[addr] = src
+	return src, nil
+}
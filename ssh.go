@@ -0,0 +1,202 @@
+package clientconfig
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sshVariables configure the tunnel; they're only valid together with ETCD_SSH_JUMP_HOST.
+var sshVariables = []string{"ETCD_SSH_USER", "ETCD_SSH_KEY", "ETCD_SSH_KNOWN_HOSTS"}
+
+// newSSHDialer returns a dialer that tunnels connections through an SSH connection to ETCD_SSH_JUMP_HOST, which is itself dialed with next.
+func newSSHDialer(o *options, settings Settings, next dialFunc) (dialFunc, error) {
+	host := settings["ETCD_SSH_JUMP_HOST"]
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "22")
+	}
+	user := settings["ETCD_SSH_USER"]
+	if user == "" {
+		user = o.base.Getenv("USER")
+	}
+	if user == "" {
+		return nil, errorf(CodeInvalidValue, "ETCD_SSH_USER", "ETCD_SSH_USER is required with ETCD_SSH_JUMP_HOST if $USER isn't set")
+	}
+	cfg := &ssh.ClientConfig{User: user}
+	if v := settings["ETCD_SSH_KEY"]; v != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(v))
+		if err != nil {
+			var ppe *ssh.PassphraseMissingError
+			if errors.As(err, &ppe) {
+				return nil, errorf(CodeInvalidValue, "ETCD_SSH_KEY", "ETCD_SSH_KEY is encrypted; load it into your ssh-agent and unset ETCD_SSH_KEY instead")
+			}
+			return nil, errorf(CodeInvalidValue, "ETCD_SSH_KEY", "failed to parse ETCD_SSH_KEY: %v", err)
+		}
+		cfg.Auth = append(cfg.Auth, ssh.PublicKeys(signer))
+	} else if sock := o.base.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		cfg.Auth = append(cfg.Auth, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+			c, err := net.Dial("unix", sock)
+			if err != nil {
+				return nil, fmt.Errorf("failed to connect to ssh-agent: %v", err)
+			}
+			// The signers use the connection, so it stays open until the process exits. We only open one per SSH connection.
+			return agent.NewClient(c).Signers()
+		}))
+	} else {
+		return nil, errorf(CodeInvalidValue, "ETCD_SSH_KEY", "ETCD_SSH_JUMP_HOST needs ETCD_SSH_KEY (or ETCD_SSH_KEY_FILE) or a running ssh-agent")
+	}
+	if v := settings["ETCD_SSH_KNOWN_HOSTS"]; v != "" {
+		cb, err := fixedHostKeys("ETCD_SSH_KNOWN_HOSTS", v)
+		if err != nil {
+			return nil, err
+		}
+		cfg.HostKeyCallback = cb
+	} else {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, errorf(CodeInvalidValue, "ETCD_SSH_KNOWN_HOSTS", "ETCD_SSH_KNOWN_HOSTS is required if there's no home directory: %v", err)
+		}
+		cb, err := knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+		if err != nil {
+			return nil, errorf(CodeUnreadableFile, "ETCD_SSH_KNOWN_HOSTS", "failed to read ~/.ssh/known_hosts to verify ETCD_SSH_JUMP_HOST (set ETCD_SSH_KNOWN_HOSTS instead): %v", err)
+		}
+		cfg.HostKeyCallback = cb
+	}
+	if next == nil {
+		next = baseDial
+	}
+	t := &sshTunnel{o: o, addr: host, cfg: cfg, dial: next}
+	return t.dialContext, nil
+}
+
+// fixedHostKeys accepts the keys in v, which is in known_hosts format (like the output of ssh-keyscan). Host patterns are ignored; all keys are for the jump host.
+func fixedHostKeys(k, v string) (ssh.HostKeyCallback, error) {
+	var keys [][]byte
+	rest := []byte(v)
+	for {
+		_, _, key, _, r, err := ssh.ParseKnownHosts(rest)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, errorf(CodeInvalidValue, k, "failed to parse %s as known_hosts lines: %v", k, err)
+		}
+		keys = append(keys, key.Marshal())
+		rest = r
+	}
+	if len(keys) == 0 {
+		return nil, errorf(CodeInvalidValue, k, "%s doesn't contain any host keys", k)
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		m := key.Marshal()
+		for _, k := range keys {
+			if bytes.Equal(k, m) {
+				return nil
+			}
+		}
+		return fmt.Errorf("ssh: host key of %s isn't in ETCD_SSH_KNOWN_HOSTS", hostname)
+	}, nil
+}
+
+// sshTunnel keeps one SSH connection to the jump host, shared by all connections to etcd. It reconnects when the connection breaks.
+type sshTunnel struct {
+	o    *options
+	addr string
+	cfg  *ssh.ClientConfig
+	dial dialFunc
+
+	mtx    sync.Mutex
+	client *ssh.Client
+}
+
+func (t *sshTunnel) dialContext(ctx context.Context, addr string) (net.Conn, error) {
+	network, address := splitDialTarget(addr)
+	c, err := t.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dialSSH(ctx, c, network, address)
+	if _, rejected := err.(*ssh.OpenChannelError); err == nil || rejected || ctx.Err() != nil {
+		// A rejected channel means the jump host is fine, but couldn't reach etcd.
+		return conn, err
+	}
+	// The jump host might have dropped our connection. Try once with a fresh one.
+	t.dropClient(c)
+	if c, err = t.getClient(ctx); err != nil {
+		return nil, err
+	}
+	return dialSSH(ctx, c, network, address)
+}
+
+func (t *sshTunnel) getClient(ctx context.Context) (*ssh.Client, error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.client != nil {
+		return t.client, nil
+	}
+	conn, err := t.dial(ctx, t.addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ETCD_SSH_JUMP_HOST %s: %v", t.addr, err)
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(dl)
+	}
+	sc, chans, reqs, err := ssh.NewClientConn(conn, t.addr, t.cfg)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to set up SSH connection to %s: %v", t.addr, err)
+	}
+	conn.SetDeadline(time.Time{})
+	t.client = ssh.NewClient(sc, chans, reqs)
+	go func(c *ssh.Client) {
+		err := c.Wait()
+		t.o.warnf("SSH connection to %s closed: %v", t.addr, err)
+		t.dropClient(c)
+	}(t.client)
+	return t.client, nil
+}
+
+// dropClient forgets c (if it's still the current client) and closes it.
+func (t *sshTunnel) dropClient(c *ssh.Client) {
+	t.mtx.Lock()
+	if t.client == c {
+		t.client = nil
+	}
+	t.mtx.Unlock()
+	c.Close()
+}
+
+// dialSSH is c.Dial, but gives up when ctx is done.
+func dialSSH(ctx context.Context, c *ssh.Client, network, address string) (net.Conn, error) {
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		conn, err := c.Dial(network, address)
+		ch <- result{conn, err}
+	}()
+	select {
+	case r := <-ch:
+		return r.conn, r.err
+	case <-ctx.Done():
+		go func() {
+			if r := <-ch; r.conn != nil {
+				r.conn.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
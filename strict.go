@@ -0,0 +1,150 @@
+package clientconfig
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// environLister is implemented by Environments that can list their variables, which strict mode needs.
+type environLister interface {
+	Environ() []string
+}
+
+func (osEnvironment) Environ() []string {
+	return os.Environ()
+}
+
+func (e lookuperEnvironment) Environ() []string {
+	m, ok := e.Lookuper.(MapLookuper)
+	if !ok {
+		return nil
+	}
+	var ret []string
+	for k, v := range m {
+		ret = append(ret, k+"="+v)
+	}
+	return ret
+}
+
+// strictMode returns whether unknown ETCD_* variables are an error, by WithStrict or ETCD_STRICT.
+func strictMode(ctx context.Context, o *options) (bool, error) {
+	if o.strict {
+		return true, nil
+	}
+	v, err := readVariable(ctx, o, "ETCD_STRICT")
+	if err != nil || v == "" {
+		return false, err
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, errorf(CodeInvalidValue, "ETCD_STRICT", "failed to parse ETCD_STRICT as bool (%q)", v)
+	}
+	return b, nil
+}
+
+// knownVariableNames returns all variable names we read, including the _FILE, _B64, _COMMAND, _KEYRING and _SOURCE variants.
+func knownVariableNames() map[string]bool {
+	known := map[string]bool{}
+	for _, v := range EnvVars() {
+		known[v.Name] = true
+		known[v.Name+"_FILE"] = true
+		if base64Variables[v.Name] {
+			known[v.Name+"_B64"] = true
+		}
+		if commandVariables[v.Name] {
+			known[v.Name+"_COMMAND"] = true
+		}
+		if keyringVariables[v.Name] {
+			known[v.Name+"_KEYRING"] = true
+		}
+		if resolvable(v.Name) {
+			known[v.Name+"_SOURCE"] = true
+		}
+	}
+	return known
+}
+
+// checkUnknownVariables returns an error listing the ETCD_* variables (with o's prefix and cluster) that we don't know, so typos don't go unnoticed.
+func checkUnknownVariables(o *options) error {
+	l, ok := o.base.(environLister)
+	if !ok {
+		o.warnf("strict mode can't list the variables of this Environment, so unknown variables aren't detected")
+		return nil
+	}
+	prefix := o.prefix + "ETCD_"
+	if o.cluster != "" {
+		prefix += strings.ToUpper(o.cluster) + "_"
+	}
+	known := knownVariableNames()
+	var unknown []string
+	for _, kv := range l.Environ() {
+		k := strings.SplitN(kv, "=", 2)[0]
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		name := "ETCD_" + strings.TrimPrefix(k, prefix)
+		if known[name] {
+			continue
+		}
+		if o.cluster == "" {
+			// Variables of named clusters (see WithCluster) are checked by their own config.
+			if sp := strings.SplitN(strings.TrimPrefix(name, "ETCD_"), "_", 2); len(sp) == 2 && isClusterName(sp[0]) && known["ETCD_"+sp[1]] {
+				continue
+			}
+		}
+		if s := closestVariable(name, known); s != "" {
+			k += " (did you mean " + strings.Replace(s, "ETCD_", prefix, 1) + "?)"
+		}
+		unknown = append(unknown, k)
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return errorf(CodeUnknownVariable, strings.SplitN(unknown[0], " ", 2)[0], "unknown variable(s) in strict mode: %s", strings.Join(unknown, ", "))
+}
+
+// closestVariable returns the known name with the smallest edit distance to name, if it's close enough to be a typo.
+func closestVariable(name string, known map[string]bool) string {
+	best, bestDist := "", 3
+	for k := range known {
+		if d := editDistance(name, k); d < bestDist || (d == bestDist && k < best) {
+			best, bestDist = k, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
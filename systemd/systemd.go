@@ -0,0 +1,57 @@
+// Package systemd tells systemd when a service's etcd client is connected, so Type=notify units only become ready once etcd is reachable.
+//
+// It lives in its own module so github.com/coreos/go-systemd only ends up in programs that use it.
+package systemd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/daemon"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// NotifyReady waits until cli can reach the cluster and then tells systemd the service is ready (READY=1), so Type=notify units gate their readiness on etcd.
+// While waiting it reports the connection state with STATUS=. Outside of systemd it only waits for the connection.
+func NotifyReady(ctx context.Context, cli *clientv3.Client) error {
+	daemon.SdNotify(false, "STATUS=Connecting to etcd")
+	for {
+		vctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		ep, err := verify(vctx, cli)
+		cancel()
+		if err == nil {
+			if _, err := daemon.SdNotify(false, fmt.Sprintf("READY=1\nSTATUS=Connected to etcd at %s", ep)); err != nil {
+				return fmt.Errorf("failed to notify systemd: %v", err)
+			}
+			return nil
+		}
+		daemon.SdNotify(false, fmt.Sprintf("STATUS=Waiting for etcd: %v", err))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// verify checks that at least one endpoint of cli answers a Status call. It returns the endpoint that answered.
+func verify(ctx context.Context, cli *clientv3.Client) (string, error) {
+	eps := cli.Endpoints()
+	if len(eps) == 0 {
+		return "", errors.New("no endpoints configured")
+	}
+	var lastErr error
+	for _, ep := range eps {
+		if _, err := cli.Status(ctx, ep); err != nil {
+			lastErr = fmt.Errorf("%s: %v", ep, err)
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		return ep, nil
+	}
+	return "", lastErr
+}
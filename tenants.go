@@ -0,0 +1,166 @@
+package clientconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc"
+)
+
+// TenantCredentials returns the username and password to use for a tenant.
+type TenantCredentials func(ctx context.Context, tenant string) (username, password string, err error)
+
+// TenantToken returns the bearer token to use for a tenant, like ETCD_AUTH_TOKEN does for a single client. A single trailing newline is removed.
+type TenantToken func(ctx context.Context, tenant string) (token string, err error)
+
+// TenantClients creates a client per tenant, which share the endpoints and TLS settings of a base config but authenticate with their own credentials. Create it with NewTenantClients.
+type TenantClients struct {
+	base   clientv3.Config
+	lookup TenantCredentials
+	token  TenantToken
+
+	mtx     sync.Mutex
+	clients map[string]*tenantClient
+	closed  bool
+}
+
+type tenantClient struct {
+	ready chan struct{}
+	cli   *clientv3.Client
+	err   error
+}
+
+// NewTenantClients returns a TenantClients that derives clients from base (for example from Get) with the credentials returned by lookup.
+func NewTenantClients(base clientv3.Config, lookup TenantCredentials) *TenantClients {
+	return &TenantClients{
+		base:    base,
+		lookup:  lookup,
+		clients: map[string]*tenantClient{},
+	}
+}
+
+// NewTenantTokenClients is like NewTenantClients, but authenticates every tenant with the token returned by lookup instead of a username and password.
+// The DialOptions of base are kept, so base shouldn't have a token of its own (from ETCD_AUTH_TOKEN or ETCD_OIDC_ISSUER). Like ETCD_AUTH_TOKEN, tokens are never sent over http:// endpoints.
+func NewTenantTokenClients(base clientv3.Config, lookup TenantToken) *TenantClients {
+	return &TenantClients{
+		base:    base,
+		token:   lookup,
+		clients: map[string]*tenantClient{},
+	}
+}
+
+// Client returns the client for tenant, creating it on first use. Concurrent calls for the same tenant share the client.
+// Failures aren't cached, so the next call tries again.
+func (t *TenantClients) Client(ctx context.Context, tenant string) (*clientv3.Client, error) {
+	t.mtx.Lock()
+	if t.closed {
+		t.mtx.Unlock()
+		return nil, errors.New("TenantClients is closed")
+	}
+	tc, ok := t.clients[tenant]
+	if !ok {
+		tc = &tenantClient{ready: make(chan struct{})}
+		t.clients[tenant] = tc
+	}
+	t.mtx.Unlock()
+	if ok {
+		select {
+		case <-tc.ready:
+			return tc.cli, tc.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	tc.cli, tc.err = t.dial(ctx, tenant)
+	t.mtx.Lock()
+	if (tc.err != nil || t.closed) && t.clients[tenant] == tc {
+		delete(t.clients, tenant)
+	}
+	closed := t.closed
+	t.mtx.Unlock()
+	if closed && tc.err == nil {
+		tc.cli.Close()
+		tc.cli, tc.err = nil, errors.New("TenantClients is closed")
+	}
+	close(tc.ready)
+	return tc.cli, tc.err
+}
+
+func (t *TenantClients) dial(ctx context.Context, tenant string) (*clientv3.Client, error) {
+	if t.token != nil {
+		return t.dialWithToken(ctx, tenant)
+	}
+	user, pass, err := t.lookup(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+	c := t.base
+	c.Username = user
+	c.Password = pass
+	return clientv3.New(c)
+}
+
+func (t *TenantClients) dialWithToken(ctx context.Context, tenant string) (*clientv3.Client, error) {
+	token, err := t.token(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+	c := t.base
+	for _, ep := range c.Endpoints {
+		if strings.HasPrefix(ep, "http://") {
+			return nil, fmt.Errorf("the token of tenant %q would be sent unencrypted to %s; use https:// or a unix socket", tenant, ep)
+		}
+	}
+	c.Username = ""
+	c.Password = ""
+	c.DialOptions = append(c.DialOptions[:len(c.DialOptions):len(c.DialOptions)], grpc.WithPerRPCCredentials(tokenSourceCredentials{staticToken(trimNewline(token))}))
+	return clientv3.New(c)
+}
+
+// Forget closes the client of tenant if there is one, for example because its credentials changed. The next call to Client creates a new one.
+func (t *TenantClients) Forget(tenant string) error {
+	t.mtx.Lock()
+	tc, ok := t.clients[tenant]
+	delete(t.clients, tenant)
+	t.mtx.Unlock()
+	if !ok {
+		return nil
+	}
+	<-tc.ready
+	if tc.cli == nil {
+		return nil
+	}
+	return tc.cli.Close()
+}
+
+// Close closes all clients and returns the first error.
+func (t *TenantClients) Close() error {
+	t.mtx.Lock()
+	t.closed = true
+	clients := t.clients
+	t.clients = map[string]*tenantClient{}
+	t.mtx.Unlock()
+	var err error
+	for _, tc := range clients {
+		<-tc.ready
+		if tc.cli == nil {
+			continue
+		}
+		if cerr := tc.cli.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// staticToken is a TokenSource that always returns the same token.
+type staticToken string
+
+func (t staticToken) Token() (string, time.Time, error) {
+	return string(t), time.Time{}, nil
+}
@@ -0,0 +1,125 @@
+package clientconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// TLSProbe is what ProbeTLS found out about a single endpoint.
+type TLSProbe struct {
+	Endpoint string
+	// Err is set if the endpoint couldn't be reached at all. The other fields are only valid if Err is nil.
+	Err error
+	// TLS is whether the endpoint speaks TLS.
+	TLS bool
+	// ClientCertRequested is whether the endpoint asked for a client certificate.
+	ClientCertRequested bool
+	// VerifyErr is why the server certificate isn't trusted by the config, or nil if it is.
+	VerifyErr error
+}
+
+// ProbeTLS connects to every endpoint of c to find out whether it speaks TLS and whether it asks for a client certificate, without sending any credentials.
+// It returns a copy of c in which endpoints without a scheme have gotten http:// or https:// to match, and TLS is enabled (with the system roots) if needed. Endpoints that couldn't be probed are left alone.
+// This is meant for diagnostics and first deploys against a cluster with an unknown TLS setup. Once you know, configure it explicitly.
+func ProbeTLS(ctx context.Context, c clientv3.Config) (clientv3.Config, []TLSProbe) {
+	probes := make([]TLSProbe, len(c.Endpoints))
+	var wg sync.WaitGroup
+	for i, ep := range c.Endpoints {
+		wg.Add(1)
+		go func(i int, ep string) {
+			defer wg.Done()
+			probes[i] = probeTLS(ctx, c.TLS, ep)
+		}(i, ep)
+	}
+	wg.Wait()
+	eps := make([]string, len(c.Endpoints))
+	for i, p := range probes {
+		eps[i] = p.Endpoint
+		if p.Err != nil || strings.Contains(p.Endpoint, "://") {
+			continue
+		}
+		if p.TLS {
+			eps[i] = "https://" + p.Endpoint
+			if c.TLS == nil {
+				c.TLS = &tls.Config{}
+			}
+		} else {
+			eps[i] = "http://" + p.Endpoint
+		}
+	}
+	c.Endpoints = eps
+	return c, probes
+}
+
+func probeTLS(ctx context.Context, base *tls.Config, ep string) TLSProbe {
+	p := TLSProbe{Endpoint: ep}
+	addr := ep
+	if i := strings.Index(addr, "://"); i != -1 {
+		switch addr[:i] {
+		case "unix", "unixs":
+			addr = "unix://" + addr[i+3:]
+		default:
+			addr = addr[i+3:]
+		}
+	}
+	network, address := splitDialTarget(addr)
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, address)
+	if err != nil {
+		p.Err = err
+		return p
+	}
+	defer conn.Close()
+	if dl, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(dl)
+	} else {
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+	}
+	serverName := ""
+	var roots *x509.CertPool
+	if base != nil {
+		serverName = base.ServerName
+		roots = base.RootCAs
+	}
+	if serverName == "" && network == "tcp" {
+		serverName, _, _ = net.SplitHostPort(address)
+	}
+	tc := tls.Client(conn, &tls.Config{
+		ServerName: serverName,
+		// We verify below, so we can still report what we found if the certificate isn't trusted.
+		InsecureSkipVerify: true,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			p.ClientCertRequested = true
+			return &tls.Certificate{}, nil
+		},
+	})
+	if err := tc.Handshake(); err != nil && !p.ClientCertRequested {
+		// Plaintext servers don't answer with a TLS handshake.
+		return p
+	}
+	p.TLS = true
+	certs := tc.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return p
+	}
+	opts := x509.VerifyOptions{Roots: roots, DNSName: serverName, Intermediates: x509.NewCertPool()}
+	for _, ic := range certs[1:] {
+		opts.Intermediates.AddCert(ic)
+	}
+	if net.ParseIP(serverName) != nil {
+		opts.DNSName = ""
+		if err := certs[0].VerifyHostname(serverName); err != nil {
+			p.VerifyErr = err
+			return p
+		}
+	}
+	_, p.VerifyErr = certs[0].Verify(opts)
+	return p
+}
@@ -0,0 +1,70 @@
+package clientconfig
+
+import (
+	"crypto/tls"
+	"sort"
+	"strings"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// tlsVersionNames returns the accepted values for ETCD_TLS_MIN_VERSION and ETCD_TLS_MAX_VERSION.
+func tlsVersionNames() []string {
+	var ret []string
+	for v := range tlsVersions {
+		ret = append(ret, v)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// parseTLSVersion parses a version like "1.2" from variable k.
+func parseTLSVersion(k, v string) (uint16, error) {
+	ver, ok := tlsVersions[strings.TrimPrefix(v, "TLS")]
+	if !ok {
+		return 0, errorf(CodeInvalidValue, k, "invalid %s %q: should be one of %s", k, v, strings.Join(tlsVersionNames(), ", "))
+	}
+	return ver, nil
+}
+
+// cipherSuiteNames returns the names of the secure cipher suites Go implements.
+func cipherSuiteNames() []string {
+	var ret []string
+	for _, cs := range tls.CipherSuites() {
+		ret = append(ret, cs.Name)
+	}
+	return ret
+}
+
+// parseCipherSuites parses ETCD_TLS_CIPHER_SUITES, a comma separated list of names like TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256. Insecure suites are accepted too, with a warning.
+func parseCipherSuites(o *options, v string) ([]uint16, error) {
+	ids := map[string]uint16{}
+	for _, cs := range tls.CipherSuites() {
+		ids[cs.Name] = cs.ID
+	}
+	insecure := map[string]uint16{}
+	for _, cs := range tls.InsecureCipherSuites() {
+		insecure[cs.Name] = cs.ID
+	}
+	var ret []uint16
+	for _, n := range strings.Split(v, ",") {
+		n = strings.TrimSpace(n)
+		if n == "" {
+			continue
+		}
+		if id, ok := ids[n]; ok {
+			ret = append(ret, id)
+		} else if id, ok := insecure[n]; ok {
+			o.warnf("ETCD_TLS_CIPHER_SUITES contains insecure cipher suite %s", n)
+			ret = append(ret, id)
+		} else {
+			return nil, errorf(CodeInvalidValue, "ETCD_TLS_CIPHER_SUITES", "unknown cipher suite %q in ETCD_TLS_CIPHER_SUITES", n)
+		}
+	}
+	return ret, nil
+}
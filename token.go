@@ -0,0 +1,61 @@
+package clientconfig
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// tokenCredentials sends ETCD_AUTH_TOKEN as a bearer token with every call. If the token came from ETCD_AUTH_TOKEN_FILE, the file is re-read when it changes.
+type tokenCredentials struct {
+	o     *options
+	files *fileReloader
+
+	mtx   sync.Mutex
+	token string
+}
+
+func newTokenCredentials(o *options, token string, reloadInterval time.Duration) *tokenCredentials {
+	t := &tokenCredentials{o: o, token: token}
+	if fn := o.env.Getenv("ETCD_AUTH_TOKEN_FILE"); fn != "" {
+		if reloadInterval == 0 {
+			// Unlike certificates, the token is needed for every call, not every connection.
+			reloadInterval = time.Second
+		}
+		t.files = newFileReloader(o.env, []string{fn}, [][]byte{[]byte(token)}, reloadInterval)
+	}
+	return t
+}
+
+// GetRequestMetadata implements credentials.PerRPCCredentials. If the file can't be read, it keeps using the previous token.
+func (t *tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.files != nil {
+		contents, changed, err := t.files.read()
+		if err != nil {
+			t.o.warnf("failed to reload ETCD_AUTH_TOKEN_FILE, using the previous token: %v", err)
+		} else if changed {
+			t.token = trimFileValue(t.o, "ETCD_AUTH_TOKEN", string(contents[0]))
+		}
+	}
+	return map[string]string{"authorization": "Bearer " + t.token}, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials. ApplySettings checks the endpoints instead, so unix sockets to a local proxy keep working.
+func (t *tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// checkTokenTransport returns an error if the token (configured by k) would be sent over plaintext TCP.
+func checkTokenTransport(c clientv3.Config, k string) error {
+	for _, ep := range c.Endpoints {
+		if strings.HasPrefix(ep, "http://") {
+			return errorf(CodeTLSRequired, k, "the token from %s would be sent unencrypted to %s; use https:// or a unix socket", k, ep)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package clientconfig
+
+import (
+	"crypto/x509"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Validate checks c for mistakes that would otherwise only show up as connection failures (or not at all), like https endpoints without TLS settings or a username without a password.
+// It works on any config, including ones you built yourself. All problems are returned together with errors.Join; each of them is an *Error.
+func Validate(c clientv3.Config) error {
+	var errs []error
+	add := func(code, variable, format string, args ...interface{}) {
+		errs = append(errs, errorf(code, variable, format, args...))
+	}
+	if len(c.Endpoints) == 0 {
+		add(CodeNotConfigured, "ETCD_ENDPOINTS", "there are no endpoints")
+	}
+	for _, ep := range c.Endpoints {
+		if strings.TrimSpace(ep) != ep || ep == "" {
+			add(CodeInvalidValue, "ETCD_ENDPOINTS", "endpoint %q is empty or has surrounding whitespace", ep)
+			continue
+		}
+		if strings.Contains(ep, "://") {
+			if u, err := url.Parse(ep); err != nil || u.Host == "" && !strings.HasPrefix(ep, "unix") {
+				add(CodeInvalidValue, "ETCD_ENDPOINTS", "endpoint %q isn't a valid URL", ep)
+			}
+		}
+	}
+	plain, secure := splitSchemes(c)
+	if c.TLS == nil && len(secure) > 0 {
+		add(CodeInconsistentTLS, "ETCD_SERVER_CA", "endpoint %s uses TLS, but there are no TLS settings, so the server is verified against the system CAs and no client certificate is sent", secure[0])
+	}
+	if c.TLS != nil && len(plain) > 0 {
+		add(CodeInconsistentTLS, "ETCD_ENDPOINTS", "TLS is configured, but endpoint %s doesn't use it", plain[0])
+	}
+	if c.Username != "" && c.Password == "" {
+		add(CodeConflictingVariables, "ETCD_PASSWORD", "a username is set, but no password, so the client won't authenticate")
+	}
+	if c.Username == "" && c.Password != "" {
+		add(CodeConflictingVariables, "ETCD_USERNAME", "a password is set, but no username, so the client won't authenticate")
+	}
+	for _, d := range []struct {
+		k string
+		d time.Duration
+	}{{"ETCD_DIAL_TIMEOUT", c.DialTimeout}, {"ETCD_DIAL_KEEPALIVE_TIME", c.DialKeepAliveTime}, {"ETCD_DIAL_KEEPALIVE_TIMEOUT", c.DialKeepAliveTimeout}, {"ETCD_AUTO_SYNC_INTERVAL", c.AutoSyncInterval}, {"ETCD_BACKOFF_WAIT_BETWEEN", c.BackoffWaitBetween}} {
+		if d.d < 0 {
+			add(CodeInvalidValue, d.k, "%s is negative (%s)", d.k, d.d)
+		}
+	}
+	if c.DialKeepAliveTimeout > 0 && c.DialKeepAliveTime == 0 {
+		add(CodeConflictingVariables, "ETCD_DIAL_KEEPALIVE_TIMEOUT", "ETCD_DIAL_KEEPALIVE_TIMEOUT has no effect without ETCD_DIAL_KEEPALIVE_TIME")
+	}
+	if c.BackoffJitterFraction < 0 || c.BackoffJitterFraction > 1 {
+		add(CodeInvalidValue, "ETCD_BACKOFF_JITTER_FRACTION", "ETCD_BACKOFF_JITTER_FRACTION should be between 0 and 1 (%g)", c.BackoffJitterFraction)
+	}
+	for _, n := range []struct {
+		k string
+		n int
+	}{{"ETCD_MAX_CALL_SEND_MSG_SIZE", c.MaxCallSendMsgSize}, {"ETCD_MAX_CALL_RECV_MSG_SIZE", c.MaxCallRecvMsgSize}} {
+		if n.n < 0 {
+			add(CodeInvalidValue, n.k, "%s is negative (%d)", n.k, n.n)
+		}
+	}
+	if t := c.TLS; t != nil {
+		if t.InsecureSkipVerify && t.RootCAs != nil {
+			add(CodeConflictingVariables, "ETCD_INSECURE_SKIP_VERIFY", "the server CA is ignored because certificate verification is disabled")
+		}
+		if t.MinVersion != 0 && t.MaxVersion != 0 && t.MinVersion > t.MaxVersion {
+			add(CodeConflictingVariables, "ETCD_TLS_MIN_VERSION", "ETCD_TLS_MIN_VERSION is higher than ETCD_TLS_MAX_VERSION, so no TLS version is allowed")
+		}
+		for _, crt := range t.Certificates {
+			if len(crt.Certificate) == 0 {
+				add(CodeInvalidCertificate, "ETCD_CLIENT_CERT", "a client certificate without certificate data is configured")
+				continue
+			}
+			if crt.PrivateKey == nil {
+				add(CodeInvalidCertificate, "ETCD_CLIENT_KEY", "the client certificate has no private key")
+			}
+			leaf := crt.Leaf
+			if leaf == nil {
+				var err error
+				if leaf, err = x509.ParseCertificate(crt.Certificate[0]); err != nil {
+					add(CodeInvalidCertificate, "ETCD_CLIENT_CERT", "failed to parse the client certificate: %v", err)
+					continue
+				}
+			}
+			if now := time.Now(); now.After(leaf.NotAfter) {
+				add(CodeInvalidCertificate, "ETCD_CLIENT_CERT", "the client certificate expired at %s", leaf.NotAfter.Format(time.RFC3339))
+			} else if now.Before(leaf.NotBefore) {
+				add(CodeInvalidCertificate, "ETCD_CLIENT_CERT", "the client certificate isn't valid until %s", leaf.NotBefore.Format(time.RFC3339))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,82 @@
+package clientconfig
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+type customVariable struct {
+	name  string
+	apply func(c *clientv3.Config, value string) error
+	// group is set instead of apply for variables registered with RegisterVariables.
+	group *variableGroup
+}
+
+type variableGroup struct {
+	names []string
+	apply func(c *clientv3.Config, values map[string]string) error
+}
+
+var (
+	customVariablesMtx sync.Mutex
+	customVariables    []customVariable
+)
+
+// RegisterVariable adds an application specific variable that is read together with ours: it supports the same _FILE suffix, shows up in Settings and is served by the sidecar.
+// Like with Bind, the name must start with ETCD_.
+// apply is called by ApplySettings with the raw value if the variable is set, after the built-in variables have been applied. It can parse the value into the application's own settings, modify the config, or both.
+// RegisterVariable is meant to be called from init functions and panics if the name is already taken.
+func RegisterVariable(name string, apply func(c *clientv3.Config, value string) error) {
+	customVariablesMtx.Lock()
+	defer customVariablesMtx.Unlock()
+	checkVariableName(name)
+	customVariables = append(customVariables, customVariable{name: name, apply: apply})
+}
+
+// RegisterVariables is like RegisterVariable for variables that only make sense together, like an address and the settings for connecting to it.
+// apply is called once if any of them is set, with the values of the ones that are set.
+func RegisterVariables(names []string, apply func(c *clientv3.Config, values map[string]string) error) {
+	customVariablesMtx.Lock()
+	defer customVariablesMtx.Unlock()
+	g := &variableGroup{names: append([]string(nil), names...), apply: apply}
+	for _, name := range names {
+		checkVariableName(name)
+		customVariables = append(customVariables, customVariable{name: name, group: g})
+	}
+}
+
+// checkVariableName panics if name can't be used for a custom variable. The caller must hold customVariablesMtx.
+func checkVariableName(name string) {
+	if !strings.HasPrefix(name, "ETCD_") || strings.HasSuffix(name, "_FILE") || strings.ContainsAny(name, "= ") {
+		panic(fmt.Sprintf("clientconfig: invalid variable name %q", name))
+	}
+	for _, k := range variables {
+		if k == name {
+			panic(fmt.Sprintf("clientconfig: variable %q is built in", name))
+		}
+	}
+	for _, cv := range customVariables {
+		if cv.name == name {
+			panic(fmt.Sprintf("clientconfig: variable %q registered twice", name))
+		}
+	}
+}
+
+// registeredVariables returns the custom variables in registration order.
+func registeredVariables() []customVariable {
+	customVariablesMtx.Lock()
+	defer customVariablesMtx.Unlock()
+	return append([]customVariable(nil), customVariables...)
+}
+
+// allVariables returns the names of the built-in and custom variables.
+func allVariables() []string {
+	ret := append([]string(nil), variables...)
+	for _, cv := range registeredVariables() {
+		ret = append(ret, cv.name)
+	}
+	return ret
+}
@@ -0,0 +1,258 @@
+// Package vault gets the etcd credentials from HashiCorp Vault instead of the environment.
+//
+// Importing it registers the ETCD_VAULT_PATH variable, which is a path in Vault's HTTP API (without /v1/). What it does depends on the path:
+//
+//   - A KV secret (version 1 or 2, like secret/data/etcd/prod) is read once and its username and password fields are used to authenticate to etcd.
+//   - A PKI issue endpoint (like pki/issue/etcd-client?common_name=myapp) issues a client certificate. The query parameters are passed to Vault, so you can request a ttl too. A new certificate is issued when two thirds of the lifetime of the current one have passed, for as long as the client lives.
+//
+// Vault is found with the variables the vault command uses: VAULT_ADDR, VAULT_TOKEN (or ~/.vault-token, which is re-read for every request so Vault Agent can renew it), VAULT_NAMESPACE and VAULT_CACERT.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	clientconfig "github.com/Jille/etcd-client-from-env"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func init() {
+	clientconfig.RegisterVariable("ETCD_VAULT_PATH", apply)
+}
+
+// requestTimeout bounds every request to Vault.
+const requestTimeout = 30 * time.Second
+
+func apply(c *clientv3.Config, value string) error {
+	vc, err := newClient()
+	if err != nil {
+		return err
+	}
+	p, err := url.Parse(strings.TrimPrefix(value, "/"))
+	if err != nil {
+		return fmt.Errorf("failed to parse %q: %v", value, err)
+	}
+	if strings.Contains("/"+p.Path, "/issue/") {
+		return applyCertificate(c, vc, p)
+	}
+	if p.RawQuery != "" {
+		return fmt.Errorf("query parameters are only supported for PKI issue endpoints (%q)", value)
+	}
+	return applyPassword(c, vc, p.Path)
+}
+
+func applyPassword(c *clientv3.Config, vc *client, path string) error {
+	if c.Username != "" {
+		return errors.New("the username is already configured (by ETCD_USERNAME?)")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	data, err := vc.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	if inner, ok := data["data"].(map[string]interface{}); ok && data["metadata"] != nil {
+		// KV version 2 wraps the secret in another data field.
+		data = inner
+	}
+	username, _ := data["username"].(string)
+	password, _ := data["password"].(string)
+	if username == "" || password == "" {
+		return fmt.Errorf("secret %s doesn't have username and password fields", path)
+	}
+	c.Username = username
+	c.Password = password
+	return nil
+}
+
+func applyCertificate(c *clientv3.Config, vc *client, p *url.URL) error {
+	if c.TLS != nil && (len(c.TLS.Certificates) > 0 || c.TLS.GetClientCertificate != nil) {
+		return errors.New("a client certificate is already configured (by ETCD_CLIENT_CERT?)")
+	}
+	params := map[string]string{}
+	for k, vs := range p.Query() {
+		params[k] = vs[0]
+	}
+	if params["common_name"] == "" {
+		return fmt.Errorf("PKI path %s needs a common_name parameter, like %s?common_name=myapp", p.Path, p.Path)
+	}
+	ci := &certIssuer{client: vc, path: p.Path, params: params}
+	ci.mtx.Lock()
+	err := ci.issue()
+	ci.mtx.Unlock()
+	if err != nil {
+		return err
+	}
+	if c.TLS == nil {
+		c.TLS = &tls.Config{}
+	} else {
+		c.TLS = c.TLS.Clone()
+	}
+	c.TLS.GetClientCertificate = ci.GetClientCertificate
+	return nil
+}
+
+// certIssuer holds a certificate issued by Vault and gets a new one when it's due.
+type certIssuer struct {
+	client *client
+	path   string
+	params map[string]string
+
+	mtx     sync.Mutex
+	cert    *tls.Certificate
+	renewAt time.Time
+	expiry  time.Time
+}
+
+// GetClientCertificate implements tls.Config.GetClientCertificate. If renewing fails, the current certificate is used until it expires.
+func (ci *certIssuer) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	ci.mtx.Lock()
+	defer ci.mtx.Unlock()
+	if now := time.Now(); now.After(ci.renewAt) {
+		if err := ci.issue(); err != nil && now.After(ci.expiry) {
+			return nil, err
+		}
+	}
+	return ci.cert, nil
+}
+
+// issue gets a new certificate. The caller must hold mtx.
+func (ci *certIssuer) issue() error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	data, err := ci.client.do(ctx, http.MethodPost, ci.path, ci.params)
+	if err != nil {
+		return err
+	}
+	certPEM, _ := data["certificate"].(string)
+	keyPEM, _ := data["private_key"].(string)
+	if chain, ok := data["ca_chain"].([]interface{}); ok {
+		for _, c := range chain {
+			if s, ok := c.(string); ok && s != certPEM {
+				certPEM += "\n" + s
+			}
+		}
+	}
+	cert, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		return fmt.Errorf("certificate issued by %s is invalid: %v", ci.path, err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("certificate issued by %s is invalid: %v", ci.path, err)
+	}
+	ci.cert = &cert
+	ci.expiry = leaf.NotAfter
+	ci.renewAt = leaf.NotBefore.Add(leaf.NotAfter.Sub(leaf.NotBefore) * 2 / 3)
+	return nil
+}
+
+// client is a minimal client for Vault's HTTP API.
+type client struct {
+	addr      string
+	namespace string
+	token     string
+	tokenFile string
+	http      *http.Client
+}
+
+func newClient() (*client, error) {
+	c := &client{
+		addr:      strings.TrimSuffix(os.Getenv("VAULT_ADDR"), "/"),
+		namespace: os.Getenv("VAULT_NAMESPACE"),
+		token:     os.Getenv("VAULT_TOKEN"),
+		http:      &http.Client{Timeout: requestTimeout},
+	}
+	if c.addr == "" {
+		c.addr = "https://127.0.0.1:8200"
+	}
+	if c.token == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("VAULT_TOKEN is not set and there's no home directory for ~/.vault-token: %v", err)
+		}
+		c.tokenFile = filepath.Join(home, ".vault-token")
+	}
+	if fn := os.Getenv("VAULT_CACERT"); fn != "" {
+		pem, err := ioutil.ReadFile(fn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read VAULT_CACERT: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("VAULT_CACERT %s doesn't contain any PEM certificates", fn)
+		}
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.TLSClientConfig = &tls.Config{RootCAs: pool}
+		c.http.Transport = t
+	}
+	return c, nil
+}
+
+// do sends a request to Vault and returns the data field of the response.
+func (c *client) do(ctx context.Context, method, path string, body interface{}) (map[string]interface{}, error) {
+	token := c.token
+	if token == "" {
+		b, err := ioutil.ReadFile(c.tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("VAULT_TOKEN is not set and reading the token failed: %v", err)
+		}
+		token = strings.TrimSpace(string(b))
+	}
+	var rd io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		rd = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.addr+"/v1/"+path, rd)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	req.Header.Set("X-Vault-Request", "true")
+	if c.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault request for %s failed: %v", path, err)
+	}
+	defer resp.Body.Close()
+	var ret struct {
+		Data   map[string]interface{} `json:"data"`
+		Errors []string               `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&ret); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("failed to parse the vault response for %s: %v", path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if len(ret.Errors) > 0 {
+			return nil, fmt.Errorf("vault request for %s failed: %s: %s", path, resp.Status, strings.Join(ret.Errors, "; "))
+		}
+		return nil, fmt.Errorf("vault request for %s failed: %s", path, resp.Status)
+	}
+	if ret.Data == nil {
+		return nil, fmt.Errorf("vault returned no data for %s", path)
+	}
+	return ret.Data, nil
+}
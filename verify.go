@@ -0,0 +1,29 @@
+package clientconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// verify checks that at least one endpoint of cli answers a Status call. It returns the endpoint that answered.
+func verify(ctx context.Context, cli *clientv3.Client) (string, error) {
+	eps := cli.Endpoints()
+	if len(eps) == 0 {
+		return "", errors.New("no endpoints configured")
+	}
+	var lastErr error
+	for _, ep := range eps {
+		if _, err := cli.Status(ctx, ep); err != nil {
+			lastErr = fmt.Errorf("%s: %v", ep, err)
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		return ep, nil
+	}
+	return "", lastErr
+}
@@ -0,0 +1,38 @@
+package clientconfig
+
+import (
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// viaModes are the values of ETCD_VIA.
+var viaModes = []string{"direct", "gateway", "grpc-proxy"}
+
+// applyVia adjusts c to the kind of endpoints ETCD_VIA says it talks to. It returns whether they're a proxy.
+func applyVia(c *clientv3.Config, settings Settings) (bool, error) {
+	v := strings.TrimSpace(settings["ETCD_VIA"])
+	switch v {
+	case "":
+		return settings["ETCD_GATEWAY_ENDPOINT"] != "", nil
+	case "direct":
+		if settings["ETCD_GATEWAY_ENDPOINT"] != "" {
+			return false, errorf(CodeConflictingVariables, "ETCD_VIA", "ETCD_VIA=direct can't be used together with ETCD_GATEWAY_ENDPOINT")
+		}
+		return false, nil
+	case "gateway", "grpc-proxy":
+		// The member list contains the addresses of the members behind the proxy, which typically aren't reachable from here.
+		c.AutoSyncInterval = 0
+		// Detect a dead proxy (or a connection it dropped silently) rather than waiting for the kernel to time it out.
+		if c.DialKeepAliveTime == 0 {
+			c.DialKeepAliveTime = 30 * time.Second
+		}
+		if c.DialKeepAliveTimeout == 0 {
+			c.DialKeepAliveTimeout = 10 * time.Second
+		}
+		return true, nil
+	default:
+		return false, errorf(CodeInvalidValue, "ETCD_VIA", "invalid ETCD_VIA %q (should be %s)", v, strings.Join(viaModes, ", "))
+	}
+}
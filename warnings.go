@@ -0,0 +1,62 @@
+package clientconfig
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// WithWarnFunc passes warnings about suspicious but valid configurations (like ETCD_INSECURE_SKIP_VERIFY, or a password sent without TLS) to f instead of logging them.
+func WithWarnFunc(f func(string)) Option {
+	return func(o *options) {
+		o.warn = f
+	}
+}
+
+// warnSuspicious warns about settings that are valid, but probably not what was intended.
+func warnSuspicious(o *options, c clientv3.Config, settings Settings) {
+	if c.TLS != nil && c.TLS.InsecureSkipVerify {
+		if settings["ETCD_SERVER_CA"] != "" {
+			o.warnf("ETCD_SERVER_CA is ignored, because ETCD_INSECURE_SKIP_VERIFY disables verifying the server certificate")
+		} else {
+			o.warnf("ETCD_INSECURE_SKIP_VERIFY is enabled: the server certificate isn't verified, so anyone in between can impersonate etcd")
+		}
+	}
+	if c.Password != "" {
+		for _, ep := range c.Endpoints {
+			if strings.HasPrefix(ep, "http://") && !isLoopback(ep) {
+				o.warnf("the password is sent unencrypted to %s; use https://", ep)
+				break
+			}
+		}
+	}
+	for _, k := range []string{"ETCD_USERNAME", "ETCD_PASSWORD", "ETCD_CLIENT_KEY_PASSWORD"} {
+		if v := settings[k]; v != strings.TrimSpace(v) {
+			o.warnf("%s starts or ends with whitespace, which is used as part of it", k)
+		}
+	}
+}
+
+// isLoopback returns whether the endpoint ep is on this host, so traffic to it doesn't cross the network.
+func isLoopback(ep string) bool {
+	u, err := url.Parse(ep)
+	if err != nil {
+		return false
+	}
+	if u.Hostname() == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(u.Hostname())
+	return ip != nil && ip.IsLoopback()
+}
+
+// trimNewline removes a single trailing newline (\n or \r\n) from s.
+func trimNewline(s string) string {
+	s, ok := strings.CutSuffix(s, "\n")
+	if ok {
+		s = strings.TrimSuffix(s, "\r")
+	}
+	return s
+}
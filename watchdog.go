@@ -0,0 +1,183 @@
+package clientconfig
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// WatchdogOptions configures StartWatchdog.
+type WatchdogOptions struct {
+	// Interval between checks. Defaults to 30 seconds.
+	Interval time.Duration
+	// Timeout for each check. Defaults to 5 seconds.
+	Timeout time.Duration
+	// MaxRTT is the round trip time above which an endpoint is considered slow. Defaults to 1 second.
+	MaxRTT time.Duration
+	// OnDegraded is called for every problem found during a check.
+	OnDegraded func(Degradation)
+	// OnReport is called with the results of every check, for example to export metrics.
+	OnReport func(WatchdogReport)
+}
+
+// WatchdogReport contains the results of a single check.
+type WatchdogReport struct {
+	Time      time.Time
+	Endpoints []EndpointHealth
+	// Alarms are the active alarms in the cluster, like "NOSPACE".
+	Alarms []MemberAlarm
+	// AlarmErr is set if the alarms couldn't be retrieved.
+	AlarmErr error
+}
+
+// EndpointHealth is the result of a Status call against a single endpoint.
+type EndpointHealth struct {
+	Endpoint string
+	RTT      time.Duration
+	Err      error
+}
+
+// MemberAlarm is an alarm raised by a member.
+type MemberAlarm struct {
+	MemberID uint64
+	Alarm    string
+}
+
+// Degradation is a single problem found by the watchdog.
+type Degradation struct {
+	// Endpoint is empty for problems with the whole cluster.
+	Endpoint string
+	// Problem is "unreachable", "slow" or "alarm".
+	Problem string
+	// Detail is a human readable description.
+	Detail string
+}
+
+// Watchdog periodically checks the health of the cluster. Create it with StartWatchdog.
+type Watchdog struct {
+	cli    *clientv3.Client
+	opts   WatchdogOptions
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mtx  sync.Mutex
+	last WatchdogReport
+}
+
+// StartWatchdog starts a goroutine that periodically runs Status against every endpoint and AlarmList against the cluster.
+// It reports members that are down, slow or raised an alarm (like NOSPACE), so applications get an early warning rather than finding out on the request path.
+func StartWatchdog(cli *clientv3.Client, opts WatchdogOptions) *Watchdog {
+	if opts.Interval <= 0 {
+		opts.Interval = 30 * time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	if opts.MaxRTT <= 0 {
+		opts.MaxRTT = time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Watchdog{
+		cli:    cli,
+		opts:   opts,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go w.run(ctx)
+	return w
+}
+
+// Stop stops the watchdog and waits for a running check to finish.
+func (w *Watchdog) Stop() {
+	w.cancel()
+	<-w.done
+}
+
+// LastReport returns the results of the most recent check.
+func (w *Watchdog) LastReport() WatchdogReport {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return w.last
+}
+
+func (w *Watchdog) run(ctx context.Context) {
+	defer close(w.done)
+	t := time.NewTicker(w.opts.Interval)
+	defer t.Stop()
+	for {
+		w.check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (w *Watchdog) check(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, w.opts.Timeout)
+	defer cancel()
+	eps := w.cli.Endpoints()
+	r := WatchdogReport{
+		Time:      time.Now(),
+		Endpoints: make([]EndpointHealth, len(eps)),
+	}
+	// Check all endpoints concurrently, so one unreachable member doesn't eat the timeout of the others.
+	var wg sync.WaitGroup
+	for i, ep := range eps {
+		wg.Add(1)
+		go func(i int, ep string) {
+			defer wg.Done()
+			start := time.Now()
+			_, err := w.cli.Status(ctx, ep)
+			r.Endpoints[i] = EndpointHealth{Endpoint: ep, RTT: time.Since(start), Err: err}
+		}(i, ep)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		resp, err := w.cli.AlarmList(ctx)
+		if err != nil {
+			r.AlarmErr = err
+			return
+		}
+		for _, a := range resp.Alarms {
+			r.Alarms = append(r.Alarms, MemberAlarm{MemberID: a.MemberID, Alarm: a.Alarm.String()})
+		}
+	}()
+	wg.Wait()
+	if ctx.Err() == context.Canceled {
+		// We're being stopped.
+		return
+	}
+	w.mtx.Lock()
+	w.last = r
+	w.mtx.Unlock()
+	if w.opts.OnReport != nil {
+		w.opts.OnReport(r)
+	}
+	if w.opts.OnDegraded != nil {
+		for _, d := range r.Degradations(w.opts.MaxRTT) {
+			w.opts.OnDegraded(d)
+		}
+	}
+}
+
+// Degradations returns the problems in this report. Endpoints with a round trip time above maxRTT are considered slow.
+func (r WatchdogReport) Degradations(maxRTT time.Duration) []Degradation {
+	var ret []Degradation
+	for _, e := range r.Endpoints {
+		if e.Err != nil {
+			ret = append(ret, Degradation{Endpoint: e.Endpoint, Problem: "unreachable", Detail: e.Err.Error()})
+		} else if e.RTT > maxRTT {
+			ret = append(ret, Degradation{Endpoint: e.Endpoint, Problem: "slow", Detail: fmt.Sprintf("status took %s", e.RTT)})
+		}
+	}
+	for _, a := range r.Alarms {
+		ret = append(ret, Degradation{Problem: "alarm", Detail: fmt.Sprintf("member %x raised alarm %s", a.MemberID, a.Alarm)})
+	}
+	return ret
+}
@@ -0,0 +1,64 @@
+package clientconfig
+
+import (
+	"context"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// WatchFiles checks the files named by the _FILE variables (including those from ETCD_CREDENTIALS_DIR) every interval (default one second), and when any of them changed, applies the configuration again and passes it to onChange.
+// This lets applications that build their own clients react to rotated credentials, certificates or endpoints. Use ManagedClient if you'd rather have the client replaced for you.
+// If the new configuration can't be applied, a warning is logged and onChange isn't called. WatchFiles blocks until ctx is cancelled.
+func WatchFiles(ctx context.Context, interval time.Duration, onChange func(c clientv3.Config), opts ...Option) error {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	o := newOptions(opts)
+	files, err := watchedFiles(o)
+	if err != nil {
+		return err
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+		if _, changed, err := files.read(); err != nil || !changed {
+			// Files are often missing for a moment while they're being replaced, so just try again next time.
+			continue
+		}
+		c, err := ApplyContext(ctx, Defaults(), opts...)
+		if err != nil {
+			o.warnf("the configuration files changed, but applying them failed: %v", err)
+			continue
+		}
+		onChange(c)
+		// The new configuration might refer to other files.
+		if f, err := watchedFiles(o); err == nil {
+			files = f
+		}
+	}
+}
+
+// watchedFiles returns a fileReloader for the files of all _FILE variables, with their current contents.
+func watchedFiles(o *options) (*fileReloader, error) {
+	var names []string
+	var contents [][]byte
+	for _, k := range allVariables() {
+		fn := o.env.Getenv(k + "_FILE")
+		if fn == "" {
+			continue
+		}
+		b, err := o.env.ReadFile(fn)
+		if err != nil {
+			return nil, errorf(CodeUnreadableFile, k, "error reading %q (for %s_FILE): %v", fn, k, err)
+		}
+		names = append(names, fn)
+		contents = append(contents, b)
+	}
+	return newFileReloader(o.env, names, contents, 0), nil
+}
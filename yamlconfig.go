@@ -0,0 +1,116 @@
+package clientconfig
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// clientv3YAML mirrors the format read by go.etcd.io/etcd/client/v3/yaml.NewConfig.
+type clientv3YAML struct {
+	Endpoints             []string      `json:"endpoints"`
+	AutoSyncInterval      time.Duration `json:"auto-sync-interval,omitempty"`
+	DialTimeout           time.Duration `json:"dial-timeout,omitempty"`
+	DialKeepAliveTime     time.Duration `json:"dial-keep-alive-time,omitempty"`
+	DialKeepAliveTimeout  time.Duration `json:"dial-keep-alive-timeout,omitempty"`
+	Username              string        `json:"username,omitempty"`
+	Password              string        `json:"password,omitempty"`
+	RejectOldCluster      bool          `json:"reject-old-cluster,omitempty"`
+	PermitWithoutStream   bool          `json:"permit-without-stream,omitempty"`
+	MaxUnaryRetries       uint          `json:"max-unary-retries,omitempty"`
+	BackoffWaitBetween    time.Duration `json:"backoff-wait-between,omitempty"`
+	BackoffJitterFraction float64       `json:"backoff-jitter-fraction,omitempty"`
+	InsecureTransport     bool          `json:"insecure-transport,omitempty"`
+	InsecureSkipTLSVerify bool          `json:"insecure-skip-tls-verify,omitempty"`
+	Certfile              string        `json:"cert-file,omitempty"`
+	Keyfile               string        `json:"key-file,omitempty"`
+	TrustedCAfile         string        `json:"trusted-ca-file,omitempty"`
+}
+
+// ToClientv3YAML resolves the configuration like Get and converts it into the YAML format read by go.etcd.io/etcd/client/v3/yaml.NewConfig, for tools that only accept that file.
+// That format refers to certificates and keys by filename. Variables read with _FILE keep pointing at their file; PEM passed directly is written to pemDir.
+// Settings the format can't express (like dial options and ETCD_GATEWAY_SERVER_NAME) are reported as warnings.
+func ToClientv3YAML(pemDir string, opts ...Option) ([]byte, error) {
+	o := newOptions(opts)
+	s, err := ReadSettings(opts...)
+	if err != nil {
+		return nil, err
+	}
+	// Converting shouldn't start a dev etcd or probe the endpoints.
+	c, err := ApplySettings(Defaults(), s, append(opts[:len(opts):len(opts)], withDryRun(nil))...)
+	if err != nil {
+		return nil, err
+	}
+	y := clientv3YAML{
+		Endpoints:             c.Endpoints,
+		AutoSyncInterval:      c.AutoSyncInterval,
+		DialTimeout:           c.DialTimeout,
+		DialKeepAliveTime:     c.DialKeepAliveTime,
+		DialKeepAliveTimeout:  c.DialKeepAliveTimeout,
+		Username:              c.Username,
+		Password:              c.Password,
+		RejectOldCluster:      c.RejectOldCluster,
+		PermitWithoutStream:   c.PermitWithoutStream,
+		MaxUnaryRetries:       c.MaxUnaryRetries,
+		BackoffWaitBetween:    c.BackoffWaitBetween,
+		BackoffJitterFraction: c.BackoffJitterFraction,
+	}
+	if c.TLS == nil && !usesTLSScheme(c.Endpoints) {
+		// NewConfig always sets up TLS unless told otherwise.
+		y.InsecureTransport = true
+	} else if c.TLS != nil {
+		y.InsecureSkipTLSVerify = c.TLS.InsecureSkipVerify
+		if c.TLS.ServerName != "" {
+			o.warnf("the clientv3 YAML format can't express ETCD_GATEWAY_SERVER_NAME or ETCD_TLS_SERVER_NAME")
+		}
+	}
+	pemFile := func(k, name string, perm os.FileMode) (string, error) {
+		if s[k] == "" {
+			return "", nil
+		}
+		if fn := o.env.Getenv(k + "_FILE"); fn != "" {
+			return filepath.Abs(fn)
+		}
+		if pemDir == "" {
+			return "", errorf(CodeInvalidValue, k, "%s is set directly, but the clientv3 YAML format needs a file; pass a directory to write it to", k)
+		}
+		fn := filepath.Join(pemDir, name)
+		if err := writeFileAtomic(fn, []byte(s[k]), perm); err != nil {
+			return "", err
+		}
+		return filepath.Abs(fn)
+	}
+	if y.TrustedCAfile, err = pemFile("ETCD_SERVER_CA", "ca.crt", 0644); err != nil {
+		return nil, err
+	}
+	if y.Certfile, err = pemFile("ETCD_CLIENT_CERT", "client.crt", 0644); err != nil {
+		return nil, err
+	}
+	if y.Keyfile, err = pemFile("ETCD_CLIENT_KEY", "client.key", 0600); err != nil {
+		return nil, err
+	}
+	if s["ETCD_CLIENT_CERT_AND_KEY"] != "" {
+		// The bundle works as both, see ApplySettings.
+		if y.Certfile, err = pemFile("ETCD_CLIENT_CERT_AND_KEY", "client.pem", 0600); err != nil {
+			return nil, err
+		}
+		y.Keyfile = y.Certfile
+	}
+	if s["ETCD_CLIENT_KEY_PASSWORD"] != "" {
+		o.warnf("the clientv3 YAML format can't express ETCD_CLIENT_KEY_PASSWORD; key-file points at the encrypted key")
+	}
+	if len(c.DialOptions) > 0 {
+		o.warnf("the clientv3 YAML format can't express dial options (from ETCD_DIAL_OPTIONS, ETCD_PROFILE, ETCD_GRPC_METADATA and the like)")
+	}
+	if c.MaxCallSendMsgSize != 0 || c.MaxCallRecvMsgSize != 0 {
+		o.warnf("the clientv3 YAML format can't express message size limits")
+	}
+	b, err := json.Marshal(y)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.JSONToYAML(b)
+}